@@ -3,13 +3,58 @@ package config
 import (
 	"errors"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	StorageDriverLocal = "local"
+	StorageDriverS3    = "s3"
+
+	DurationMismatchFlag   = "flag"
+	DurationMismatchReject = "reject"
+
+	ReconcileReport     = "report"
+	ReconcileQuarantine = "quarantine"
+	ReconcileDelete     = "delete"
+)
+
 type AppConfig struct {
 	MySQLDSN string
 	AppPort  string
+
+	StorageDriver    string
+	StorageLocalRoot string
+	S3Endpoint       string
+	S3Region         string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3UsePathStyle   bool
+
+	UploadDir        string
+	UploadMaxSize    int64
+	UploadExpiration time.Duration
+
+	DurationMismatchPolicy string
+	RequireIfMatch         bool
+
+	MovieRetention       time.Duration
+	MovieRetentionDryRun bool
+
+	ReconcileInterval    time.Duration
+	ReconcileMode        string
+	ReconcileGracePeriod time.Duration
+
+	JWTSecret     string
+	JWTIssuer     string
+	JWTAccessTTL  time.Duration
+	JWTRefreshTTL time.Duration
+
+	AdminEmail    string
+	AdminPassword string
 }
 
 func LoadConfig() (*AppConfig, error) {
@@ -28,12 +73,187 @@ func LoadConfig() (*AppConfig, error) {
 		appPort = "8080"
 	}
 
+	storageDriver := os.Getenv("STORAGE_DRIVER")
+	if storageDriver == "" {
+		storageDriver = StorageDriverLocal
+	}
+
+	storageLocalRoot := os.Getenv("STORAGE_LOCAL_ROOT")
+	if storageLocalRoot == "" {
+		storageLocalRoot = "."
+	}
+
+	s3UsePathStyle := true
+	if value := os.Getenv("S3_USE_PATH_STYLE"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("S3_USE_PATH_STYLE must be a boolean")
+		}
+		s3UsePathStyle = parsed
+	}
+
+	// Partial uploads stay on the local disk whatever the storage driver, so
+	// instances behind a load balancer need a shared UPLOAD_DIR or sticky
+	// sessions for /api/uploads.
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads_partial"
+	}
+
+	var uploadMaxSize int64 = 10 << 30
+	if value := os.Getenv("UPLOAD_MAX_SIZE"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("UPLOAD_MAX_SIZE must be a number of bytes")
+		}
+		uploadMaxSize = parsed
+	}
+
+	uploadExpiration := 24 * time.Hour
+	if value := os.Getenv("UPLOAD_EXPIRATION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, errors.New("UPLOAD_EXPIRATION must be a duration such as 24h")
+		}
+		uploadExpiration = parsed
+	}
+
+	durationMismatchPolicy := os.Getenv("DURATION_MISMATCH_POLICY")
+	switch durationMismatchPolicy {
+	case "":
+		durationMismatchPolicy = DurationMismatchFlag
+	case DurationMismatchFlag, DurationMismatchReject:
+	default:
+		return nil, errors.New("DURATION_MISMATCH_POLICY must be either flag or reject")
+	}
+
+	requireIfMatch := false
+	if value := os.Getenv("REQUIRE_IF_MATCH"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("REQUIRE_IF_MATCH must be a boolean")
+		}
+		requireIfMatch = parsed
+	}
+
+	// Purging cannot be undone, so it only runs once an operator sets a
+	// retention window.
+	var movieRetention time.Duration
+	if value := os.Getenv("MOVIE_RETENTION"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, errors.New("MOVIE_RETENTION must be a duration such as 720h, or 0 to keep deleted movies")
+		}
+		movieRetention = parsed
+	}
+
+	movieRetentionDryRun := false
+	if value := os.Getenv("MOVIE_RETENTION_DRY_RUN"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("MOVIE_RETENTION_DRY_RUN must be a boolean")
+		}
+		movieRetentionDryRun = parsed
+	}
+
+	reconcileInterval := 24 * time.Hour
+	if value := os.Getenv("RECONCILE_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, errors.New("RECONCILE_INTERVAL must be a duration such as 24h, or 0 to disable it")
+		}
+		reconcileInterval = parsed
+	}
+
+	reconcileMode := os.Getenv("RECONCILE_MODE")
+	switch reconcileMode {
+	case "":
+		reconcileMode = ReconcileReport
+	case ReconcileReport, ReconcileQuarantine, ReconcileDelete:
+	default:
+		return nil, errors.New("RECONCILE_MODE must be report, quarantine or delete")
+	}
+
+	reconcileGracePeriod := time.Hour
+	if value := os.Getenv("RECONCILE_GRACE_PERIOD"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, errors.New("RECONCILE_GRACE_PERIOD must be a duration such as 1h")
+		}
+		reconcileGracePeriod = parsed
+	}
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if len(jwtSecret) < 32 {
+		return nil, errors.New("JWT_SECRET must be set to at least 32 characters")
+	}
+
+	adminEmail := os.Getenv("ADMIN_EMAIL")
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	if adminEmail != "" && adminPassword == "" {
+		return nil, errors.New("ADMIN_PASSWORD is required when ADMIN_EMAIL is set")
+	}
+
+	jwtIssuer := os.Getenv("JWT_ISSUER")
+	if jwtIssuer == "" {
+		jwtIssuer = "movie-festival-api"
+	}
+
+	jwtAccessTTL := 15 * time.Minute
+	if value := os.Getenv("JWT_ACCESS_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, errors.New("JWT_ACCESS_TTL must be a positive duration such as 15m")
+		}
+		jwtAccessTTL = parsed
+	}
+
+	jwtRefreshTTL := 7 * 24 * time.Hour
+	if value := os.Getenv("JWT_REFRESH_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, errors.New("JWT_REFRESH_TTL must be a positive duration such as 168h")
+		}
+		jwtRefreshTTL = parsed
+	}
+
 	return &AppConfig{
 		MySQLDSN: mysqlDSN,
-		AppPort: appPort,
+		AppPort:  appPort,
+
+		StorageDriver:    storageDriver,
+		StorageLocalRoot: storageLocalRoot,
+		S3Endpoint:       os.Getenv("S3_ENDPOINT"),
+		S3Region:         os.Getenv("S3_REGION"),
+		S3Bucket:         os.Getenv("S3_BUCKET"),
+		S3AccessKey:      os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:      os.Getenv("S3_SECRET_KEY"),
+		S3UsePathStyle:   s3UsePathStyle,
+
+		UploadDir:        uploadDir,
+		UploadMaxSize:    uploadMaxSize,
+		UploadExpiration: uploadExpiration,
+
+		DurationMismatchPolicy: durationMismatchPolicy,
+		RequireIfMatch:         requireIfMatch,
+
+		MovieRetention:       movieRetention,
+		MovieRetentionDryRun: movieRetentionDryRun,
+
+		ReconcileInterval:    reconcileInterval,
+		ReconcileMode:        reconcileMode,
+		ReconcileGracePeriod: reconcileGracePeriod,
+
+		JWTSecret:     jwtSecret,
+		JWTIssuer:     jwtIssuer,
+		JWTAccessTTL:  jwtAccessTTL,
+		JWTRefreshTTL: jwtRefreshTTL,
+
+		AdminEmail:    adminEmail,
+		AdminPassword: adminPassword,
 	}, nil
 }
 
 func (c *AppConfig) GetDBDSN() string {
 	return c.MySQLDSN
-}
\ No newline at end of file
+}
@@ -0,0 +1,65 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Errors created by this package match their kind
+// with errors.Is, so callers can tell them apart without knowing the
+// concrete error of each package.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrValidation   = errors.New("validation failed")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+)
+
+type Error struct {
+	kind    error
+	message string
+	cause   error
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// New returns an error of the given kind with message.
+func New(kind error, message string) *Error {
+	return &Error{kind: kind, message: message}
+}
+
+// Wrap returns an error of the given kind that also matches cause, which
+// lets a package sentinel such as ErrMovieNotFound carry a formatted message.
+func Wrap(kind error, cause error, format string, args ...interface{}) *Error {
+	return &Error{kind: kind, message: fmt.Sprintf(format, args...), cause: cause}
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return New(ErrNotFound, fmt.Sprintf(format, args...))
+}
+
+func Validation(format string, args ...interface{}) error {
+	return New(ErrValidation, fmt.Sprintf(format, args...))
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return New(ErrConflict, fmt.Sprintf(format, args...))
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return New(ErrForbidden, fmt.Sprintf(format, args...))
+}
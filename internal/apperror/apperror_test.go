@@ -0,0 +1,73 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	errMovieNotFound := New(ErrNotFound, "movie not found")
+	wrapped := fmt.Errorf("failed to load: %w", Wrap(ErrNotFound, errMovieNotFound, "movie with ID %d not found", 7))
+
+	if wrapped.Error() != "failed to load: movie with ID 7 not found" {
+		t.Errorf("Error() = %q", wrapped.Error())
+	}
+
+	if !errors.Is(wrapped, ErrNotFound) {
+		t.Error("errors.Is(wrapped, ErrNotFound) = false")
+	}
+
+	if !errors.Is(wrapped, errMovieNotFound) {
+		t.Error("errors.Is(wrapped, errMovieNotFound) = false")
+	}
+
+	if errors.Is(wrapped, ErrConflict) {
+		t.Error("errors.Is(wrapped, ErrConflict) = true")
+	}
+
+	for kind, err := range map[error]error{
+		ErrValidation: Validation("title is required"),
+		ErrConflict:   Conflict("genre already exists"),
+		ErrForbidden:  Forbidden("role %s may not delete", "viewer"),
+		ErrNotFound:   NotFound("person not found"),
+	} {
+		if !errors.Is(err, kind) {
+			t.Errorf("errors.Is(%v, %v) = false", err, kind)
+		}
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	errUnsupported := errors.New("unsupported media type")
+
+	errs := &ValidationErrors{}
+	if errs.Err() != nil {
+		t.Fatal("Err() of empty ValidationErrors is not nil")
+	}
+
+	errs.Add("title", CodeRequired, "title is required")
+	errs.AddError("movie_file", CodeUnsupportedMediaType, fmt.Errorf("%w: not a video", errUnsupported))
+
+	err := errs.Err()
+	if err.Error() != "title is required; unsupported media type: not a video" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+
+	if !errors.Is(err, ErrValidation) {
+		t.Error("errors.Is(err, ErrValidation) = false")
+	}
+
+	if !errors.Is(err, errUnsupported) {
+		t.Error("errors.Is(err, errUnsupported) = false")
+	}
+
+	var verr *ValidationErrors
+	if !errors.As(fmt.Errorf("create: %w", err), &verr) || len(verr.Fields) != 2 {
+		t.Fatalf("errors.As() = %v", verr)
+	}
+
+	if verr.Fields[1].Field != "movie_file" || verr.Fields[1].Code != CodeUnsupportedMediaType {
+		t.Errorf("Fields[1] = %+v", verr.Fields[1])
+	}
+}
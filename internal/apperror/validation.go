@@ -0,0 +1,79 @@
+package apperror
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stable codes of field errors. Clients match on these, so existing codes
+// must not be renamed.
+const (
+	CodeRequired             = "required"
+	CodeNotANumber           = "not_a_number"
+	CodeOutOfRange           = "out_of_range"
+	CodeTooLong              = "too_long"
+	CodeInvalid              = "invalid"
+	CodeExtensionNotAllowed  = "extension_not_allowed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeMutuallyExclusive    = "mutually_exclusive"
+	CodeDurationMismatch     = "duration_mismatch"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	cause   error
+}
+
+// ValidationErrors collects every invalid field of a request so they can be
+// reported at once. It matches ErrValidation and the causes of its fields.
+type ValidationErrors struct {
+	Fields []FieldError
+}
+
+func (e *ValidationErrors) Add(field, code, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// AddError records err as the error of field, keeping it as the cause.
+func (e *ValidationErrors) AddError(field, code string, err error) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: err.Error(), cause: err})
+}
+
+// Err returns nil when no field error was recorded.
+func (e *ValidationErrors) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationErrors) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationErrors) Is(target error) bool {
+	return target == ErrValidation
+}
+
+func (e *ValidationErrors) Unwrap() []error {
+	var causes []error
+	for _, field := range e.Fields {
+		if field.cause != nil {
+			causes = append(causes, field.cause)
+		}
+	}
+	return causes
+}
+
+// Field returns a validation error for a single field.
+func Field(field, code, format string, args ...interface{}) error {
+	errs := &ValidationErrors{}
+	errs.Add(field, code, format, args...)
+	return errs
+}
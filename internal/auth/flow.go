@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = apperror.New(apperror.ErrUnauthorized, "invalid email or password")
+	ErrInvalidUser        = apperror.New(apperror.ErrValidation, "invalid user")
+	ErrTokenRevoked       = apperror.New(apperror.ErrUnauthorized, "token has been revoked")
+	ErrAdminEmailTaken    = apperror.New(apperror.ErrConflict, "admin email is registered to a user who is not an admin")
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after 72 bytes.
+	maxPasswordLength = 72
+)
+
+// dummyPasswordHash is compared against when logging in with an unknown email,
+// so that it takes as long as a wrong password and does not reveal whether
+// the email is registered.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not the password of any user"), bcrypt.DefaultCost)
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type AuthFlowInterface interface {
+	Register(ctx context.Context, email, name, password string) (*entity.User, error)
+	Login(ctx context.Context, email, password string) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(ctx context.Context, principal *Principal, refreshToken string) error
+	Authenticate(ctx context.Context, accessToken string) (*Principal, error)
+	GetUser(ctx context.Context, id int) (*entity.User, error)
+	SetUserRole(ctx context.Context, id int, role string) (*entity.User, error)
+	CleanupExpired(ctx context.Context) (int64, error)
+	SeedAdmin(ctx context.Context) (*entity.User, error)
+}
+
+// AuthConfig.AdminEmail and AdminPassword are the first administrator, which
+// SeedAdmin creates at startup so it can be bootstrapped without touching the
+// database.
+type AuthConfig struct {
+	AdminEmail    string
+	AdminPassword string
+}
+
+type authFlow struct {
+	userRepo UserRepository
+	tokens   *TokenManager
+	cfg      AuthConfig
+	now      func() time.Time
+}
+
+func NewAuthFlow(userRepo UserRepository, tokens *TokenManager, cfg AuthConfig) AuthFlowInterface {
+	return &authFlow{
+		userRepo: userRepo,
+		tokens:   tokens,
+		cfg:      cfg,
+		now:      time.Now,
+	}
+}
+
+func (f *authFlow) Register(ctx context.Context, email, name, password string) (*entity.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.createUser(ctx, email, name, password, entity.UserRoleViewer)
+}
+
+// SeedAdmin creates the administrator of AuthConfig unless it exists. It
+// returns nil if none is configured. A user who registered the admin email
+// is not promoted, as anyone could have registered it.
+func (f *authFlow) SeedAdmin(ctx context.Context) (*entity.User, error) {
+	if f.cfg.AdminEmail == "" {
+		return nil, nil
+	}
+
+	email, err := normalizeEmail(f.cfg.AdminEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := f.userRepo.GetUserByEmail(ctx, email)
+	if err == nil {
+		if user.Role != entity.UserRoleAdmin {
+			return nil, ErrAdminEmailTaken
+		}
+		return user, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	return f.createUser(ctx, email, "Admin", f.cfg.AdminPassword, entity.UserRoleAdmin)
+}
+
+func (f *authFlow) createUser(ctx context.Context, email, name, password, role string) (*entity.User, error) {
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidUser, minPasswordLength)
+	}
+	if len(password) > maxPasswordLength {
+		return nil, fmt.Errorf("%w: password must be at most %d bytes", ErrInvalidUser, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	currentTime := f.now()
+	user := &entity.User{
+		Email:        email,
+		Name:         strings.TrimSpace(name),
+		PasswordHash: string(hash),
+		Role:         role,
+		CreatedAt:    currentTime,
+		UpdatedAt:    currentTime,
+	}
+
+	return f.userRepo.CreateUser(ctx, user)
+}
+
+func (f *authFlow) Login(ctx context.Context, email, password string) (*TokenPair, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := f.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return f.issueTokens(ctx, user)
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already
+// rotated or revoked revokes every refresh token of the user, since it
+// means the token has leaked.
+func (f *authFlow) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	claims, err := f.tokens.Parse(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := f.userRepo.GetRefreshToken(ctx, claims.ID)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := f.now()
+	if stored.UserID != userID || !stored.IsActive(now) {
+		if stored.RevokedAt != nil {
+			if err := f.userRepo.RevokeUserRefreshTokens(ctx, stored.UserID, now); err != nil {
+				return nil, err
+			}
+			return nil, ErrTokenRevoked
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if err := f.userRepo.RevokeRefreshToken(ctx, stored.ID, now); err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil, ErrTokenRevoked
+		}
+		return nil, err
+	}
+
+	user, err := f.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return f.issueTokens(ctx, user)
+}
+
+// Logout revokes the access token of the principal and, when given, the
+// refresh token issued alongside it.
+func (f *authFlow) Logout(ctx context.Context, principal *Principal, refreshToken string) error {
+	err := f.userRepo.RevokeAccessToken(ctx, &entity.RevokedToken{
+		ID:        principal.TokenID,
+		ExpiresAt: principal.ExpiresAt,
+		CreatedAt: f.now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	claims, err := f.tokens.Parse(refreshToken, TokenTypeRefresh)
+	if err != nil {
+		return err
+	}
+
+	if userID, err := claims.UserID(); err != nil || userID != principal.UserID {
+		return ErrInvalidToken
+	}
+
+	err = f.userRepo.RevokeRefreshToken(ctx, claims.ID, f.now())
+	if err != nil && !errors.Is(err, ErrTokenNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (f *authFlow) Authenticate(ctx context.Context, accessToken string) (*Principal, error) {
+	claims, err := f.tokens.Parse(accessToken, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := f.userRepo.IsAccessTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return &Principal{
+		UserID:    userID,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		TokenID:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (f *authFlow) GetUser(ctx context.Context, id int) (*entity.User, error) {
+	return f.userRepo.GetUser(ctx, id)
+}
+
+// SetUserRole changes the role of a user. Access tokens already issued keep
+// the old role until they are refreshed.
+func (f *authFlow) SetUserRole(ctx context.Context, id int, role string) (*entity.User, error) {
+	if err := Authorize(ctx, ActionManageUsers); err != nil {
+		return nil, err
+	}
+
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !entity.IsUserRole(role) {
+		return nil, fmt.Errorf("%w: role must be one of %s", ErrInvalidUser, strings.Join(entity.UserRoles, ", "))
+	}
+
+	return f.userRepo.UpdateUserRole(ctx, id, role)
+}
+
+func (f *authFlow) CleanupExpired(ctx context.Context) (int64, error) {
+	return f.userRepo.DeleteExpiredTokens(ctx, f.now())
+}
+
+func (f *authFlow) issueTokens(ctx context.Context, user *entity.User) (*TokenPair, error) {
+	accessToken, accessClaims, err := f.tokens.Issue(TokenTypeAccess, user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshClaims, err := f.tokens.Issue(TokenTypeRefresh, user)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.userRepo.CreateRefreshToken(ctx, &entity.RefreshToken{
+		ID:        refreshClaims.ID,
+		UserID:    user.ID,
+		ExpiresAt: refreshClaims.ExpiresAt.Time,
+		CreatedAt: f.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessClaims.ExpiresAt.Time.Sub(accessClaims.IssuedAt.Time).Seconds()),
+	}, nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", fmt.Errorf("%w: email is required", ErrInvalidUser)
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("%w: email is not valid", ErrInvalidUser)
+	}
+
+	return email, nil
+}
@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"roketin-case-study-challenge2/internal/entity"
+	"testing"
+	"time"
+)
+
+type MockUserRepository struct {
+	users         []entity.User
+	refreshTokens map[string]entity.RefreshToken
+	revoked       map[string]entity.RevokedToken
+	// revokedErr fails IsAccessTokenRevoked, as a database outage would.
+	revokedErr error
+}
+
+func NewMockUserRepository() *MockUserRepository {
+	return &MockUserRepository{
+		refreshTokens: make(map[string]entity.RefreshToken),
+		revoked:       make(map[string]entity.RevokedToken),
+	}
+}
+
+func (m *MockUserRepository) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	for _, u := range m.users {
+		if u.Email == user.Email {
+			return nil, ErrEmailTaken
+		}
+	}
+
+	user.ID = len(m.users) + 1
+	m.users = append(m.users, *user)
+
+	return user, nil
+}
+
+func (m *MockUserRepository) GetUser(ctx context.Context, id int) (*entity.User, error) {
+	for _, u := range m.users {
+		if u.ID == id {
+			user := u
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+func (m *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	for _, u := range m.users {
+		if u.Email == email {
+			user := u
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+func (m *MockUserRepository) UpdateUserRole(ctx context.Context, id int, role string) (*entity.User, error) {
+	for i, u := range m.users {
+		if u.ID == id {
+			m.users[i].Role = role
+			user := m.users[i]
+			return &user, nil
+		}
+	}
+
+	return nil, ErrUserNotFound
+}
+
+func (m *MockUserRepository) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	m.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (m *MockUserRepository) GetRefreshToken(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	token, ok := m.refreshTokens[id]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return &token, nil
+}
+
+func (m *MockUserRepository) RevokeRefreshToken(ctx context.Context, id string, at time.Time) error {
+	token, ok := m.refreshTokens[id]
+	if !ok || token.RevokedAt != nil {
+		return ErrTokenNotFound
+	}
+
+	token.RevokedAt = &at
+	m.refreshTokens[id] = token
+
+	return nil
+}
+
+func (m *MockUserRepository) RevokeUserRefreshTokens(ctx context.Context, userID int, at time.Time) error {
+	for id, token := range m.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+			m.refreshTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+func (m *MockUserRepository) RevokeAccessToken(ctx context.Context, token *entity.RevokedToken) error {
+	m.revoked[token.ID] = *token
+	return nil
+}
+
+func (m *MockUserRepository) IsAccessTokenRevoked(ctx context.Context, id string) (bool, error) {
+	if m.revokedErr != nil {
+		return false, m.revokedErr
+	}
+	_, ok := m.revoked[id]
+	return ok, nil
+}
+
+func (m *MockUserRepository) DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	var removed int64
+	for id, token := range m.refreshTokens {
+		if token.ExpiresAt.Before(before) {
+			delete(m.refreshTokens, id)
+			removed++
+		}
+	}
+	for id, token := range m.revoked {
+		if token.ExpiresAt.Before(before) {
+			delete(m.revoked, id)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func newTestAuthFlow(repo UserRepository) *authFlow {
+	tokens := NewTokenManager(TokenConfig{
+		Secret:     "test-secret-with-at-least-32-characters",
+		Issuer:     "test",
+		AccessTTL:  15 * time.Minute,
+		RefreshTTL: time.Hour,
+	})
+
+	return NewAuthFlow(repo, tokens, AuthConfig{AdminEmail: "Admin@Example.com", AdminPassword: "correct horse"}).(*authFlow)
+}
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "success register", email: " Jury@Example.com ", password: "correct horse"},
+		{name: "fail - invalid email", email: "not-an-email", password: "correct horse", wantErr: ErrInvalidUser},
+		{name: "fail - missing email", email: "", password: "correct horse", wantErr: ErrInvalidUser},
+		{name: "fail - short password", email: "jury@example.com", password: "short", wantErr: ErrInvalidUser},
+		{name: "fail - duplicate email", email: "taken@example.com", password: "correct horse", wantErr: ErrEmailTaken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := NewMockUserRepository()
+			repo.users = []entity.User{{ID: 1, Email: "taken@example.com"}}
+			flow := newTestAuthFlow(repo)
+
+			user, err := flow.Register(context.Background(), test.email, "Jury", test.password)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("Register() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Register() error = %v", err)
+			}
+
+			if user.Email != "jury@example.com" {
+				t.Errorf("Register() email = %q, want normalized email", user.Email)
+			}
+
+			if user.PasswordHash == "" || user.PasswordHash == test.password {
+				t.Error("Register() password was not hashed")
+			}
+		})
+	}
+}
+
+func TestLoginAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	flow := newTestAuthFlow(NewMockUserRepository())
+
+	if _, err := flow.Register(ctx, "jury@example.com", "Jury", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := flow.Login(ctx, "jury@example.com", "wrong password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with wrong password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	if _, err := flow.Login(ctx, "nobody@example.com", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with unknown email error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	tokens, err := flow.Login(ctx, "JURY@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn != int64((15*time.Minute).Seconds()) {
+		t.Errorf("Login() token type = %q, expires in = %d", tokens.TokenType, tokens.ExpiresIn)
+	}
+
+	principal, err := flow.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	if principal.UserID != 1 || principal.Email != "jury@example.com" || principal.Role != entity.UserRoleViewer {
+		t.Errorf("Authenticate() principal = %+v", principal)
+	}
+
+	if _, err := flow.Authenticate(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate() with refresh token error = %v, want %v", err, ErrInvalidToken)
+	}
+
+	flow.tokens.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if _, err := flow.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Authenticate() with expired token error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockUserRepository()
+	flow := newTestAuthFlow(repo)
+
+	if _, err := flow.Register(ctx, "jury@example.com", "Jury", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := flow.Login(ctx, "jury@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := flow.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Refresh() did not rotate the refresh token")
+	}
+
+	if _, err := flow.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Refresh() with reused token error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	if _, err := flow.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Refresh() after reuse error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	if _, err := flow.Refresh(ctx, second.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Refresh() with access token error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	flow := newTestAuthFlow(NewMockUserRepository())
+
+	if _, err := flow.Register(ctx, "jury@example.com", "Jury", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := flow.Login(ctx, "jury@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	principal, err := flow.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := flow.Logout(ctx, principal, tokens.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+
+	if _, err := flow.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Authenticate() after logout error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	if _, err := flow.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Refresh() after logout error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	removed, err := flow.CleanupExpired(ctx)
+	if err != nil || removed != 0 {
+		t.Errorf("CleanupExpired() = %d, %v, want nothing removed yet", removed, err)
+	}
+
+	flow.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	removed, err = flow.CleanupExpired(ctx)
+	if err != nil || removed != 2 {
+		t.Errorf("CleanupExpired() = %d, %v, want 2 removed", removed, err)
+	}
+}
+
+func TestSeedAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates the admin once", func(t *testing.T) {
+		repo := NewMockUserRepository()
+		flow := newTestAuthFlow(repo)
+
+		admin, err := flow.SeedAdmin(ctx)
+		if err != nil {
+			t.Fatalf("SeedAdmin() error = %v", err)
+		}
+		if admin.Email != "admin@example.com" || admin.Role != entity.UserRoleAdmin {
+			t.Errorf("SeedAdmin() email = %q, role = %q", admin.Email, admin.Role)
+		}
+
+		if _, err := flow.SeedAdmin(ctx); err != nil {
+			t.Fatalf("SeedAdmin() again error = %v", err)
+		}
+		if len(repo.users) != 1 {
+			t.Errorf("SeedAdmin() twice created %d users, want 1", len(repo.users))
+		}
+
+		if _, err := flow.Login(ctx, "admin@example.com", "correct horse"); err != nil {
+			t.Errorf("Login() as seeded admin error = %v", err)
+		}
+	})
+
+	t.Run("does not promote a registered user", func(t *testing.T) {
+		repo := NewMockUserRepository()
+		flow := newTestAuthFlow(repo)
+
+		user, err := flow.Register(ctx, "admin@example.com", "Mallory", "correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Role != entity.UserRoleViewer {
+			t.Errorf("Register() with admin email role = %q, want %q", user.Role, entity.UserRoleViewer)
+		}
+
+		if _, err := flow.SeedAdmin(ctx); !errors.Is(err, ErrAdminEmailTaken) {
+			t.Errorf("SeedAdmin() error = %v, want %v", err, ErrAdminEmailTaken)
+		}
+		if repo.users[0].Role != entity.UserRoleViewer {
+			t.Errorf("SeedAdmin() promoted the registered user to %q", repo.users[0].Role)
+		}
+	})
+
+	t.Run("without admin email", func(t *testing.T) {
+		flow := newTestAuthFlow(NewMockUserRepository())
+		flow.cfg = AuthConfig{}
+
+		if admin, err := flow.SeedAdmin(ctx); admin != nil || err != nil {
+			t.Errorf("SeedAdmin() = %v, %v, want nil, nil", admin, err)
+		}
+	})
+}
+
+func TestSetUserRole(t *testing.T) {
+	ctx := context.Background()
+	flow := newTestAuthFlow(NewMockUserRepository())
+
+	admin, err := flow.SeedAdmin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := flow.Register(ctx, "jury@example.com", "Jury", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewerCtx := WithPrincipal(ctx, &Principal{UserID: user.ID, Role: user.Role})
+	if _, err := flow.SetUserRole(viewerCtx, user.ID, entity.UserRoleAdmin); !errors.Is(err, ErrForbidden) {
+		t.Errorf("SetUserRole() as viewer error = %v, want %v", err, ErrForbidden)
+	}
+
+	adminCtx := WithPrincipal(ctx, &Principal{UserID: admin.ID, Role: admin.Role})
+	if _, err := flow.SetUserRole(adminCtx, user.ID, "superuser"); !errors.Is(err, ErrInvalidUser) {
+		t.Errorf("SetUserRole() with unknown role error = %v, want %v", err, ErrInvalidUser)
+	}
+
+	updated, err := flow.SetUserRole(adminCtx, user.ID, " Jury ")
+	if err != nil {
+		t.Fatalf("SetUserRole() error = %v", err)
+	}
+	if updated.Role != entity.UserRoleJury {
+		t.Errorf("SetUserRole() role = %q, want %q", updated.Role, entity.UserRoleJury)
+	}
+
+	tokens, err := flow.Login(ctx, "jury@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := flow.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal.Role != entity.UserRoleJury {
+		t.Errorf("Authenticate() role = %q, want %q", principal.Role, entity.UserRoleJury)
+	}
+}
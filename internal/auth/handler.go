@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"net/http"
+	"roketin-case-study-challenge2/internal/constant"
+	"roketin-case-study-challenge2/internal/response"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type AuthHandler struct {
+	authFlow AuthFlowInterface
+}
+
+func NewAuthHandler(authFlow AuthFlowInterface) *AuthHandler {
+	return &AuthHandler{
+		authFlow: authFlow,
+	}
+}
+
+func (h *AuthHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Post("/register", h.Register)
+	r.Post("/login", h.Login)
+	r.Post("/refresh", h.Refresh)
+
+	r.Group(func(r chi.Router) {
+		r.Use(Authenticator(h.authFlow))
+
+		r.Post("/logout", h.Logout)
+		r.Get("/me", h.Me)
+		r.Put("/users/{id}/role", h.SetUserRole)
+	})
+
+	return r
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	user, err := h.authFlow.Register(r.Context(), r.PostFormValue("email"), r.PostFormValue("name"), r.PostFormValue("password"))
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, user)
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.authFlow.Login(r.Context(), r.PostFormValue("email"), r.PostFormValue("password"))
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, tokens)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	refreshToken := r.PostFormValue("refresh_token")
+	if refreshToken == "" {
+		response.Error(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	tokens, err := h.authFlow.Refresh(r.Context(), refreshToken)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, _ := PrincipalFromContext(r.Context())
+
+	if err := h.authFlow.Logout(r.Context(), principal, r.PostFormValue("refresh_token")); err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, constant.LOGGED_OUT_SUCCESSFULLY)
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	principal, _ := PrincipalFromContext(r.Context())
+
+	user, err := h.authFlow.GetUser(r.Context(), principal.UserID)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, user)
+}
+
+func (h *AuthHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_USER_ID)
+		return
+	}
+
+	user, err := h.authFlow.SetUserRole(r.Context(), id, r.PostFormValue("role"))
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, user)
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"roketin-case-study-challenge2/internal/response"
+	"strings"
+	"testing"
+)
+
+func TestAuthHandler(t *testing.T) {
+	flow := newTestAuthFlow(NewMockUserRepository())
+	server := httptest.NewServer(NewAuthHandler(flow).Routes())
+	defer server.Close()
+
+	do := func(method, path, token string, form url.Values) (*http.Response, response.Response) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var body response.Response
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	resp, _ := do(http.MethodPost, "/register", "", url.Values{"email": {"jury@example.com"}, "name": {"Jury"}, "password": {"correct horse"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /register status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, _ = do(http.MethodPost, "/register", "", url.Values{"email": {"jury@example.com"}, "password": {"correct horse"}})
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("POST /register duplicate status = %v, want %v", resp.StatusCode, http.StatusConflict)
+	}
+
+	resp, _ = do(http.MethodPost, "/login", "", url.Values{"email": {"jury@example.com"}, "password": {"wrong password"}})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST /login wrong password status = %v, want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp, body := do(http.MethodPost, "/login", "", url.Values{"email": {"jury@example.com"}, "password": {"correct horse"}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /login status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+	data := body.Data.(map[string]interface{})
+	accessToken := data["access_token"].(string)
+	refreshToken := data["refresh_token"].(string)
+
+	resp, _ = do(http.MethodGet, "/me", "", nil)
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("GET /me without token status = %v, want %v with challenge", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp, body = do(http.MethodGet, "/me", accessToken, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /me status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+	me := body.Data.(map[string]interface{})
+	if me["email"] != "jury@example.com" || me["password_hash"] != nil || me["PasswordHash"] != nil {
+		t.Errorf("GET /me data = %v", me)
+	}
+
+	resp, _ = do(http.MethodPost, "/refresh", "", nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /refresh without token status = %v, want %v", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	resp, body = do(http.MethodPost, "/refresh", "", url.Values{"refresh_token": {refreshToken}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /refresh status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+	refreshToken = body.Data.(map[string]interface{})["refresh_token"].(string)
+
+	resp, _ = do(http.MethodPost, "/logout", accessToken, url.Values{"refresh_token": {refreshToken}})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /logout status = %v, want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, _ = do(http.MethodGet, "/me", accessToken, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /me after logout status = %v, want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp, _ = do(http.MethodPost, "/refresh", "", url.Values{"refresh_token": {refreshToken}})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("POST /refresh after logout status = %v, want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
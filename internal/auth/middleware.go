@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/response"
+	"strings"
+)
+
+// Authenticator rejects requests without a valid bearer access token and
+// places the principal into the request context.
+func Authenticator(authFlow AuthFlowInterface) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "missing bearer token")
+				return
+			}
+
+			principal, err := authFlow.Authenticate(r.Context(), token)
+			if errors.Is(err, apperror.ErrUnauthorized) {
+				unauthorized(w, "invalid or expired token")
+				return
+			}
+			if err != nil {
+				// Failures such as a database outage are not the client's
+				// fault and their details stay in the log.
+				log.Printf("Failed to authenticate request: %v", err)
+				response.Error(w, http.StatusInternalServerError, "failed to authenticate request")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	response.Error(w, http.StatusUnauthorized, message)
+}
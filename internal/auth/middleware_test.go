@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticator(t *testing.T) {
+	repo := NewMockUserRepository()
+	flow := newTestAuthFlow(repo)
+	ctx := context.Background()
+
+	if _, err := flow.Register(ctx, "jury@example.com", "Jury", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := flow.Login(ctx, "jury@example.com", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		repoErr    error
+		wantStatus int
+		wantDetail string
+	}{
+		{name: "success", token: tokens.AccessToken, wantStatus: http.StatusOK},
+		{name: "fail - missing token", wantStatus: http.StatusUnauthorized, wantDetail: "missing bearer token"},
+		{name: "fail - invalid token", token: "not-a-token", wantStatus: http.StatusUnauthorized, wantDetail: "invalid or expired token"},
+		{
+			name:       "fail - database error",
+			token:      tokens.AccessToken,
+			repoErr:    errors.New("dial tcp 10.0.0.5:3306: connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantDetail: "failed to authenticate request",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo.revokedErr = test.repoErr
+
+			handler := Authenticator(flow)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+			if test.wantDetail != "" && !strings.Contains(rr.Body.String(), `"detail":"`+test.wantDetail+`"`) {
+				t.Errorf("body = %s, want detail %q", rr.Body.String(), test.wantDetail)
+			}
+			if strings.Contains(rr.Body.String(), "10.0.0.5") {
+				t.Errorf("body = %s leaks the internal error", rr.Body.String())
+			}
+		})
+	}
+}
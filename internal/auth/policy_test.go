@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		role    string
+		action  Action
+		allowed bool
+	}{
+		{role: entity.UserRoleAdmin, action: ActionManageUsers, allowed: true},
+		{role: entity.UserRoleProgrammer, action: ActionManageUsers, allowed: false},
+		{role: entity.UserRoleProgrammer, action: ActionCreateMovie, allowed: true},
+		{role: entity.UserRoleProgrammer, action: ActionDeleteMovie, allowed: true},
+		{role: entity.UserRoleJury, action: ActionStreamMovie, allowed: true},
+		{role: entity.UserRoleJury, action: ActionUpdateMovie, allowed: false},
+		{role: entity.UserRoleProgrammer, action: ActionRestoreMovie, allowed: true},
+		{role: entity.UserRoleJury, action: ActionRestoreMovie, allowed: false},
+		{role: entity.UserRoleAdmin, action: ActionPurgeMovie, allowed: true},
+		{role: entity.UserRoleProgrammer, action: ActionPurgeMovie, allowed: false},
+		{role: entity.UserRoleViewer, action: ActionReadMovie, allowed: true},
+		{role: entity.UserRoleViewer, action: ActionStreamMovie, allowed: false},
+		{role: entity.UserRoleViewer, action: ActionUploadMovie, allowed: false},
+		{role: "unknown", action: ActionReadMovie, allowed: false},
+	}
+
+	for _, test := range tests {
+		if got := Can(test.role, test.action); got != test.allowed {
+			t.Errorf("Can(%q, %q) = %v, want %v", test.role, test.action, got, test.allowed)
+		}
+	}
+}
+
+func TestAuthorizeWithoutPrincipal(t *testing.T) {
+	err := Authorize(context.Background(), ActionReadMovie)
+	if !errors.Is(err, apperror.ErrUnauthorized) || errors.Is(err, ErrForbidden) {
+		t.Errorf("Authorize() error = %v, want %v", err, apperror.ErrUnauthorized)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	handler := Require(ActionUploadMovie)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for role, want := range map[string]int{
+		entity.UserRoleProgrammer: http.StatusNoContent,
+		entity.UserRoleViewer:     http.StatusForbidden,
+		"":                        http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if role != "" {
+			req = req.WithContext(WithPrincipal(req.Context(), &Principal{UserID: 1, Role: role}))
+		}
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != want {
+			t.Errorf("Require() for %s status = %v, want %v", role, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"time"
+)
+
+var (
+	ErrUserNotFound  = apperror.New(apperror.ErrNotFound, "user not found")
+	ErrEmailTaken    = apperror.New(apperror.ErrConflict, "email is already registered")
+	ErrTokenNotFound = apperror.New(apperror.ErrNotFound, "token not found")
+)
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *entity.User) (*entity.User, error)
+	GetUser(ctx context.Context, id int) (*entity.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
+	UpdateUserRole(ctx context.Context, id int, role string) (*entity.User, error)
+	CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id string) (*entity.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string, at time.Time) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int, at time.Time) error
+	RevokeAccessToken(ctx context.Context, token *entity.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, id string) (bool, error)
+	DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error)
+}
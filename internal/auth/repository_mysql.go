@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"roketin-case-study-challenge2/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type mySQLUserRepository struct {
+	db *gorm.DB
+}
+
+func NewMySQLUserRepository(db *gorm.DB) UserRepository {
+	return &mySQLUserRepository{
+		db: db,
+	}
+}
+
+func (r *mySQLUserRepository) CreateUser(ctx context.Context, user *entity.User) (*entity.User, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+
+		return tx.Create(user).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *mySQLUserRepository) GetUser(ctx context.Context, id int) (*entity.User, error) {
+	var user entity.User
+	err := r.db.WithContext(ctx).First(&user, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *mySQLUserRepository) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	var user entity.User
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *mySQLUserRepository) UpdateUserRole(ctx context.Context, id int, role string) (*entity.User, error) {
+	result := r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"role":       role,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update user role: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	return r.GetUser(ctx, id)
+}
+
+func (r *mySQLUserRepository) CreateRefreshToken(ctx context.Context, token *entity.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mySQLUserRepository) GetRefreshToken(ctx context.Context, id string) (*entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *mySQLUserRepository) RevokeRefreshToken(ctx context.Context, id string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+func (r *mySQLUserRepository) RevokeUserRefreshTokens(ctx context.Context, userID int, at time.Time) error {
+	err := r.db.WithContext(ctx).Model(&entity.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mySQLUserRepository) RevokeAccessToken(ctx context.Context, token *entity.RevokedToken) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mySQLUserRepository) IsAccessTokenRevoked(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.RevokedToken{}).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check revoked token: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *mySQLUserRepository) DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	var removed int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("expires_at < ?", before).Delete(&entity.RefreshToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed += result.RowsAffected
+
+		result = tx.Where("expires_at < ?", before).Delete(&entity.RevokedToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed += result.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+var ErrInvalidToken = apperror.New(apperror.ErrUnauthorized, "invalid or expired token")
+
+type TokenConfig struct {
+	Secret     string
+	Issuer     string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Type  string `json:"typ"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
+
+// UserID returns the user ID carried in the subject claim.
+func (c *Claims) UserID() (int, error) {
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidToken
+	}
+	return id, nil
+}
+
+type TokenManager struct {
+	cfg TokenConfig
+	now func() time.Time
+}
+
+func NewTokenManager(cfg TokenConfig) *TokenManager {
+	return &TokenManager{
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+// Issue signs a token of the given type for user and returns it with its
+// claims. Only access tokens carry the email and role of the user.
+func (m *TokenManager) Issue(tokenType string, user *entity.User) (string, *Claims, error) {
+	ttl := m.cfg.AccessTTL
+	if tokenType == TokenTypeRefresh {
+		ttl = m.cfg.RefreshTTL
+	}
+
+	id, err := newTokenID()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := m.now()
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    m.cfg.Issuer,
+			Subject:   strconv.Itoa(user.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type: tokenType,
+	}
+	if tokenType == TokenTypeAccess {
+		claims.Email = user.Email
+		claims.Role = user.Role
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(m.cfg.Secret))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return token, claims, nil
+}
+
+// Parse verifies the signature, issuer, lifetime and type of a token.
+func (m *TokenManager) Parse(token string, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(m.cfg.Secret), nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Type != tokenType || claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
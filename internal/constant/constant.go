@@ -1,5 +1,32 @@
 package constant
 
+import "time"
+
 var ERROR_INVALID_MOVIE_ID = "invalid movie ID"
 
-var MOVIE_DELETED_SUCCESSFULLY = "Movie deleted successfully"
\ No newline at end of file
+var ERROR_MOVIE_FILE_NOT_FOUND = "movie file not found"
+
+var MOVIE_DELETED_SUCCESSFULLY = "Movie deleted successfully"
+var MOVIE_PURGED_SUCCESSFULLY = "Movie permanently deleted"
+var MOVIE_UPLOAD_PATH = "uploads"
+var MOVIE_STAGING_PATH = "uploads/staging"
+var MOVIE_QUARANTINE_PATH = "quarantine"
+
+// REQUEST_TIMEOUT bounds requests that do not transfer a video.
+var REQUEST_TIMEOUT = 60 * time.Second
+
+var ERROR_INVALID_GENRE_ID = "invalid genre ID"
+
+var GENRE_DELETED_SUCCESSFULLY = "Genre deleted successfully"
+
+var ERROR_INVALID_PERSON_ID = "invalid person ID"
+
+var ERROR_INVALID_CREDIT_ID = "invalid credit ID"
+
+var PERSON_DELETED_SUCCESSFULLY = "Person deleted successfully"
+
+var CREDIT_DELETED_SUCCESSFULLY = "Credit deleted successfully"
+
+var LOGGED_OUT_SUCCESSFULLY = "Logged out successfully"
+
+var ERROR_INVALID_USER_ID = "invalid user ID"
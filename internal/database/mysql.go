@@ -41,7 +41,7 @@ func InitMySQLDB(dsn string) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	err = db.AutoMigrate(&entity.Movie{})
+	err = db.AutoMigrate(&entity.Genre{}, &entity.Movie{}, &entity.MovieFileVersion{}, &entity.Person{}, &entity.Credit{}, &entity.Upload{}, &entity.User{}, &entity.RefreshToken{}, &entity.RevokedToken{})
 	if err != nil {
 		return nil, fmt.Errorf("Failed to auto migrate: %w", err)
 	}
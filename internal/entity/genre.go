@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type Genre struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Genre) TableName() string {
+	return "genres"
+}
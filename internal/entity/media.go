@@ -0,0 +1,13 @@
+package entity
+
+// MediaInfo describes the stream properties probed from an uploaded video.
+type MediaInfo struct {
+	Container       string  `gorm:"type:varchar(32)" json:"container,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	VideoCodec      string  `gorm:"type:varchar(32)" json:"video_codec,omitempty"`
+	AudioCodec      string  `gorm:"type:varchar(32)" json:"audio_codec,omitempty"`
+	Bitrate         int64   `json:"bitrate,omitempty"`
+	FrameRate       float64 `json:"frame_rate,omitempty"`
+}
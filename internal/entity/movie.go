@@ -1,37 +1,177 @@
 package entity
 
 import (
+	"encoding/json"
+	"roketin-case-study-challenge2/internal/search"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Movie struct {
-	ID          int             `gorm:"primaryKey" json:"id"`
-	Title       string          `gorm:"type:varchar(255); not null" json:"title"`
-	Description string          `gorm:"type:text" json:"description"`
-	Duration    int             `json:"duration_minutes"`
-	Artists     string          `gorm:"type:varchar(255)" json:"artists"`
-	Genres      string          `gorm:"type:varchar(255)" json:"genres"`
-	FilePath    string          `gorm:"type:varchar(255)" json:"file_path"`
-	CreatedAt   time.Time       `json:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at"`
-	DeletedAt   *gorm.DeletedAt `json:"deleted_at,omitempty"` //soft delete
+	ID               int             `gorm:"primaryKey" json:"id"`
+	Title            string          `gorm:"type:varchar(255); not null;index:idx_movies_search,class:FULLTEXT" json:"title"`
+	Description      string          `gorm:"type:text;index:idx_movies_search,class:FULLTEXT" json:"description"`
+	Duration         int             `json:"duration_minutes"`
+	Artists          NameList        `gorm:"type:varchar(255);index:idx_movies_search,class:FULLTEXT" json:"artists"`
+	Genres           NameList        `gorm:"type:varchar(255);index:idx_movies_search,class:FULLTEXT" json:"genres"`
+	GenreList        []Genre         `gorm:"many2many:movie_genres" json:"-"`
+	Credits          []Credit        `json:"credits,omitempty"`
+	FilePath         string          `gorm:"type:varchar(255)" json:"file_path"`
+	MimeType         string          `gorm:"type:varchar(64)" json:"mime_type"`
+	Media            MediaInfo       `gorm:"embedded;embeddedPrefix:media_" json:"media"`
+	DurationMismatch bool            `json:"duration_mismatch"`
+	Version          int             `gorm:"not null;default:1" json:"version"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	DeletedAt        *gorm.DeletedAt `json:"deleted_at,omitempty"` //soft delete
+	// Score is the relevance of the movie to a full-text query.
+	Score float64 `gorm:"->;-:migration" json:"score,omitempty"`
+}
+
+// NameList is a list of names stored as one comma separated column, such as
+// the artists and genres of a movie. In JSON it is an array of strings.
+type NameList string
+
+// Names returns the names of the list, without blank entries.
+func (l NameList) Names() []string {
+	names := []string{}
+	for _, name := range strings.Split(string(l), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func (l NameList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.Names())
+}
+
+func (l *NameList) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+
+	*l = NameList(strings.Join(names, ","))
+	return nil
+}
+
+// MovieInclude selects the related data returned with a single movie.
+type MovieInclude struct {
+	Files   bool
+	Credits bool
+	Stats   bool
+}
+
+// MovieFile is a video of a movie. Videos that were replaced have the time
+// they were replaced at.
+type MovieFile struct {
+	Path       string     `json:"path"`
+	MimeType   string     `json:"mime_type"`
+	Size       int64      `json:"size"`
+	Media      MediaInfo  `json:"media"`
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+}
+
+// MovieFileVersion is a video a movie used before it was replaced, kept so
+// the replacement can be rolled back.
+type MovieFileVersion struct {
+	ID         int       `gorm:"primaryKey" json:"id"`
+	MovieID    int       `gorm:"not null;index" json:"movie_id"`
+	FilePath   string    `gorm:"type:varchar(255);not null" json:"file_path"`
+	MimeType   string    `gorm:"type:varchar(64)" json:"mime_type"`
+	Media      MediaInfo `gorm:"embedded;embeddedPrefix:media_" json:"media"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type MovieStats struct {
+	CreditCount   int64            `json:"credit_count"`
+	CreditsByRole map[string]int64 `json:"credits_by_role"`
+	GenreCount    int64            `json:"genre_count"`
+}
+
+// MovieDetail is a movie with the related data asked for by a MovieInclude.
+type MovieDetail struct {
+	Movie
+	Files []MovieFile `json:"files,omitempty"`
+	Stats *MovieStats `json:"stats,omitempty"`
 }
 
 type MovieFilter struct {
+	// Query is a full-text query matched against title, description,
+	// artists and genres. Results are ordered by relevance.
+	Query string
+	// Expr is a parsed filter query the movies must match.
+	Expr        search.Node
 	Title       string
 	Description string
 	Genres      []string
 	Artists     []string
-	Page        int
-	Limit       int
+	// Facets are the facets counted over the movies matching the filter.
+	Facets []string
+	// Sort orders the movies by its keys in turn, then by ID.
+	Sort  []MovieSort
+	Page  int
+	Limit int
+}
+
+// Sort keys of a movie search.
+const (
+	SortTitle     = "title"
+	SortDuration  = "duration"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortRelevance = "relevance"
+)
+
+// MovieSort orders movies by a sort key, descending if Desc.
+type MovieSort struct {
+	Key  string
+	Desc bool
+}
+
+// Facets of a movie search.
+const (
+	FacetGenre    = "genre"
+	FacetArtist   = "artist"
+	FacetDuration = "duration"
+	FacetYear     = "year"
+)
+
+// FacetCount is the number of movies having a value of a facet.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// MovieFacets maps the facets of a movie search to their counts.
+type MovieFacets map[string][]FacetCount
+
+// Kinds of search suggestions.
+const (
+	SuggestionTitle  = "title"
+	SuggestionArtist = "artist"
+	SuggestionGenre  = "genre"
+)
+
+// Suggestion is a known title, artist or genre completing a search.
+type Suggestion struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
 }
 
 func (Movie) TableName() string {
 	return "movies"
 }
 
+func (MovieFileVersion) TableName() string {
+	return "movie_file_versions"
+}
+
 func (f *MovieFilter) GetPage() int {
 	if f.Page <= 0 {
 		return 1
@@ -0,0 +1,71 @@
+package entity
+
+import "time"
+
+const (
+	RoleDirector = "director"
+	RoleActor    = "actor"
+	RoleWriter   = "writer"
+	RoleComposer = "composer"
+	RoleEditor   = "editor"
+)
+
+var CreditRoles = []string{RoleDirector, RoleActor, RoleWriter, RoleComposer, RoleEditor}
+
+type Person struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"type:varchar(255);not null;index" json:"name"`
+	Bio       string    `gorm:"type:text" json:"bio"`
+	Country   string    `gorm:"type:varchar(64)" json:"country"`
+	PhotoURL  string    `gorm:"type:varchar(255)" json:"photo_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Credit links a person to a movie in a given role.
+type Credit struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	MovieID   int       `gorm:"not null;uniqueIndex:idx_credit,priority:1" json:"movie_id"`
+	PersonID  int       `gorm:"not null;uniqueIndex:idx_credit,priority:2;index" json:"person_id"`
+	Role      string    `gorm:"type:varchar(32);not null;uniqueIndex:idx_credit,priority:3" json:"role"`
+	Movie     *Movie    `json:"movie,omitempty"`
+	Person    *Person   `json:"person,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PersonFilter struct {
+	Name  string
+	Page  int
+	Limit int
+}
+
+func (Person) TableName() string {
+	return "people"
+}
+
+func (Credit) TableName() string {
+	return "credits"
+}
+
+func IsCreditRole(role string) bool {
+	for _, r := range CreditRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *PersonFilter) GetPage() int {
+	if f.Page <= 0 {
+		return 1
+	}
+	return f.Page
+}
+
+func (f *PersonFilter) GetLimit() int {
+	if f.Limit <= 0 {
+		return 10
+	}
+	return f.Limit
+}
@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+type Upload struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)" json:"id"`
+	Length    int64     `gorm:"not null" json:"length"`
+	Offset    int64     `gorm:"not null;default:0" json:"offset"`
+	Metadata  string    `gorm:"type:text" json:"metadata"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Upload) TableName() string {
+	return "uploads"
+}
+
+func (u *Upload) IsComplete() bool {
+	return u.Offset >= u.Length
+}
+
+func (u *Upload) IsExpired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && now.After(u.ExpiresAt)
+}
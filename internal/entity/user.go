@@ -0,0 +1,64 @@
+package entity
+
+import "time"
+
+const (
+	UserRoleAdmin      = "admin"
+	UserRoleProgrammer = "programmer"
+	UserRoleJury       = "jury"
+	UserRoleViewer     = "viewer"
+)
+
+var UserRoles = []string{UserRoleAdmin, UserRoleProgrammer, UserRoleJury, UserRoleViewer}
+
+type User struct {
+	ID           int       `gorm:"primaryKey" json:"id"`
+	Email        string    `gorm:"type:varchar(255);not null;uniqueIndex" json:"email"`
+	Name         string    `gorm:"type:varchar(255)" json:"name"`
+	PasswordHash string    `gorm:"type:varchar(255);not null" json:"-"`
+	Role         string    `gorm:"type:varchar(16);not null;default:viewer" json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// RefreshToken records an issued refresh token by its JWT ID so it can be
+// rotated and revoked.
+type RefreshToken struct {
+	ID        string    `gorm:"type:varchar(64);primaryKey"`
+	UserID    int       `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"index"`
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RevokedToken blocks an access token until it would have expired anyway.
+type RevokedToken struct {
+	ID        string    `gorm:"type:varchar(64);primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (User) TableName() string {
+	return "users"
+}
+
+func IsUserRole(role string) bool {
+	for _, r := range UserRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,102 @@
+package genre
+
+import (
+	"context"
+	"fmt"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidGenre = apperror.New(apperror.ErrValidation, "invalid genre")
+
+// MaxNameLength is the size of the genres.name column.
+const MaxNameLength = 100
+
+type GenreFlowInterface interface {
+	ListGenres(ctx context.Context) ([]entity.Genre, error)
+	GetGenre(ctx context.Context, id int) (*entity.Genre, error)
+	CreateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error)
+	UpdateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error)
+	DeleteGenre(ctx context.Context, id int) error
+}
+
+type genreFlow struct {
+	genreRepo GenreRepository
+}
+
+func NewGenreFlow(genreRepo GenreRepository) GenreFlowInterface {
+	return &genreFlow{
+		genreRepo: genreRepo,
+	}
+}
+
+func (f *genreFlow) ListGenres(ctx context.Context) ([]entity.Genre, error) {
+	return f.genreRepo.ListGenres(ctx)
+}
+
+func (f *genreFlow) GetGenre(ctx context.Context, id int) (*entity.Genre, error) {
+	return f.genreRepo.GetGenre(ctx, id)
+}
+
+func (f *genreFlow) CreateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error) {
+	if err := auth.Authorize(ctx, auth.ActionManageGenres); err != nil {
+		return nil, err
+	}
+
+	if err := validateName(genre); err != nil {
+		return nil, err
+	}
+
+	currentTime := time.Now()
+	genre.CreatedAt = currentTime
+	genre.UpdatedAt = currentTime
+
+	return f.genreRepo.CreateGenre(ctx, genre)
+}
+
+func (f *genreFlow) UpdateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error) {
+	if err := auth.Authorize(ctx, auth.ActionManageGenres); err != nil {
+		return nil, err
+	}
+
+	if genre.ID == 0 {
+		return nil, fmt.Errorf("%w: genre ID is required", ErrInvalidGenre)
+	}
+
+	if err := validateName(genre); err != nil {
+		return nil, err
+	}
+
+	genre.UpdatedAt = time.Now()
+
+	return f.genreRepo.UpdateGenre(ctx, genre)
+}
+
+func (f *genreFlow) DeleteGenre(ctx context.Context, id int) error {
+	if err := auth.Authorize(ctx, auth.ActionManageGenres); err != nil {
+		return err
+	}
+
+	return f.genreRepo.DeleteGenre(ctx, id)
+}
+
+// validateName trims the name in place. Commas are rejected because genres
+// are still exchanged as comma separated lists on movies.
+func validateName(genre *entity.Genre) error {
+	genre.Name = strings.TrimSpace(genre.Name)
+
+	switch {
+	case genre.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidGenre)
+	case strings.Contains(genre.Name, ","):
+		return fmt.Errorf("%w: name must not contain commas", ErrInvalidGenre)
+	case utf8.RuneCountInString(genre.Name) > MaxNameLength:
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidGenre, MaxNameLength)
+	}
+
+	return nil
+}
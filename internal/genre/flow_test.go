@@ -0,0 +1,227 @@
+package genre
+
+import (
+	"context"
+	"errors"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+	"testing"
+)
+
+type MockGenreRepository struct {
+	genres []entity.Genre
+	err    error
+}
+
+func (m *MockGenreRepository) ListGenres(ctx context.Context) ([]entity.Genre, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.genres, nil
+}
+
+func (m *MockGenreRepository) GetGenre(ctx context.Context, id int) (*entity.Genre, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, g := range m.genres {
+		if g.ID == id {
+			genre := g
+			return &genre, nil
+		}
+	}
+
+	return nil, ErrGenreNotFound
+}
+
+func (m *MockGenreRepository) CreateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, g := range m.genres {
+		if strings.EqualFold(g.Name, genre.Name) {
+			return nil, ErrGenreExists
+		}
+	}
+
+	genre.ID = len(m.genres) + 1
+	m.genres = append(m.genres, *genre)
+
+	return genre, nil
+}
+
+func (m *MockGenreRepository) UpdateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for i, g := range m.genres {
+		if g.ID == genre.ID {
+			m.genres[i].Name = genre.Name
+			updated := m.genres[i]
+			return &updated, nil
+		}
+	}
+
+	return nil, ErrGenreNotFound
+}
+
+func (m *MockGenreRepository) DeleteGenre(ctx context.Context, id int) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	for i, g := range m.genres {
+		if g.ID == id {
+			m.genres = append(m.genres[:i], m.genres[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrGenreNotFound
+}
+
+func (m *MockGenreRepository) ResolveGenres(ctx context.Context, names []string) ([]entity.Genre, error) {
+	return nil, m.err
+}
+
+func contextWithRole(role string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: role})
+}
+
+func TestCreateGenre(t *testing.T) {
+	tests := []struct {
+		name     string
+		genre    entity.Genre
+		wantErr  error
+		wantName string
+	}{
+		{
+			name:     "success create genre",
+			genre:    entity.Genre{Name: "  Documentary "},
+			wantName: "Documentary",
+		},
+		{
+			name:    "fail - empty name",
+			genre:   entity.Genre{Name: "   "},
+			wantErr: ErrInvalidGenre,
+		},
+		{
+			name:    "fail - name with comma",
+			genre:   entity.Genre{Name: "Drama, Comedy"},
+			wantErr: ErrInvalidGenre,
+		},
+		{
+			name:    "fail - name too long",
+			genre:   entity.Genre{Name: strings.Repeat("a", MaxNameLength+1)},
+			wantErr: ErrInvalidGenre,
+		},
+		{
+			name:    "fail - duplicate name",
+			genre:   entity.Genre{Name: "drama"},
+			wantErr: ErrGenreExists,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := &MockGenreRepository{
+				genres: []entity.Genre{{ID: 1, Name: "Drama"}},
+			}
+			flow := NewGenreFlow(mockRepo)
+
+			genre, err := flow.CreateGenre(contextWithRole(entity.UserRoleAdmin), &test.genre)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("CreateGenre() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateGenre() error = %v", err)
+			}
+
+			if genre.Name != test.wantName {
+				t.Errorf("CreateGenre() name = %q, want %q", genre.Name, test.wantName)
+			}
+
+			if genre.CreatedAt.IsZero() || genre.UpdatedAt.IsZero() {
+				t.Error("CreateGenre() timestamps not set")
+			}
+		})
+	}
+}
+
+func TestUpdateGenre(t *testing.T) {
+	tests := []struct {
+		name    string
+		genre   entity.Genre
+		wantErr error
+	}{
+		{
+			name:  "success update genre",
+			genre: entity.Genre{ID: 1, Name: "Drama Film"},
+		},
+		{
+			name:    "fail - missing ID",
+			genre:   entity.Genre{Name: "Drama Film"},
+			wantErr: ErrInvalidGenre,
+		},
+		{
+			name:    "fail - not found",
+			genre:   entity.Genre{ID: 99, Name: "Drama Film"},
+			wantErr: ErrGenreNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := &MockGenreRepository{
+				genres: []entity.Genre{{ID: 1, Name: "Drama"}},
+			}
+			flow := NewGenreFlow(mockRepo)
+
+			genre, err := flow.UpdateGenre(contextWithRole(entity.UserRoleAdmin), &test.genre)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("UpdateGenre() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("UpdateGenre() error = %v", err)
+			}
+
+			if genre.Name != test.genre.Name {
+				t.Errorf("UpdateGenre() name = %q, want %q", genre.Name, test.genre.Name)
+			}
+		})
+	}
+}
+
+func TestSplitNames(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: "", want: nil},
+		{input: " , ,", want: nil},
+		{input: "Drama", want: []string{"Drama"}},
+		{input: " Drama , Documentary,drama", want: []string{"Drama", "Documentary"}},
+	}
+
+	for _, test := range tests {
+		got := SplitNames(test.input)
+		if strings.Join(got, "|") != strings.Join(test.want, "|") || len(got) != len(test.want) {
+			t.Errorf("SplitNames(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
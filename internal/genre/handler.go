@@ -0,0 +1,100 @@
+package genre
+
+import (
+	"net/http"
+	"roketin-case-study-challenge2/internal/constant"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/response"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type GenreHandler struct {
+	genreFlow GenreFlowInterface
+}
+
+func NewGenreHandler(genreFlow GenreFlowInterface) *GenreHandler {
+	return &GenreHandler{
+		genreFlow: genreFlow,
+	}
+}
+
+func (h *GenreHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListGenres)
+	r.Post("/", h.CreateGenre)
+	r.Get("/{id}", h.GetGenre)
+	r.Put("/{id}", h.UpdateGenre)
+	r.Delete("/{id}", h.DeleteGenre)
+
+	return r
+}
+
+func (h *GenreHandler) ListGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.genreFlow.ListGenres(r.Context())
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, genres)
+}
+
+func (h *GenreHandler) GetGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_GENRE_ID)
+		return
+	}
+
+	genre, err := h.genreFlow.GetGenre(r.Context(), id)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, genre)
+}
+
+func (h *GenreHandler) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	genre, err := h.genreFlow.CreateGenre(r.Context(), &entity.Genre{Name: r.PostFormValue("name")})
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, genre)
+}
+
+func (h *GenreHandler) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_GENRE_ID)
+		return
+	}
+
+	genre, err := h.genreFlow.UpdateGenre(r.Context(), &entity.Genre{ID: id, Name: r.PostFormValue("name")})
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, genre)
+}
+
+func (h *GenreHandler) DeleteGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_GENRE_ID)
+		return
+	}
+
+	if err := h.genreFlow.DeleteGenre(r.Context(), id); err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, constant.GENRE_DELETED_SUCCESSFULLY)
+}
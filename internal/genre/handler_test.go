@@ -0,0 +1,157 @@
+package genre
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/response"
+	"strings"
+	"testing"
+)
+
+func TestGenreHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		form       url.Values
+		role       string
+		mockError  error
+		wantStatus int
+		wantData   string
+	}{
+		{
+			name:       "list genres",
+			method:     http.MethodGet,
+			path:       "/",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "get genre",
+			method:     http.MethodGet,
+			path:       "/1",
+			wantStatus: http.StatusOK,
+			wantData:   "Drama",
+		},
+		{
+			name:       "get missing genre",
+			method:     http.MethodGet,
+			path:       "/99",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "get invalid ID",
+			method:     http.MethodGet,
+			path:       "/abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "create genre",
+			method:     http.MethodPost,
+			path:       "/",
+			form:       url.Values{"name": {"Documentary"}},
+			wantStatus: http.StatusOK,
+			wantData:   "Documentary",
+		},
+		{
+			name:       "create genre as viewer",
+			method:     http.MethodPost,
+			path:       "/",
+			form:       url.Values{"name": {"Documentary"}},
+			role:       entity.UserRoleViewer,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "list genres as viewer",
+			method:     http.MethodGet,
+			path:       "/",
+			role:       entity.UserRoleViewer,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "create duplicate genre",
+			method:     http.MethodPost,
+			path:       "/",
+			form:       url.Values{"name": {"DRAMA"}},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "create genre without name",
+			method:     http.MethodPost,
+			path:       "/",
+			form:       url.Values{},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "rename genre",
+			method:     http.MethodPut,
+			path:       "/1",
+			form:       url.Values{"name": {"Drama Film"}},
+			wantStatus: http.StatusOK,
+			wantData:   "Drama Film",
+		},
+		{
+			name:       "delete genre",
+			method:     http.MethodDelete,
+			path:       "/1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "delete missing genre",
+			method:     http.MethodDelete,
+			path:       "/99",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "repository failure",
+			method:     http.MethodGet,
+			path:       "/",
+			mockError:  fmt.Errorf("failed to get genres"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := &MockGenreRepository{
+				genres: []entity.Genre{{ID: 1, Name: "Drama"}},
+				err:    test.mockError,
+			}
+			handler := NewGenreHandler(NewGenreFlow(mockRepo))
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.form.Encode()))
+			if test.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			role := test.role
+			if role == "" {
+				role = entity.UserRoleProgrammer
+			}
+			req = req.WithContext(contextWithRole(role))
+			rr := httptest.NewRecorder()
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Fatalf("%s %s status = %v, want %v: %s", test.method, test.path, rr.Code, test.wantStatus, rr.Body.String())
+			}
+
+			if test.wantData == "" {
+				return
+			}
+
+			var resp response.Response
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+
+			data, ok := resp.Data.(map[string]interface{})
+			if !ok || data["name"] != test.wantData {
+				t.Errorf("%s %s data = %v, want name %q", test.method, test.path, resp.Data, test.wantData)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package genre
+
+import (
+	"context"
+	"fmt"
+	"roketin-case-study-challenge2/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+// MigrateMovieGenres links movies whose comma separated genres column has
+// not been split into the movie_genres join table yet. It is safe to run on
+// every start and returns the number of movies migrated.
+func MigrateMovieGenres(ctx context.Context, db *gorm.DB) (int, error) {
+	var movies []entity.Movie
+	err := db.WithContext(ctx).Unscoped().Select("id", "genres").
+		Where("genres <> ''").
+		Where("id NOT IN (?)", db.Table("movie_genres").Select("movie_id")).
+		Find(&movies).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find movies to migrate: %w", err)
+	}
+
+	repo := NewMySQLGenreRepository(db)
+	for i, movie := range movies {
+		genres, err := repo.ResolveGenres(ctx, SplitNames(string(movie.Genres)))
+		if err != nil {
+			return i, err
+		}
+
+		err = db.WithContext(ctx).Unscoped().Model(&movie).Association("GenreList").Append(genres)
+		if err != nil {
+			return i, fmt.Errorf("failed to link genres of movie %d: %w", movie.ID, err)
+		}
+	}
+
+	return len(movies), nil
+}
@@ -0,0 +1,58 @@
+package genre
+
+import (
+	"context"
+	"roketin-case-study-challenge2/internal"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+)
+
+var (
+	ErrGenreNotFound = apperror.New(apperror.ErrNotFound, "genre not found")
+	ErrGenreExists   = apperror.New(apperror.ErrConflict, "genre already exists")
+)
+
+type GenreRepository interface {
+	ListGenres(ctx context.Context) ([]entity.Genre, error)
+	GetGenre(ctx context.Context, id int) (*entity.Genre, error)
+	CreateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error)
+	UpdateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error)
+	DeleteGenre(ctx context.Context, id int) error
+	// ResolveGenres returns the genres with the given names, creating the
+	// ones that don't exist yet.
+	ResolveGenres(ctx context.Context, names []string) ([]entity.Genre, error)
+}
+
+// SplitNames splits a comma separated genre list, dropping blanks and
+// case-insensitive duplicates while keeping the original order.
+func SplitNames(csv string) []string {
+	cleaned := internal.CleanCsvString(csv)
+	if cleaned == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range strings.Split(cleaned, ",") {
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// JoinNames renders genres back into the comma separated form stored on
+// entity.Movie.Genres.
+func JoinNames(genres []entity.Genre) entity.NameList {
+	names := make([]string, len(genres))
+	for i, genre := range genres {
+		names[i] = genre.Name
+	}
+
+	return entity.NameList(strings.Join(names, ","))
+}
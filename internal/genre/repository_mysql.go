@@ -0,0 +1,199 @@
+package genre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type mySQLGenreRepository struct {
+	db *gorm.DB
+}
+
+func NewMySQLGenreRepository(db *gorm.DB) GenreRepository {
+	return &mySQLGenreRepository{
+		db: db,
+	}
+}
+
+func (r *mySQLGenreRepository) ListGenres(ctx context.Context) ([]entity.Genre, error) {
+	var genres []entity.Genre
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&genres).Error; err != nil {
+		return nil, fmt.Errorf("failed to get genres: %w", err)
+	}
+
+	return genres, nil
+}
+
+func (r *mySQLGenreRepository) GetGenre(ctx context.Context, id int) (*entity.Genre, error) {
+	var genre entity.Genre
+	err := r.db.WithContext(ctx).First(&genre, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGenreNotFound
+		}
+		return nil, fmt.Errorf("failed to get genre: %w", err)
+	}
+
+	return &genre, nil
+}
+
+func (r *mySQLGenreRepository) CreateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := ensureNameAvailable(tx, genre.Name, 0); err != nil {
+			return err
+		}
+
+		return tx.Create(genre).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrGenreExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create genre: %w", err)
+	}
+
+	return genre, nil
+}
+
+func (r *mySQLGenreRepository) UpdateGenre(ctx context.Context, genre *entity.Genre) (*entity.Genre, error) {
+	var updated entity.Genre
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&updated, genre.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGenreNotFound
+			}
+			return err
+		}
+
+		if err := ensureNameAvailable(tx, genre.Name, genre.ID); err != nil {
+			return err
+		}
+
+		if err := rewriteMovieGenres(tx, updated.ID, updated.Name, genre.Name); err != nil {
+			return err
+		}
+
+		updated.Name = genre.Name
+		updated.UpdatedAt = genre.UpdatedAt
+		return tx.Save(&updated).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrGenreNotFound) || errors.Is(err, ErrGenreExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update genre: %w", err)
+	}
+
+	return &updated, nil
+}
+
+func (r *mySQLGenreRepository) DeleteGenre(ctx context.Context, id int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var genre entity.Genre
+		if err := tx.First(&genre, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGenreNotFound
+			}
+			return err
+		}
+
+		if err := rewriteMovieGenres(tx, genre.ID, genre.Name, ""); err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM movie_genres WHERE genre_id = ?", genre.ID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&genre).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrGenreNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete genre: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mySQLGenreRepository) ResolveGenres(ctx context.Context, names []string) ([]entity.Genre, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	genres := make([]entity.Genre, 0, len(names))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			var genre entity.Genre
+			err := tx.Where("LOWER(name) = ?", strings.ToLower(name)).
+				Attrs(entity.Genre{Name: name}).
+				FirstOrCreate(&genre).Error
+			if err != nil {
+				return err
+			}
+			genres = append(genres, genre)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve genres: %w", err)
+	}
+
+	return genres, nil
+}
+
+func ensureNameAvailable(tx *gorm.DB, name string, exceptID int) error {
+	var count int64
+	err := tx.Model(&entity.Genre{}).
+		Where("LOWER(name) = ? AND id <> ?", strings.ToLower(name), exceptID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%w: %s", ErrGenreExists, name)
+	}
+
+	return nil
+}
+
+// rewriteMovieGenres keeps the denormalized movies.genres column in sync
+// when a genre is renamed, or removed when newName is empty.
+func rewriteMovieGenres(tx *gorm.DB, genreID int, oldName, newName string) error {
+	var movies []entity.Movie
+	err := tx.Unscoped().Select("id", "genres").
+		Where("id IN (?)", tx.Table("movie_genres").Select("movie_id").Where("genre_id = ?", genreID)).
+		Find(&movies).Error
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		var names []string
+		for _, name := range SplitNames(string(movie.Genres)) {
+			if strings.EqualFold(name, oldName) {
+				if newName == "" {
+					continue
+				}
+				name = newName
+			}
+			names = append(names, name)
+		}
+
+		err := tx.Unscoped().Model(&entity.Movie{}).
+			Where("id = ?", movie.ID).
+			UpdateColumn("genres", strings.Join(names, ",")).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{name: "replace value", target: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{name: "add value", target: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{name: "null removes", target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{name: "arrays are replaced", target: `{"a":["b","c"]}`, patch: `{"a":["d"]}`, want: `{"a":["d"]}`},
+		{name: "nested objects merge", target: `{"a":{"b":"c","d":"e"}}`, patch: `{"a":{"d":null,"f":"g"}}`, want: `{"a":{"b":"c","f":"g"}}`},
+		{name: "non object patch replaces", target: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Merge(decode(t, test.target), decode(t, test.patch))
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Merge() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		ops     string
+		want    string
+		wantErr error
+	}{
+		{name: "add member", doc: `{"a":1}`, ops: `[{"op":"add","path":"/b","value":null}]`, want: `{"a":1,"b":null}`},
+		{name: "add to array", doc: `{"a":["x","z"]}`, ops: `[{"op":"add","path":"/a/1","value":"y"},{"op":"add","path":"/a/-","value":"w"}]`, want: `{"a":["x","y","z","w"]}`},
+		{name: "remove", doc: `{"a":1,"b":2}`, ops: `[{"op":"remove","path":"/a"}]`, want: `{"b":2}`},
+		{name: "remove from array", doc: `{"a":["x","y"]}`, ops: `[{"op":"remove","path":"/a/0"}]`, want: `{"a":["y"]}`},
+		{name: "replace", doc: `{"a":{"b":1}}`, ops: `[{"op":"replace","path":"/a/b","value":2}]`, want: `{"a":{"b":2}}`},
+		{name: "move", doc: `{"a":{"b":1},"c":{}}`, ops: `[{"op":"move","from":"/a/b","path":"/c/d"}]`, want: `{"a":{},"c":{"d":1}}`},
+		{name: "copy", doc: `{"a":["x"]}`, ops: `[{"op":"copy","from":"/a","path":"/b"},{"op":"add","path":"/b/-","value":"y"}]`, want: `{"a":["x"],"b":["x","y"]}`},
+		{name: "escaped pointer", doc: `{"a/b":1,"m~n":2}`, ops: `[{"op":"remove","path":"/a~1b"},{"op":"replace","path":"/m~0n","value":3}]`, want: `{"m~n":3}`},
+		{name: "test passes", doc: `{"a":["x"]}`, ops: `[{"op":"test","path":"/a","value":["x"]}]`, want: `{"a":["x"]}`},
+		{name: "test fails", doc: `{"a":1}`, ops: `[{"op":"test","path":"/a","value":2}]`, wantErr: ErrTestFailed},
+		{name: "replace missing member", doc: `{"a":1}`, ops: `[{"op":"replace","path":"/b","value":2}]`, wantErr: ErrInvalidPatch},
+		{name: "index out of range", doc: `{"a":[]}`, ops: `[{"op":"add","path":"/a/1","value":2}]`, wantErr: ErrInvalidPatch},
+		{name: "move into itself", doc: `{"a":{"b":{}}}`, ops: `[{"op":"move","from":"/a","path":"/a/b/c"}]`, wantErr: ErrInvalidPatch},
+		{name: "unknown operation", doc: `{}`, ops: `[{"op":"merge","path":"/a"}]`, wantErr: ErrInvalidPatch},
+		{name: "missing value", doc: `{}`, ops: `[{"op":"add","path":"/a"}]`, wantErr: ErrInvalidPatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(test.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Apply(decode(t, test.doc), ops)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("Apply() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Apply() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -3,73 +3,471 @@ package movie
 import (
 	"context"
 	"fmt"
+	"math"
+	"path"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/constant"
 	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/genre"
+	"roketin-case-study-challenge2/internal/storage"
+	"strings"
+	"sync"
 	"time"
 )
 
 type MovieFlowInterface interface {
 	CreateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
+	// ListMovies replaces misspelled names of filter it corrected, so that
+	// ListMovieFacets counts the movies it listed.
 	ListMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error)
+	ListMovieFacets(ctx context.Context, filter *entity.MovieFilter) (entity.MovieFacets, error)
+	ListDeletedMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error)
+	SuggestMovies(ctx context.Context, prefix string, limit int) ([]entity.Suggestion, error)
+	GetMovie(ctx context.Context, id int) (*entity.Movie, error)
+	GetMovieDetail(ctx context.Context, id int, include entity.MovieInclude) (*entity.MovieDetail, error)
+	GetStreamableMovie(ctx context.Context, id int) (*entity.Movie, error)
 	UpdateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
-	DeleteMovie(ctx context.Context, id int) error
+	PatchMovie(ctx context.Context, id int, patch *MoviePatch) (*entity.Movie, error)
+	ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
+	DeleteMovie(ctx context.Context, id int, version int) error
+	RestoreMovie(ctx context.Context, id int) (*entity.Movie, error)
+	PurgeMovie(ctx context.Context, id int, version int) error
+	PurgeDeletedMovies(ctx context.Context, dryRun bool) (*PurgeReport, error)
+}
+
+// AnyVersion is the version of an "If-Match: *" precondition, which any
+// stored version satisfies. Version 0 means no precondition was given.
+const AnyVersion = -1
+
+// durationToleranceSeconds absorbs the rounding of whole minute durations.
+const durationToleranceSeconds = 60
+
+type MovieFlowConfig struct {
+	RejectDurationMismatch bool
+	RequireIfMatch         bool
+	// Retention is how long deleted movies are kept before they are purged.
+	Retention time.Duration
 }
 
 type movieFlow struct {
 	movieRepo MovieRepository
+	genreRepo genre.GenreRepository
+	storage   storage.Storage
+	cfg       MovieFlowConfig
+	now       func() time.Time
+
+	termsMu sync.Mutex
+	terms   *searchTerms
 }
 
-func NewMovieFlow(movieRepo MovieRepository) MovieFlowInterface {
+func NewMovieFlow(movieRepo MovieRepository, genreRepo genre.GenreRepository, storage storage.Storage, cfg MovieFlowConfig) MovieFlowInterface {
 	return &movieFlow{
 		movieRepo: movieRepo,
+		genreRepo: genreRepo,
+		storage:   storage,
+		cfg:       cfg,
+		now:       time.Now,
 	}
 }
 
 func (f *movieFlow) CreateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionCreateMovie); err != nil {
+		return nil, err
+	}
+
 	if movie.Title == "" {
-		return nil, fmt.Errorf("title is required")
+		return nil, apperror.Field("title", apperror.CodeRequired, "title is required")
+	}
+
+	if err := f.checkDuration(movie); err != nil {
+		return nil, err
 	}
 
-	currentTime := time.Now()
+	if err := f.resolveGenres(ctx, movie); err != nil {
+		return nil, err
+	}
+
+	currentTime := f.now()
+	movie.Version = 1
 	movie.CreatedAt = currentTime
 	movie.UpdatedAt = currentTime
 
-	createdMovie, err := f.movieRepo.CreateMovie(ctx, movie)
+	stagedPath := movie.FilePath
+	createdMovie, err := f.movieRepo.CreateMovie(ctx, movie, f.promoteMovieFile(ctx, movie))
 	if err != nil {
+		// The commit may have failed after the file was promoted.
+		if movie.FilePath != stagedPath {
+			f.storage.Delete(context.WithoutCancel(ctx), movie.FilePath)
+			movie.FilePath = stagedPath
+		}
 		return nil, err
 	}
 
+	f.invalidateSearchTerms()
 	return createdMovie, nil
 }
 
+// promoteMovieFile points a movie whose video is staged at its final key and
+// returns the move that puts the video there. It runs before the movie is
+// committed, so a listed movie never points at a missing file. Videos stored
+// anywhere else are used in place.
+func (f *movieFlow) promoteMovieFile(ctx context.Context, movie *entity.Movie) func() error {
+	stagedPath := movie.FilePath
+	if !strings.HasPrefix(stagedPath, constant.MOVIE_STAGING_PATH+"/") {
+		return nil
+	}
+
+	movie.FilePath = path.Join(constant.MOVIE_UPLOAD_PATH, path.Base(stagedPath))
+
+	return func() error {
+		if err := f.storage.Move(ctx, stagedPath, movie.FilePath); err != nil {
+			return fmt.Errorf("failed to promote movie file: %w", err)
+		}
+		return nil
+	}
+}
+
+func (f *movieFlow) checkDuration(movie *entity.Movie) error {
+	probed := movie.Media.DurationSeconds
+	if probed <= 0 {
+		return nil
+	}
+
+	if movie.Duration == 0 {
+		movie.Duration = int(math.Round(probed / 60))
+		return nil
+	}
+
+	movie.DurationMismatch = math.Abs(float64(movie.Duration*60)-probed) > durationToleranceSeconds
+	if movie.DurationMismatch && f.cfg.RejectDurationMismatch {
+		return apperror.Field("duration_minutes", apperror.CodeDurationMismatch, "duration_minutes %d does not match the video duration of %.1f minutes", movie.Duration, probed/60)
+	}
+
+	return nil
+}
+
+// resolveGenres links the movie to the genre records named in its comma
+// separated Genres and rewrites Genres with their canonical names.
+func (f *movieFlow) resolveGenres(ctx context.Context, movie *entity.Movie) error {
+	genres, err := f.genreRepo.ResolveGenres(ctx, genre.SplitNames(string(movie.Genres)))
+	if err != nil {
+		return err
+	}
+
+	movie.GenreList = genres
+	movie.Genres = genre.JoinNames(genres)
+	return nil
+}
+
 func (f *movieFlow) ListMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	if err := auth.Authorize(ctx, auth.ActionReadMovie); err != nil {
+		return nil, 0, err
+	}
+
 	movies, total, err := f.movieRepo.ListMovies(ctx, filter)
 	if err != nil {
 		return nil, 0, err
 	}
 
+	// A misspelled title, artist or genre matches nothing, so the search
+	// is retried once with the closest known names.
+	if total == 0 {
+		corrected, ok, err := f.correctFilter(ctx, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			*filter = *corrected
+			return f.movieRepo.ListMovies(ctx, filter)
+		}
+	}
+
 	return movies, total, nil
 }
 
+func (f *movieFlow) ListMovieFacets(ctx context.Context, filter *entity.MovieFilter) (entity.MovieFacets, error) {
+	if err := auth.Authorize(ctx, auth.ActionReadMovie); err != nil {
+		return nil, err
+	}
+
+	if len(filter.Facets) == 0 {
+		return entity.MovieFacets{}, nil
+	}
+
+	return f.movieRepo.ListMovieFacets(ctx, filter)
+}
+
+// ListDeletedMovies lists the trash, i.e. movies that can be restored.
+func (f *movieFlow) ListDeletedMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	if err := auth.Authorize(ctx, auth.ActionRestoreMovie); err != nil {
+		return nil, 0, err
+	}
+
+	return f.movieRepo.ListDeletedMovies(ctx, filter)
+}
+
+func (f *movieFlow) GetMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionReadMovie); err != nil {
+		return nil, err
+	}
+
+	movie, err := f.movieRepo.GetMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+// GetMovieDetail returns a movie with the related data selected by include.
+// File sizes are left to the caller, which has access to the storage.
+func (f *movieFlow) GetMovieDetail(ctx context.Context, id int, include entity.MovieInclude) (*entity.MovieDetail, error) {
+	movie, err := f.GetMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &entity.MovieDetail{Movie: *movie}
+
+	if include.Files {
+		if movie.FilePath != "" {
+			detail.Files = []entity.MovieFile{{
+				Path:     movie.FilePath,
+				MimeType: movie.MimeType,
+				Media:    movie.Media,
+			}}
+		}
+
+		versions, err := f.movieRepo.GetMovieFileVersions(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			replacedAt := version.ReplacedAt
+			detail.Files = append(detail.Files, entity.MovieFile{
+				Path:       version.FilePath,
+				MimeType:   version.MimeType,
+				Media:      version.Media,
+				ReplacedAt: &replacedAt,
+			})
+		}
+	}
+
+	if include.Credits {
+		detail.Credits, err = f.movieRepo.GetMovieCredits(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if include.Stats {
+		detail.Stats, err = f.movieRepo.GetMovieStats(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return detail, nil
+}
+
+// GetStreamableMovie returns a movie whose video the caller may watch.
+func (f *movieFlow) GetStreamableMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionStreamMovie); err != nil {
+		return nil, err
+	}
+
+	return f.movieRepo.GetMovie(ctx, id)
+}
+
 func (f *movieFlow) UpdateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionUpdateMovie); err != nil {
+		return nil, err
+	}
+
 	if movie.ID == 0 {
-		return nil, fmt.Errorf("movie ID is required")
+		return nil, apperror.Validation("movie ID is required")
+	}
+
+	version, err := f.checkVersion(movie.Version)
+	if err != nil {
+		return nil, err
+	}
+	movie.Version = version
+
+	if movie.Genres != "" {
+		if err := f.resolveGenres(ctx, movie); err != nil {
+			return nil, err
+		}
 	}
 
-	movie.UpdatedAt = time.Now()
+	movie.UpdatedAt = f.now()
 
 	updatedMovie, err := f.movieRepo.UpdateMovie(ctx, movie)
 	if err != nil {
 		return nil, err
 	}
 
+	f.invalidateSearchTerms()
 	return updatedMovie, nil
 }
 
-func (f *movieFlow) DeleteMovie(ctx context.Context, id int) error {
-	err := f.movieRepo.DeleteMovie(ctx, id)
+// PatchMovie applies patch to the metadata of a movie. Unlike UpdateMovie,
+// fields can be cleared, and the result is validated as on create.
+func (f *movieFlow) PatchMovie(ctx context.Context, id int, patch *MoviePatch) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionUpdateMovie); err != nil {
+		return nil, err
+	}
+
+	version, err := f.checkVersion(patch.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	movie, err := f.movieRepo.GetMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The movie is written back only if it is still at the version read
+	// here, so the patch never applies to a newer movie than it was based on.
+	if version > 0 && movie.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	doc, err := movieDocument(movie)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := patch.apply(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := &apperror.ValidationErrors{}
+	body := decodePatchedMovie(patched, errs)
+	if body.Title == "" {
+		errs.Add("title", apperror.CodeRequired, "title is required")
+	}
+
+	fields := body.toMovie(errs)
+	movie.Title = fields.Title
+	movie.Description = fields.Description
+	movie.Duration = fields.Duration
+	movie.Artists = fields.Artists
+	movie.Genres = fields.Genres
+
+	ValidateMovieFields(errs, movie)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	movie.DurationMismatch = false
+	if err := f.checkDuration(movie); err != nil {
+		return nil, err
+	}
+
+	if err := f.resolveGenres(ctx, movie); err != nil {
+		return nil, err
+	}
+	if movie.GenreList == nil {
+		movie.GenreList = []entity.Genre{}
+	}
+
+	movie.UpdatedAt = f.now()
+
+	replaced, err := f.movieRepo.ReplaceMovie(ctx, movie)
 	if err != nil {
+		return nil, err
+	}
+
+	f.invalidateSearchTerms()
+	return replaced, nil
+}
+
+// ReplaceMovieFile switches a movie to the video in movie.FilePath, which
+// must already be stored. The previous video is kept as a prior version.
+func (f *movieFlow) ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionUpdateMovie); err != nil {
+		return nil, err
+	}
+
+	if movie.FilePath == "" {
+		return nil, apperror.Field("movie_file", apperror.CodeRequired, "movie file is required")
+	}
+
+	version, err := f.checkVersion(movie.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := f.movieRepo.GetMovie(ctx, movie.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if version > 0 && current.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	// The declared duration is checked against the new video; the write is
+	// conditional on the version read here, like PatchMovie.
+	movie.Version = current.Version
+	movie.Duration = current.Duration
+	movie.DurationMismatch = false
+	if err := f.checkDuration(movie); err != nil {
+		return nil, err
+	}
+
+	movie.UpdatedAt = f.now()
+
+	return f.movieRepo.ReplaceMovieFile(ctx, movie)
+}
+
+func (f *movieFlow) DeleteMovie(ctx context.Context, id int, version int) error {
+	if err := auth.Authorize(ctx, auth.ActionDeleteMovie); err != nil {
 		return err
 	}
 
+	version, err := f.checkVersion(version)
+	if err != nil {
+		return err
+	}
+
+	err = f.movieRepo.DeleteMovie(ctx, id, version)
+	if err != nil {
+		return err
+	}
+
+	f.invalidateSearchTerms()
 	return nil
 }
+
+func (f *movieFlow) RestoreMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	if err := auth.Authorize(ctx, auth.ActionRestoreMovie); err != nil {
+		return nil, err
+	}
+
+	restored, err := f.movieRepo.RestoreMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	f.invalidateSearchTerms()
+	return restored, nil
+}
+
+// checkVersion enforces RequireIfMatch and returns the version a write must
+// match, 0 for any.
+func (f *movieFlow) checkVersion(version int) (int, error) {
+	if version == 0 && f.cfg.RequireIfMatch {
+		return 0, apperror.New(apperror.ErrPreconditionRequired, "If-Match with the movie version is required")
+	}
+
+	if version == AnyVersion {
+		return 0, nil
+	}
+
+	return version, nil
+}
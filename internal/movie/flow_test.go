@@ -2,21 +2,52 @@ package movie
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/auth"
 	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/genre"
+	"roketin-case-study-challenge2/internal/storage"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type MockMovieRepository struct {
-	movies []entity.Movie
-	err    error
+	movies       []entity.Movie
+	credits      []entity.Credit
+	fileVersions []entity.MovieFileVersion
+	deleted      []entity.Movie
+	err          error
+	// commitErr fails CreateMovie after its beforeCommit ran.
+	commitErr error
+	// purgeable, if set, is what ListPurgeableMovies returns, to stand for
+	// a list that went stale before the purge.
+	purgeable []entity.Movie
+	// termLoads counts the calls to ListSearchTerms.
+	termLoads int
 }
 
-func (m *MockMovieRepository) CreateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+func (m *MockMovieRepository) CreateMovie(ctx context.Context, movie *entity.Movie, beforeCommit func() error) (*entity.Movie, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
 
+	if beforeCommit != nil {
+		if err := beforeCommit(); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.commitErr != nil {
+		return nil, m.commitErr
+	}
+
 	movie.ID = len(m.movies) + 1
 	m.movies = append(m.movies, *movie)
 
@@ -28,7 +59,122 @@ func (m *MockMovieRepository) ListMovies(ctx context.Context, filter *entity.Mov
 		return nil, 0, m.err
 	}
 
-	return m.movies, int64(len(m.movies)), nil
+	if filter.Title == "" {
+		return m.movies, int64(len(m.movies)), nil
+	}
+
+	var movies []entity.Movie
+	for _, movie := range m.movies {
+		if strings.Contains(movie.Title, filter.Title) {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, int64(len(movies)), nil
+}
+
+func (m *MockMovieRepository) ListSearchTerms(ctx context.Context) ([]entity.Suggestion, error) {
+	m.termLoads++
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var terms []entity.Suggestion
+	for _, movie := range m.movies {
+		terms = append(terms, entity.Suggestion{Kind: entity.SuggestionTitle, Value: movie.Title})
+		for _, artist := range movie.Artists.Names() {
+			terms = append(terms, entity.Suggestion{Kind: entity.SuggestionArtist, Value: artist})
+		}
+		for _, name := range movie.Genres.Names() {
+			terms = append(terms, entity.Suggestion{Kind: entity.SuggestionGenre, Value: name})
+		}
+	}
+	return terms, nil
+}
+
+// ListMovieFacets counts the genres of the movies ListMovies finds, for
+// every facet asked for.
+func (m *MockMovieRepository) ListMovieFacets(ctx context.Context, filter *entity.MovieFilter) (entity.MovieFacets, error) {
+	movies, _, err := m.ListMovies(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	facets := entity.MovieFacets{}
+	for _, facet := range filter.Facets {
+		facets[facet] = countGenres(movies)
+	}
+	return facets, nil
+}
+
+// countGenres counts the movies per genre, the most frequent first.
+func countGenres(movies []entity.Movie) []entity.FacetCount {
+	counts := []entity.FacetCount{}
+	index := map[string]int{}
+	for _, movie := range movies {
+		for _, name := range movie.Genres.Names() {
+			if i, ok := index[name]; ok {
+				counts[i].Count++
+				continue
+			}
+			index[name] = len(counts)
+			counts = append(counts, entity.FacetCount{Value: name, Count: 1})
+		}
+	}
+
+	sort.SliceStable(counts, func(a, b int) bool { return counts[a].Count > counts[b].Count })
+	return counts
+}
+
+func (m *MockMovieRepository) ListDeletedMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	if m.err != nil {
+		return nil, 0, m.err
+	}
+
+	return m.deleted, int64(len(m.deleted)), nil
+}
+
+func (m *MockMovieRepository) GetMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, mov := range m.movies {
+		if mov.ID == id {
+			movie := mov
+			return &movie, nil
+		}
+	}
+
+	return nil, newMovieNotFoundError(id)
+}
+
+func (m *MockMovieRepository) GetMovieCredits(ctx context.Context, id int) ([]entity.Credit, error) {
+	var credits []entity.Credit
+	for _, credit := range m.credits {
+		if credit.MovieID == id {
+			credits = append(credits, credit)
+		}
+	}
+
+	return credits, nil
+}
+
+func (m *MockMovieRepository) GetMovieStats(ctx context.Context, id int) (*entity.MovieStats, error) {
+	stats := &entity.MovieStats{CreditsByRole: map[string]int64{}}
+	for _, credit := range m.credits {
+		if credit.MovieID == id {
+			stats.CreditCount++
+			stats.CreditsByRole[credit.Role]++
+		}
+	}
+
+	for _, mov := range m.movies {
+		if mov.ID == id {
+			stats.GenreCount = int64(len(genre.SplitNames(string(mov.Genres))))
+		}
+	}
+
+	return stats, nil
 }
 
 func (m *MockMovieRepository) UpdateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
@@ -59,7 +205,71 @@ func (m *MockMovieRepository) UpdateMovie(ctx context.Context, movie *entity.Mov
 	return nil, fmt.Errorf("movie with ID %d not found", movie.ID)
 }
 
-func (m *MockMovieRepository) DeleteMovie(ctx context.Context, id int) error {
+func (m *MockMovieRepository) ReplaceMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for i, mov := range m.movies {
+		if mov.ID == movie.ID {
+			if movie.Version > 0 && movie.Version != mov.Version {
+				return nil, ErrVersionMismatch
+			}
+			movie.Version = mov.Version + 1
+			m.movies[i] = *movie
+			return movie, nil
+		}
+	}
+
+	return nil, newMovieNotFoundError(movie.ID)
+}
+
+func (m *MockMovieRepository) ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for i, mov := range m.movies {
+		if mov.ID == movie.ID {
+			if movie.Version > 0 && movie.Version != mov.Version {
+				return nil, ErrVersionMismatch
+			}
+			if mov.FilePath != "" {
+				m.fileVersions = append([]entity.MovieFileVersion{{
+					ID:         len(m.fileVersions) + 1,
+					MovieID:    mov.ID,
+					FilePath:   mov.FilePath,
+					MimeType:   mov.MimeType,
+					Media:      mov.Media,
+					ReplacedAt: movie.UpdatedAt,
+				}}, m.fileVersions...)
+			}
+			mov.FilePath = movie.FilePath
+			mov.MimeType = movie.MimeType
+			mov.Media = movie.Media
+			mov.Duration = movie.Duration
+			mov.DurationMismatch = movie.DurationMismatch
+			mov.Version++
+			m.movies[i] = mov
+			return &mov, nil
+		}
+	}
+
+	return nil, newMovieNotFoundError(movie.ID)
+}
+
+func (m *MockMovieRepository) GetMovieFileVersions(ctx context.Context, id int) ([]entity.MovieFileVersion, error) {
+	var versions []entity.MovieFileVersion
+	for _, version := range m.fileVersions {
+		if version.MovieID == id {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
+
+func (m *MockMovieRepository) DeleteMovie(ctx context.Context, id int, version int) error {
 	if m.err != nil {
 		return m.err
 	}
@@ -79,6 +289,12 @@ func (m *MockMovieRepository) DeleteMovie(ctx context.Context, id int) error {
 
 	for i, mov := range m.movies {
 		if mov.ID == id {
+			if version > 0 && version != mov.Version {
+				return ErrVersionMismatch
+			}
+			mov.DeletedAt = &gorm.DeletedAt{Time: time.Now(), Valid: true}
+			mov.Version++
+			m.deleted = append(m.deleted, mov)
 			m.movies = append(m.movies[:i], m.movies[i+1:]...)
 			return nil
 		}
@@ -87,6 +303,134 @@ func (m *MockMovieRepository) DeleteMovie(ctx context.Context, id int) error {
 	return fmt.Errorf("movie with ID %d not found", id)
 }
 
+func (m *MockMovieRepository) RestoreMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for i, mov := range m.deleted {
+		if mov.ID == id {
+			mov.DeletedAt = nil
+			mov.Version++
+			m.deleted = append(m.deleted[:i], m.deleted[i+1:]...)
+			m.movies = append(m.movies, mov)
+			return &mov, nil
+		}
+	}
+
+	if _, err := m.GetMovie(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrMovieNotDeleted
+}
+
+func (m *MockMovieRepository) ListPurgeableMovies(ctx context.Context, deletedBefore time.Time) ([]entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.purgeable != nil {
+		return m.purgeable, nil
+	}
+
+	var movies []entity.Movie
+	for _, mov := range m.deleted {
+		if mov.DeletedAt.Time.Before(deletedBefore) {
+			movies = append(movies, mov)
+		}
+	}
+
+	return movies, nil
+}
+
+func (m *MockMovieRepository) PurgeDeletedMovie(ctx context.Context, id int, deletedBefore time.Time) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, mov := range m.deleted {
+		if mov.ID == id && mov.DeletedAt.Time.Before(deletedBefore) {
+			return m.PurgeMovie(ctx, id, 0)
+		}
+	}
+	return nil, ErrMovieNotPurgeable
+}
+
+func (m *MockMovieRepository) PurgeMovie(ctx context.Context, id int, version int) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var paths []string
+	found := false
+	for _, list := range []*[]entity.Movie{&m.movies, &m.deleted} {
+		for i, mov := range *list {
+			if mov.ID == id {
+				if version > 0 && version != mov.Version {
+					return nil, ErrVersionMismatch
+				}
+				if mov.FilePath != "" {
+					paths = append(paths, mov.FilePath)
+				}
+				*list = append((*list)[:i], (*list)[i+1:]...)
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, newMovieNotFoundError(id)
+	}
+
+	var kept []entity.MovieFileVersion
+	for _, version := range m.fileVersions {
+		if version.MovieID == id {
+			paths = append(paths, version.FilePath)
+		} else {
+			kept = append(kept, version)
+		}
+	}
+	m.fileVersions = kept
+
+	return paths, nil
+}
+
+type MockGenreRepository struct {
+	genre.GenreRepository
+	genres []entity.Genre
+	err    error
+}
+
+func (m *MockGenreRepository) ResolveGenres(ctx context.Context, names []string) ([]entity.Genre, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var resolved []entity.Genre
+	for _, name := range names {
+		found := false
+		for _, g := range m.genres {
+			if strings.EqualFold(g.Name, name) {
+				resolved = append(resolved, g)
+				found = true
+				break
+			}
+		}
+		if !found {
+			g := entity.Genre{ID: len(m.genres) + 1, Name: name}
+			m.genres = append(m.genres, g)
+			resolved = append(resolved, g)
+		}
+	}
+
+	return resolved, nil
+}
+
+func contextWithRole(role string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: role})
+}
+
 func TestCreateMovie(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -141,9 +485,11 @@ func TestCreateMovie(t *testing.T) {
 				err: test.mockError,
 			}
 
-			flow := NewMovieFlow(mockRepo)
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			flow.(*movieFlow).now = func() time.Time { return now }
 
-			movie, err := flow.CreateMovie(context.Background(), &test.movie)
+			movie, err := flow.CreateMovie(contextWithRole(entity.UserRoleAdmin), &test.movie)
 
 			if (err != nil) != test.wantErr {
 				t.Errorf("CreateMovie() error = %v, wantErr %v", err, test.wantErr)
@@ -164,7 +510,61 @@ func TestCreateMovie(t *testing.T) {
 			}
 
 			if movie == nil {
-				t.Error("CreateMovie() got nil, want non-nil")
+				t.Fatal("CreateMovie() got nil, want non-nil")
+			}
+			if !movie.CreatedAt.Equal(now) || !movie.UpdatedAt.Equal(now) {
+				t.Errorf("CreateMovie() created at %v, updated at %v, want %v", movie.CreatedAt, movie.UpdatedAt, now)
+			}
+		})
+	}
+}
+
+func TestCreateMoviePromotesFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		stage     bool
+		commitErr error
+		wantErr   bool
+		wantFiles []string
+	}{
+		{name: "success promotes the staged file", stage: true, wantFiles: []string{"uploads/1-film.mp4"}},
+		{name: "fail - commit removes the promoted file", stage: true, commitErr: fmt.Errorf("commit failed"), wantErr: true},
+		{name: "fail - missing staged file rolls back", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := contextWithRole(entity.UserRoleAdmin)
+			store := storage.NewLocalStorage(t.TempDir())
+			if test.stage {
+				store.Put(ctx, "uploads/staging/1-film.mp4", strings.NewReader("film"), 4)
+			}
+
+			mockRepo := &MockMovieRepository{commitErr: test.commitErr}
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, store, MovieFlowConfig{})
+
+			movie, err := flow.CreateMovie(ctx, &entity.Movie{Title: "Film", FilePath: "uploads/staging/1-film.mp4"})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("CreateMovie() error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				if len(mockRepo.movies) != 0 {
+					t.Errorf("CreateMovie() stored %v, want nothing", mockRepo.movies)
+				}
+			} else if movie.FilePath != "uploads/1-film.mp4" {
+				t.Errorf("CreateMovie() file path = %v, want uploads/1-film.mp4", movie.FilePath)
+			}
+
+			objects, _ := store.List(ctx, "uploads/")
+			var files []string
+			for _, object := range objects {
+				if object.Key != "uploads/staging/1-film.mp4" {
+					files = append(files, object.Key)
+				}
+			}
+			if strings.Join(files, ",") != strings.Join(test.wantFiles, ",") {
+				t.Errorf("CreateMovie() left files %v, want %v", files, test.wantFiles)
 			}
 		})
 	}
@@ -256,9 +656,9 @@ func TestListMovies(t *testing.T) {
 				movies: test.mockData,
 				err:    test.mockError,
 			}
-			flow := NewMovieFlow(mockRepo)
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
 
-			movies, total, err := flow.ListMovies(context.Background(), test.filter)
+			movies, total, err := flow.ListMovies(contextWithRole(entity.UserRoleAdmin), test.filter)
 
 			if (err != nil) != test.wantErr {
 				t.Errorf("ListMovies() error = %v, wantErr %v", err, test.wantErr)
@@ -357,9 +757,9 @@ func TestUpdateMovie(t *testing.T) {
 			mockRepo := &MockMovieRepository{
 				err: test.mockError,
 			}
-			flow := NewMovieFlow(mockRepo)
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
 
-			movie, err := flow.UpdateMovie(context.Background(), test.movie)
+			movie, err := flow.UpdateMovie(contextWithRole(entity.UserRoleAdmin), test.movie)
 
 			if (err != nil) != test.wantErr {
 				t.Errorf("UpdateMovie() error = %v, wantErr %v", err, test.wantErr)
@@ -420,9 +820,9 @@ func TestDeleteMovie(t *testing.T) {
 			mockRepo := &MockMovieRepository{
 				err: test.mockError,
 			}
-			flow := NewMovieFlow(mockRepo)
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
 
-			err := flow.DeleteMovie(context.Background(), test.id)
+			err := flow.DeleteMovie(contextWithRole(entity.UserRoleAdmin), test.id, 0)
 
 			if (err != nil) != test.wantErr {
 				t.Errorf("DeleteMovie() error = %v, wantErr %v", err, test.wantErr)
@@ -442,3 +842,775 @@ func TestDeleteMovie(t *testing.T) {
 		})
 	}
 }
+
+func TestGetMovieDetail(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{
+			{ID: 1, Title: "Movie", Genres: "Drama, Comedy", FilePath: "uploads/movie.mp4", MimeType: "video/mp4"},
+			{ID: 2, Title: "No File"},
+		},
+		credits: []entity.Credit{
+			{ID: 1, MovieID: 1, PersonID: 1, Role: entity.RoleDirector},
+			{ID: 2, MovieID: 1, PersonID: 2, Role: entity.RoleActor},
+			{ID: 3, MovieID: 1, PersonID: 3, Role: entity.RoleActor},
+			{ID: 4, MovieID: 2, PersonID: 1, Role: entity.RoleActor},
+		},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+	ctx := contextWithRole(entity.UserRoleViewer)
+
+	detail, err := flow.GetMovieDetail(ctx, 1, entity.MovieInclude{})
+	if err != nil {
+		t.Fatalf("GetMovieDetail() error = %v", err)
+	}
+	if detail.Files != nil || detail.Credits != nil || detail.Stats != nil {
+		t.Errorf("GetMovieDetail() without include = %+v, want no related data", detail)
+	}
+
+	detail, err = flow.GetMovieDetail(ctx, 1, entity.MovieInclude{Files: true, Credits: true, Stats: true})
+	if err != nil {
+		t.Fatalf("GetMovieDetail() error = %v", err)
+	}
+	if len(detail.Files) != 1 || detail.Files[0].Path != "uploads/movie.mp4" {
+		t.Errorf("GetMovieDetail() files = %+v", detail.Files)
+	}
+	if len(detail.Credits) != 3 {
+		t.Errorf("GetMovieDetail() credits = %d, want 3", len(detail.Credits))
+	}
+	if detail.Stats.CreditCount != 3 || detail.Stats.CreditsByRole[entity.RoleActor] != 2 || detail.Stats.GenreCount != 2 {
+		t.Errorf("GetMovieDetail() stats = %+v", detail.Stats)
+	}
+
+	detail, err = flow.GetMovieDetail(ctx, 2, entity.MovieInclude{Files: true})
+	if err != nil {
+		t.Fatalf("GetMovieDetail() error = %v", err)
+	}
+	if len(detail.Files) != 0 {
+		t.Errorf("GetMovieDetail() files of movie without file = %+v", detail.Files)
+	}
+
+	if _, err := flow.GetMovieDetail(ctx, 99, entity.MovieInclude{}); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("GetMovieDetail() missing movie error = %v, want ErrMovieNotFound", err)
+	}
+}
+
+func TestPatchMovie(t *testing.T) {
+	original := entity.Movie{
+		ID:          1,
+		Title:       "Original Movie",
+		Description: "Original Description",
+		Duration:    120,
+		Artists:     "Jane Doe, John Roe",
+		Genres:      "Action,Drama",
+	}
+
+	tests := []struct {
+		name       string
+		patch      string
+		jsonPatch  bool
+		wantErr    error
+		wantFields []string
+		wantMovie  entity.Movie
+		wantGenres int
+	}{
+		{
+			name:       "merge patch clears fields",
+			patch:      `{"description": null, "duration_minutes": 0, "artists": [], "genres": null}`,
+			wantMovie:  entity.Movie{Title: "Original Movie"},
+			wantGenres: 0,
+		},
+		{
+			name:       "merge patch replaces lists",
+			patch:      `{"title": "New Title", "genres": ["comedy"]}`,
+			wantMovie:  entity.Movie{Title: "New Title", Description: "Original Description", Duration: 120, Artists: "Jane Doe,John Roe", Genres: "comedy"},
+			wantGenres: 1,
+		},
+		{
+			name:       "json patch appends genre",
+			patch:      `[{"op": "test", "path": "/title", "value": "Original Movie"}, {"op": "add", "path": "/genres/-", "value": "Comedy"}, {"op": "remove", "path": "/artists/0"}]`,
+			jsonPatch:  true,
+			wantMovie:  entity.Movie{Title: "Original Movie", Description: "Original Description", Duration: 120, Artists: "John Roe", Genres: "Action,Drama,Comedy"},
+			wantGenres: 3,
+		},
+		{
+			name:       "fail - title cleared",
+			patch:      `{"title": null, "duration_minutes": -1}`,
+			wantErr:    apperror.ErrValidation,
+			wantFields: []string{"title:required", "duration_minutes:out_of_range"},
+		},
+		{
+			name:       "fail - unknown and mistyped fields",
+			patch:      `{"rating": 5, "artists": "Jane Doe"}`,
+			wantErr:    apperror.ErrValidation,
+			wantFields: []string{"rating:invalid", "artists:invalid"},
+		},
+		{
+			name:      "fail - json patch test",
+			patch:     `[{"op": "test", "path": "/title", "value": "Other"}]`,
+			jsonPatch: true,
+			wantErr:   apperror.ErrConflict,
+		},
+		{
+			name:      "fail - json patch missing path",
+			patch:     `[{"op": "replace", "path": "/rating", "value": 5}]`,
+			jsonPatch: true,
+			wantErr:   apperror.ErrValidation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := &MockMovieRepository{movies: []entity.Movie{original}}
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+
+			patch := &MoviePatch{}
+			var err error
+			if test.jsonPatch {
+				err = json.Unmarshal([]byte(test.patch), &patch.Operations)
+			} else {
+				err = json.Unmarshal([]byte(test.patch), &patch.Merge)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			movie, err := flow.PatchMovie(contextWithRole(entity.UserRoleProgrammer), 1, patch)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("PatchMovie() error = %v, want %v", err, test.wantErr)
+				}
+				var verr *apperror.ValidationErrors
+				if len(test.wantFields) > 0 && errors.As(err, &verr) {
+					var got []string
+					for _, field := range verr.Fields {
+						got = append(got, field.Field+":"+field.Code)
+					}
+					if strings.Join(got, ",") != strings.Join(test.wantFields, ",") {
+						t.Errorf("PatchMovie() field errors = %v, want %v", got, test.wantFields)
+					}
+				}
+				if mockRepo.movies[0].Title != original.Title {
+					t.Error("PatchMovie() saved a movie despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PatchMovie() error = %v", err)
+			}
+
+			if movie.Title != test.wantMovie.Title || movie.Description != test.wantMovie.Description ||
+				movie.Duration != test.wantMovie.Duration || movie.Artists != test.wantMovie.Artists ||
+				movie.Genres != test.wantMovie.Genres {
+				t.Errorf("PatchMovie() = %+v, want %+v", movie, test.wantMovie)
+			}
+
+			if movie.GenreList == nil || len(movie.GenreList) != test.wantGenres {
+				t.Errorf("PatchMovie() genre list = %v, want %d genres", movie.GenreList, test.wantGenres)
+			}
+		})
+	}
+}
+
+func TestMovieFlowVersions(t *testing.T) {
+	ctx := contextWithRole(entity.UserRoleProgrammer)
+	merge := map[string]interface{}{"description": "Patched"}
+
+	mockRepo := &MockMovieRepository{movies: []entity.Movie{{ID: 1, Title: "Movie", Version: 2}}}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+
+	if _, err := flow.PatchMovie(ctx, 1, &MoviePatch{Merge: merge, Version: 1}); !errors.Is(err, apperror.ErrPreconditionFailed) {
+		t.Errorf("PatchMovie() with stale version error = %v, want ErrPreconditionFailed", err)
+	}
+
+	movie, err := flow.PatchMovie(ctx, 1, &MoviePatch{Merge: merge, Version: 2})
+	if err != nil {
+		t.Fatalf("PatchMovie() error = %v", err)
+	}
+	if movie.Version != 3 {
+		t.Errorf("PatchMovie() version = %d, want 3", movie.Version)
+	}
+
+	if _, err := flow.PatchMovie(ctx, 1, &MoviePatch{Merge: merge, Version: AnyVersion}); err != nil {
+		t.Errorf("PatchMovie() with any version error = %v", err)
+	}
+
+	if err := flow.DeleteMovie(ctx, 1, 3); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("DeleteMovie() with stale version error = %v, want ErrVersionMismatch", err)
+	}
+
+	strict := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{RequireIfMatch: true})
+
+	if _, err := strict.PatchMovie(ctx, 1, &MoviePatch{Merge: merge}); !errors.Is(err, apperror.ErrPreconditionRequired) {
+		t.Errorf("PatchMovie() without version error = %v, want ErrPreconditionRequired", err)
+	}
+	if _, err := strict.UpdateMovie(ctx, &entity.Movie{ID: 1, Title: "Movie"}); !errors.Is(err, apperror.ErrPreconditionRequired) {
+		t.Errorf("UpdateMovie() without version error = %v, want ErrPreconditionRequired", err)
+	}
+	if err := strict.DeleteMovie(ctx, 1, 0); !errors.Is(err, apperror.ErrPreconditionRequired) {
+		t.Errorf("DeleteMovie() without version error = %v, want ErrPreconditionRequired", err)
+	}
+	if err := strict.DeleteMovie(ctx, 1, AnyVersion); err != nil {
+		t.Errorf("DeleteMovie() with any version error = %v", err)
+	}
+}
+
+func TestReplaceMovieFile(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{{ID: 1, Title: "Movie", Duration: 10, FilePath: "uploads/old.mp4", MimeType: "video/mp4", Version: 2}},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{RejectDurationMismatch: true})
+	ctx := contextWithRole(entity.UserRoleProgrammer)
+
+	newFile := func(version int, seconds float64) *entity.Movie {
+		return &entity.Movie{
+			ID:       1,
+			FilePath: "uploads/new.mkv",
+			MimeType: "video/x-matroska",
+			Media:    entity.MediaInfo{DurationSeconds: seconds},
+			Version:  version,
+		}
+	}
+
+	if _, err := flow.ReplaceMovieFile(contextWithRole(entity.UserRoleViewer), newFile(0, 600)); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("ReplaceMovieFile() as viewer error = %v, want ErrForbidden", err)
+	}
+
+	if _, err := flow.ReplaceMovieFile(ctx, newFile(1, 600)); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("ReplaceMovieFile() with stale version error = %v, want ErrVersionMismatch", err)
+	}
+
+	if _, err := flow.ReplaceMovieFile(ctx, newFile(2, 3600)); !errors.Is(err, apperror.ErrValidation) {
+		t.Errorf("ReplaceMovieFile() with mismatching duration error = %v, want ErrValidation", err)
+	}
+
+	if _, err := flow.ReplaceMovieFile(ctx, &entity.Movie{ID: 99, FilePath: "uploads/new.mkv"}); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("ReplaceMovieFile() of missing movie error = %v, want ErrMovieNotFound", err)
+	}
+
+	movie, err := flow.ReplaceMovieFile(ctx, newFile(2, 600))
+	if err != nil {
+		t.Fatalf("ReplaceMovieFile() error = %v", err)
+	}
+	if movie.FilePath != "uploads/new.mkv" || movie.Version != 3 || movie.Duration != 10 {
+		t.Errorf("ReplaceMovieFile() = %+v", movie)
+	}
+
+	detail, err := flow.GetMovieDetail(ctx, 1, entity.MovieInclude{Files: true})
+	if err != nil {
+		t.Fatalf("GetMovieDetail() error = %v", err)
+	}
+	if len(detail.Files) != 2 || detail.Files[0].ReplacedAt != nil || detail.Files[1].Path != "uploads/old.mp4" || detail.Files[1].ReplacedAt == nil {
+		t.Errorf("GetMovieDetail() files = %+v, want the new file and the replaced one", detail.Files)
+	}
+}
+
+func TestRestoreMovie(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{{ID: 1, Title: "Kept"}, {ID: 2, Title: "Deleted"}},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+	ctx := contextWithRole(entity.UserRoleProgrammer)
+
+	if err := flow.DeleteMovie(ctx, 2, 0); err != nil {
+		t.Fatalf("DeleteMovie() error = %v", err)
+	}
+
+	if _, _, err := flow.ListDeletedMovies(contextWithRole(entity.UserRoleViewer), &entity.MovieFilter{}); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("ListDeletedMovies() as viewer error = %v, want ErrForbidden", err)
+	}
+
+	trash, total, err := flow.ListDeletedMovies(ctx, &entity.MovieFilter{})
+	if err != nil {
+		t.Fatalf("ListDeletedMovies() error = %v", err)
+	}
+	if total != 1 || trash[0].ID != 2 || trash[0].DeletedAt == nil {
+		t.Errorf("ListDeletedMovies() = %+v, want the deleted movie", trash)
+	}
+
+	if _, err := flow.RestoreMovie(ctx, 1); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("RestoreMovie() of movie in use error = %v, want ErrConflict", err)
+	}
+	if _, err := flow.RestoreMovie(ctx, 99); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("RestoreMovie() of missing movie error = %v, want ErrMovieNotFound", err)
+	}
+
+	movie, err := flow.RestoreMovie(ctx, 2)
+	if err != nil {
+		t.Fatalf("RestoreMovie() error = %v", err)
+	}
+	if movie.DeletedAt != nil {
+		t.Errorf("RestoreMovie() deleted_at = %v, want none", movie.DeletedAt)
+	}
+	if _, err := flow.GetMovie(ctx, 2); err != nil {
+		t.Errorf("GetMovie() after restore error = %v", err)
+	}
+}
+
+func TestPurgeMovie(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewLocalStorage(t.TempDir())
+	for _, key := range []string{"uploads/current.mp4", "uploads/previous.mp4"} {
+		store.Put(ctx, key, strings.NewReader("video"), 5)
+	}
+
+	mockRepo := &MockMovieRepository{
+		movies:       []entity.Movie{{ID: 1, Title: "Movie", FilePath: "uploads/current.mp4", Version: 2}},
+		fileVersions: []entity.MovieFileVersion{{ID: 1, MovieID: 1, FilePath: "uploads/previous.mp4"}},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, store, MovieFlowConfig{})
+
+	if err := flow.PurgeMovie(contextWithRole(entity.UserRoleProgrammer), 1, 0); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("PurgeMovie() as programmer error = %v, want ErrForbidden", err)
+	}
+
+	admin := contextWithRole(entity.UserRoleAdmin)
+	if err := flow.PurgeMovie(admin, 1, 1); !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("PurgeMovie() with stale version error = %v, want ErrVersionMismatch", err)
+	}
+
+	if err := flow.PurgeMovie(admin, 1, 2); err != nil {
+		t.Fatalf("PurgeMovie() error = %v", err)
+	}
+
+	if _, err := mockRepo.GetMovie(ctx, 1); !errors.Is(err, ErrMovieNotFound) {
+		t.Errorf("GetMovie() after purge error = %v, want ErrMovieNotFound", err)
+	}
+	objects, _ := store.List(ctx, "uploads/")
+	if len(objects) != 0 {
+		t.Errorf("stored objects after purge = %+v, want none", objects)
+	}
+}
+
+func TestPurgeDeletedMovies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := func(age time.Duration) *gorm.DeletedAt {
+		return &gorm.DeletedAt{Time: now.Add(-age), Valid: true}
+	}
+
+	store := storage.NewLocalStorage(t.TempDir())
+	for _, key := range []string{"uploads/old.mp4", "uploads/recent.mp4"} {
+		store.Put(ctx, key, strings.NewReader("video"), 5)
+	}
+
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{{ID: 3, Title: "Active"}},
+		deleted: []entity.Movie{
+			{ID: 1, Title: "Old", FilePath: "uploads/old.mp4", DeletedAt: deletedAt(48 * time.Hour)},
+			{ID: 2, Title: "Recent", FilePath: "uploads/recent.mp4", DeletedAt: deletedAt(time.Hour)},
+		},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, store, MovieFlowConfig{Retention: 24 * time.Hour})
+	flow.(*movieFlow).now = func() time.Time { return now }
+
+	report, err := flow.PurgeDeletedMovies(ctx, true)
+	if err != nil {
+		t.Fatalf("PurgeDeletedMovies() dry run error = %v", err)
+	}
+	if !report.DryRun || len(report.Movies) != 1 || report.Movies[0].ID != 1 || len(report.Movies[0].Files) != 1 {
+		t.Errorf("PurgeDeletedMovies() dry run report = %+v", report)
+	}
+	if len(mockRepo.deleted) != 2 {
+		t.Errorf("PurgeDeletedMovies() dry run removed movies, %d left", len(mockRepo.deleted))
+	}
+
+	report, err = flow.PurgeDeletedMovies(ctx, false)
+	if err != nil {
+		t.Fatalf("PurgeDeletedMovies() error = %v", err)
+	}
+	if report.DryRun || len(report.Movies) != 1 || report.Movies[0].ID != 1 {
+		t.Errorf("PurgeDeletedMovies() report = %+v", report)
+	}
+	if len(mockRepo.deleted) != 1 || mockRepo.deleted[0].ID != 2 || len(mockRepo.movies) != 1 {
+		t.Errorf("PurgeDeletedMovies() left deleted = %+v, movies = %+v", mockRepo.deleted, mockRepo.movies)
+	}
+
+	objects, _ := store.List(ctx, "uploads/")
+	if len(objects) != 1 || objects[0].Key != "uploads/recent.mp4" {
+		t.Errorf("stored objects after purge = %+v, want only the recent file", objects)
+	}
+}
+
+func TestPurgeDeletedMoviesSkipsRestoredMovies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := &gorm.DeletedAt{Time: now.Add(-48 * time.Hour), Valid: true}
+
+	store := storage.NewLocalStorage(t.TempDir())
+	store.Put(ctx, "uploads/restored.mp4", strings.NewReader("video"), 5)
+
+	// Movie 1 was listed for purging, then restored before it was purged.
+	mockRepo := &MockMovieRepository{
+		movies:    []entity.Movie{{ID: 1, Title: "Restored", FilePath: "uploads/restored.mp4"}},
+		deleted:   []entity.Movie{{ID: 2, Title: "Old", DeletedAt: deletedAt}},
+		purgeable: []entity.Movie{{ID: 1, Title: "Restored", DeletedAt: deletedAt}, {ID: 2, Title: "Old", DeletedAt: deletedAt}},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, store, MovieFlowConfig{Retention: 24 * time.Hour})
+	flow.(*movieFlow).now = func() time.Time { return now }
+
+	report, err := flow.PurgeDeletedMovies(ctx, false)
+	if err != nil {
+		t.Fatalf("PurgeDeletedMovies() error = %v", err)
+	}
+	if len(report.Movies) != 1 || report.Movies[0].ID != 2 {
+		t.Errorf("PurgeDeletedMovies() report = %+v, want only movie 2", report)
+	}
+	if len(mockRepo.movies) != 1 || len(mockRepo.deleted) != 0 {
+		t.Errorf("PurgeDeletedMovies() left movies = %+v, deleted = %+v", mockRepo.movies, mockRepo.deleted)
+	}
+	if _, err := store.Stat(ctx, "uploads/restored.mp4"); err != nil {
+		t.Errorf("video of the restored movie was deleted: %v", err)
+	}
+}
+
+func TestCreateMovieDurationCheck(t *testing.T) {
+	tests := []struct {
+		name             string
+		duration         int
+		probedSeconds    float64
+		reject           bool
+		wantErr          bool
+		wantDuration     int
+		wantDurationFlag bool
+	}{
+		{
+			name:          "matching duration",
+			duration:      12,
+			probedSeconds: 12*60 + 20,
+			wantDuration:  12,
+		},
+		{
+			name:          "missing duration is taken from file",
+			duration:      0,
+			probedSeconds: 754,
+			wantDuration:  13,
+		},
+		{
+			name:             "mismatch is flagged",
+			duration:         90,
+			probedSeconds:    754,
+			wantDuration:     90,
+			wantDurationFlag: true,
+		},
+		{
+			name:          "mismatch is rejected",
+			duration:      90,
+			probedSeconds: 754,
+			reject:        true,
+			wantErr:       true,
+		},
+		{
+			name:         "unprobed file is not checked",
+			duration:     90,
+			reject:       true,
+			wantDuration: 90,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flow := NewMovieFlow(&MockMovieRepository{}, &MockGenreRepository{}, nil, MovieFlowConfig{RejectDurationMismatch: test.reject})
+
+			movie, err := flow.CreateMovie(contextWithRole(entity.UserRoleAdmin), &entity.Movie{
+				Title:    "Test Movie",
+				Duration: test.duration,
+				Media:    entity.MediaInfo{DurationSeconds: test.probedSeconds},
+			})
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("CreateMovie() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			if movie.Duration != test.wantDuration {
+				t.Errorf("CreateMovie() duration = %v, want %v", movie.Duration, test.wantDuration)
+			}
+			if movie.DurationMismatch != test.wantDurationFlag {
+				t.Errorf("CreateMovie() duration mismatch = %v, want %v", movie.DurationMismatch, test.wantDurationFlag)
+			}
+		})
+	}
+}
+
+func TestCreateMovieResolvesGenres(t *testing.T) {
+	genreRepo := &MockGenreRepository{
+		genres: []entity.Genre{{ID: 1, Name: "Drama"}},
+	}
+	flow := NewMovieFlow(&MockMovieRepository{}, genreRepo, nil, MovieFlowConfig{})
+
+	movie, err := flow.CreateMovie(contextWithRole(entity.UserRoleAdmin), &entity.Movie{
+		Title:  "Test Movie",
+		Genres: " drama , Comedy,DRAMA",
+	})
+	if err != nil {
+		t.Fatalf("CreateMovie() error = %v", err)
+	}
+
+	if movie.Genres != "Drama,Comedy" {
+		t.Errorf("CreateMovie() genres = %q, want %q", movie.Genres, "Drama,Comedy")
+	}
+
+	if len(movie.GenreList) != 2 || movie.GenreList[0].ID != 1 || movie.GenreList[1].ID != 2 {
+		t.Errorf("CreateMovie() genre list = %+v, want Drama(1) and Comedy(2)", movie.GenreList)
+	}
+}
+
+func TestMovieFlowAuthorization(t *testing.T) {
+	tests := []struct {
+		role        string
+		canList     bool
+		canStream   bool
+		canCreate   bool
+		canUpdate   bool
+		canDelete   bool
+		description string
+	}{
+		{role: entity.UserRoleAdmin, canList: true, canStream: true, canCreate: true, canUpdate: true, canDelete: true},
+		{role: entity.UserRoleProgrammer, canList: true, canStream: true, canCreate: true, canUpdate: true, canDelete: true},
+		{role: entity.UserRoleJury, canList: true, canStream: true},
+		{role: entity.UserRoleViewer, canList: true},
+		{role: "", description: "no principal"},
+	}
+
+	for _, test := range tests {
+		name := test.role
+		if test.description != "" {
+			name = test.description
+		}
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.role != "" {
+				ctx = contextWithRole(test.role)
+			}
+
+			mockRepo := &MockMovieRepository{
+				movies: []entity.Movie{{ID: 1, Title: "Existing Movie"}},
+			}
+			flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+
+			denied := auth.ErrForbidden
+			if test.role == "" {
+				denied = apperror.ErrUnauthorized
+			}
+
+			check := func(action string, allowed bool, err error) {
+				if allowed && err != nil {
+					t.Errorf("%s error = %v, want allowed", action, err)
+				}
+				if !allowed && !errors.Is(err, denied) {
+					t.Errorf("%s error = %v, want %v", action, err, denied)
+				}
+			}
+
+			_, _, err := flow.ListMovies(ctx, &entity.MovieFilter{})
+			check("ListMovies", test.canList, err)
+
+			_, err = flow.GetStreamableMovie(ctx, 1)
+			check("GetStreamableMovie", test.canStream, err)
+
+			_, err = flow.UpdateMovie(ctx, &entity.Movie{ID: 1, Title: "Updated"})
+			check("UpdateMovie", test.canUpdate, err)
+
+			_, err = flow.CreateMovie(ctx, &entity.Movie{Title: "New Movie"})
+			check("CreateMovie", test.canCreate, err)
+
+			err = flow.DeleteMovie(ctx, 1, 0)
+			check("DeleteMovie", test.canDelete, err)
+		})
+	}
+}
+
+func TestSuggestMovies(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{
+			{ID: 1, Title: "Amélie", Artists: "Audrey Tautou", Genres: "Comedy"},
+			{ID: 2, Title: "American Beauty", Artists: "Kevin Spacey", Genres: "Drama, Comedy"},
+			{ID: 3, Title: "2001: A Space Odyssey", Artists: "Keir Dullea", Genres: "Sci-Fi"},
+		},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+	ctx := contextWithRole(entity.UserRoleViewer)
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []entity.Suggestion
+	}{
+		{
+			name:   "titles without accents",
+			prefix: "ame",
+			limit:  10,
+			want: []entity.Suggestion{
+				{Kind: entity.SuggestionTitle, Value: "Amélie"},
+				{Kind: entity.SuggestionTitle, Value: "American Beauty"},
+			},
+		},
+		{
+			name:   "typo in a later word",
+			prefix: "odysey",
+			limit:  10,
+			want:   []entity.Suggestion{{Kind: entity.SuggestionTitle, Value: "2001: A Space Odyssey"}},
+		},
+		{
+			name:   "genres are suggested once",
+			prefix: "comed",
+			limit:  10,
+			want:   []entity.Suggestion{{Kind: entity.SuggestionGenre, Value: "Comedy"}},
+		},
+		{
+			name:   "artists",
+			prefix: "ke",
+			limit:  10,
+			want: []entity.Suggestion{
+				{Kind: entity.SuggestionArtist, Value: "Keir Dullea"},
+				{Kind: entity.SuggestionArtist, Value: "Kevin Spacey"},
+			},
+		},
+		{
+			name:   "limit",
+			prefix: "ame",
+			limit:  1,
+			want:   []entity.Suggestion{{Kind: entity.SuggestionTitle, Value: "Amélie"}},
+		},
+		{
+			name:   "no match",
+			prefix: "western",
+			limit:  10,
+			want:   []entity.Suggestion{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := flow.SuggestMovies(ctx, test.prefix, test.limit)
+			if err != nil {
+				t.Fatalf("SuggestMovies() error = %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("SuggestMovies() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := flow.SuggestMovies(context.Background(), "ame", 10); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Errorf("SuggestMovies() without user error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestListMoviesCorrectsTypos(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{
+			{ID: 1, Title: "Amélie"},
+			{ID: 2, Title: "2001: A Space Odyssey"},
+		},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+	ctx := contextWithRole(entity.UserRoleViewer)
+
+	tests := []struct {
+		title   string
+		wantIDs []int
+	}{
+		{title: "Odyssey", wantIDs: []int{2}},
+		{title: "amelie", wantIDs: []int{1}},
+		{title: "space odysey", wantIDs: []int{2}},
+		{title: "western", wantIDs: nil},
+	}
+
+	for _, test := range tests {
+		movies, total, err := flow.ListMovies(ctx, &entity.MovieFilter{Title: test.title})
+		if err != nil {
+			t.Fatalf("ListMovies(%q) error = %v", test.title, err)
+		}
+
+		var ids []int
+		for _, movie := range movies {
+			ids = append(ids, movie.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(test.wantIDs) || total != int64(len(test.wantIDs)) {
+			t.Errorf("ListMovies(%q) = %v, %d, want %v", test.title, ids, total, test.wantIDs)
+		}
+	}
+}
+
+func TestSuggestMoviesCachesSearchTerms(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{
+			{ID: 1, Title: "Amélie", Artists: "Audrey Tautou", Genres: "Comedy"},
+			{ID: 2, Title: "Heat", Artists: "Al Pacino", Genres: "Crime"},
+		},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	flow.(*movieFlow).now = func() time.Time { return now }
+	ctx := contextWithRole(entity.UserRoleAdmin)
+
+	suggest := func(prefix string) []entity.Suggestion {
+		t.Helper()
+		suggestions, err := flow.SuggestMovies(ctx, prefix, 10)
+		if err != nil {
+			t.Fatalf("SuggestMovies() error = %v", err)
+		}
+		return suggestions
+	}
+
+	suggest("ame")
+	suggest("hea")
+	if _, _, err := flow.ListMovies(ctx, &entity.MovieFilter{Title: "amelie"}); err != nil {
+		t.Fatalf("ListMovies() error = %v", err)
+	}
+	if mockRepo.termLoads != 1 {
+		t.Fatalf("search terms loaded %d times, want 1", mockRepo.termLoads)
+	}
+
+	if err := flow.DeleteMovie(ctx, 2, 0); err != nil {
+		t.Fatalf("DeleteMovie() error = %v", err)
+	}
+	if got := suggest("hea"); len(got) != 0 {
+		t.Errorf("suggestions after delete = %v, want none", got)
+	}
+	if mockRepo.termLoads != 2 {
+		t.Fatalf("search terms loaded %d times after delete, want 2", mockRepo.termLoads)
+	}
+
+	now = now.Add(searchTermsMaxAge)
+	suggest("ame")
+	if mockRepo.termLoads != 3 {
+		t.Fatalf("search terms loaded %d times after expiry, want 3", mockRepo.termLoads)
+	}
+}
+
+func TestListMovieFacets(t *testing.T) {
+	mockRepo := &MockMovieRepository{
+		movies: []entity.Movie{
+			{ID: 1, Title: "Amélie", Genres: "Comedy, Romance"},
+			{ID: 2, Title: "American Beauty", Genres: "Drama"},
+		},
+	}
+	flow := NewMovieFlow(mockRepo, &MockGenreRepository{}, nil, MovieFlowConfig{})
+	ctx := contextWithRole(entity.UserRoleViewer)
+
+	filter := &entity.MovieFilter{Title: "amelie", Facets: []string{entity.FacetGenre}}
+	if _, _, err := flow.ListMovies(ctx, filter); err != nil {
+		t.Fatalf("ListMovies() error = %v", err)
+	}
+
+	// The facets count the movies found with the corrected title.
+	facets, err := flow.ListMovieFacets(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListMovieFacets() error = %v", err)
+	}
+	want := entity.MovieFacets{entity.FacetGenre: {{Value: "Comedy", Count: 1}, {Value: "Romance", Count: 1}}}
+	if fmt.Sprint(facets) != fmt.Sprint(want) {
+		t.Errorf("ListMovieFacets() = %v, want %v", facets, want)
+	}
+
+	if _, err := flow.ListMovieFacets(context.Background(), filter); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Errorf("ListMovieFacets() without user error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -1,113 +1,414 @@
 package movie
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"roketin-case-study-challenge2/internal"
-	"roketin-case-study-challenge2/internal/response"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/auth"
 	"roketin-case-study-challenge2/internal/constant"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/probe"
+	"roketin-case-study-challenge2/internal/response"
+	"roketin-case-study-challenge2/internal/storage"
+	"roketin-case-study-challenge2/internal/stream"
+	"roketin-case-study-challenge2/internal/upload"
 
 	"strconv"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 )
 
 type MovieHandler struct {
 	movieParser MovieParserInterface
 	movieFlow   MovieFlowInterface
+	storage     storage.Storage
+	uploadFlow  upload.UploadFlowInterface
 }
 
-func NewMovieHandler(movieParser MovieParserInterface, movieFlow MovieFlowInterface) *MovieHandler {
+func NewMovieHandler(movieParser MovieParserInterface, movieFlow MovieFlowInterface, storage storage.Storage, uploadFlow upload.UploadFlowInterface) *MovieHandler {
 	return &MovieHandler{
 		movieParser: movieParser,
 		movieFlow:   movieFlow,
+		storage:     storage,
+		uploadFlow:  uploadFlow,
 	}
 }
 
 func (h *MovieHandler) Routes() chi.Router {
 	r := chi.NewRouter()
 
+	// Uploading, claiming and streaming a video take as long as the video
+	// needs, so these routes have no request timeout.
 	r.Post("/", h.CreateMovie)
-	r.Get("/", h.ListMovies)
-	r.Get("/search", h.SearchMovies)
-	r.Put("/{id}", h.UpdateMovie)
-	r.Delete("/{id}", h.DeleteMovie)
+	r.Get("/{id}/stream", h.StreamMovie)
+	r.Head("/{id}/stream", h.StreamMovie)
+	r.Put("/{id}/file", h.ReplaceMovieFile)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(constant.REQUEST_TIMEOUT))
+
+		r.Get("/", h.ListMovies)
+		r.Get("/search", h.SearchMovies)
+		r.Get("/trash", h.ListDeletedMovies)
+		r.Get("/suggest", h.SuggestMovies)
+		r.Get("/{id}", h.GetMovie)
+		r.Put("/{id}", h.UpdateMovie)
+		r.Patch("/{id}", h.PatchMovie)
+		r.Delete("/{id}", h.DeleteMovie)
+		r.Post("/{id}/restore", h.RestoreMovie)
+	})
 
 	return r
 }
 
 func (h *MovieHandler) CreateMovie(w http.ResponseWriter, r *http.Request) {
+	createdMovie, status, err := h.createMovie(r)
+	if err != nil {
+		response.ErrorWithStatus(w, status, err)
+		return
+	}
+
+	response.Success(w, createdMovie)
+}
+
+// createMovie stages the video and lets movieFlow promote it together with
+// the new row. A video still staged when it returns, even by a panic, is
+// removed. CreateMovie writes the one reply from its results.
+func (h *MovieHandler) createMovie(r *http.Request) (createdMovie *entity.Movie, status int, err error) {
 	ctx := r.Context()
 
+	// Checked before the video is stored; movieFlow enforces it again.
+	if err := auth.Authorize(ctx, auth.ActionCreateMovie); err != nil {
+		return nil, response.StatusFromError(err), err
+	}
+
 	movieData, file, err := h.movieParser.ParseCreateMovie(r)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, parseErrorStatus(err), err
 	}
 
-	filePath, err := internal.SaveUploadedFile(file, "uploads")
+	status, err = h.storeMovieFile(ctx, movieData, file, constant.MOVIE_STAGING_PATH)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
-		return
+		return nil, status, err
 	}
 
-	movieData.FilePath = filePath
+	stagedPath := movieData.FilePath
+	defer func() {
+		if createdMovie == nil {
+			h.discardMovieFile(ctx, stagedPath)
+		}
+	}()
+
+	movieData.Media = h.probeMovieFile(ctx, movieData.FilePath)
 
-	createdMovie, err := h.movieFlow.CreateMovie(ctx, movieData)
+	createdMovie, err = h.movieFlow.CreateMovie(ctx, movieData)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		return nil, response.StatusFromError(err), err
 	}
 
-	response.Success(w, createdMovie)
+	return createdMovie, http.StatusOK, nil
+}
+
+func (h *MovieHandler) storeMovieFile(ctx context.Context, movie *entity.Movie, file *MovieFileInput, basePath string) (int, error) {
+	if file.Header != nil {
+		filePath, err := internal.SaveUploadedFile(ctx, h.storage, file.Header, basePath)
+		if err != nil {
+			return http.StatusInternalServerError, err
+		}
+		movie.FilePath = filePath
+		return http.StatusOK, nil
+	}
+
+	pending, err := h.uploadFlow.GetUpload(ctx, file.UploadID)
+	if err != nil {
+		if errors.Is(err, upload.ErrUploadNotFound) || errors.Is(err, upload.ErrUploadExpired) {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	fileName := upload.FileName(pending)
+	if err := ValidateMovieFileName(fileName); err != nil {
+		errs := &apperror.ValidationErrors{}
+		errs.AddError("upload_id", apperror.CodeExtensionNotAllowed, err)
+		return http.StatusUnprocessableEntity, errs
+	}
+
+	filePath, err := h.uploadFlow.ClaimUpload(ctx, file.UploadID, basePath)
+	if err != nil {
+		if errors.Is(err, upload.ErrUploadIncomplete) {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	sniffed := false
+	defer func() {
+		if !sniffed {
+			h.discardMovieFile(ctx, filePath)
+		}
+	}()
+
+	mimeType, err := h.sniffStoredFile(ctx, filePath, fileName)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			errs := &apperror.ValidationErrors{}
+			errs.AddError("upload_id", apperror.CodeUnsupportedMediaType, err)
+			return http.StatusUnsupportedMediaType, errs
+		}
+		return http.StatusInternalServerError, err
+	}
+	sniffed = true
+
+	movie.FilePath = filePath
+	movie.MimeType = mimeType
+	return http.StatusOK, nil
+}
+
+// discardMovieFile removes a video that no movie ended up pointing at. It
+// also runs when the client went away, so it does not use its cancellation.
+func (h *MovieHandler) discardMovieFile(ctx context.Context, filePath string) {
+	if err := h.storage.Delete(context.WithoutCancel(ctx), filePath); err != nil {
+		log.Printf("Failed to delete movie file %s: %v", filePath, err)
+	}
+}
+
+// eachIfMatchVersion runs write with the versions of an If-Match list in
+// turn until one matches the movie, so that the list matches if any of its
+// entity tags does. Without If-Match write runs once with version 0.
+func eachIfMatchVersion(versions []int, write func(version int) error) error {
+	if len(versions) == 0 {
+		return write(0)
+	}
+
+	var err error
+	for _, version := range versions {
+		if err = write(version); !errors.Is(err, ErrVersionMismatch) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// parseErrorStatus returns 415 for a video that is not what it claims to be,
+// 422 for invalid fields, 412 for an If-Match no version can satisfy and 400
+// for a request that could not be read. A video rejected along with other
+// fields is reported as 422, so that none of them is hidden behind the 415.
+func parseErrorStatus(err error) int {
+	var fields *apperror.ValidationErrors
+	switch {
+	case errors.As(err, &fields) && len(fields.Fields) > 1:
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, apperror.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperror.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (h *MovieHandler) sniffStoredFile(ctx context.Context, filePath, fileName string) (string, error) {
+	rc, err := h.storage.GetRange(ctx, filePath, 0, probe.SniffLength)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	header, err := io.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+
+	return DetectMovieMimeType(header, fileName)
+}
+
+func (h *MovieHandler) probeMovieFile(ctx context.Context, filePath string) entity.MediaInfo {
+	info, err := h.storage.Stat(ctx, filePath)
+	if err != nil {
+		log.Printf("Failed to stat movie file %s: %v", filePath, err)
+		return entity.MediaInfo{}
+	}
+
+	metadata, err := probe.Probe(storage.NewReaderAt(ctx, h.storage, filePath, info.Size), info.Size)
+	if err != nil {
+		log.Printf("Failed to probe movie file %s: %v", filePath, err)
+		return entity.MediaInfo{}
+	}
+
+	return entity.MediaInfo{
+		Container:       metadata.Container,
+		DurationSeconds: metadata.Duration.Seconds(),
+		Width:           metadata.Width,
+		Height:          metadata.Height,
+		VideoCodec:      metadata.VideoCodec,
+		AudioCodec:      metadata.AudioCodec,
+		Bitrate:         metadata.Bitrate,
+		FrameRate:       metadata.FrameRate,
+	}
 }
 
 func (h *MovieHandler) ListMovies(w http.ResponseWriter, r *http.Request) {
+	h.listMovies(w, r)
+}
+
+func (h *MovieHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
+	h.listMovies(w, r)
+}
+
+// listMovies writes a page of the movies matching the filter, with the
+// counts of the facets asked for by ?facets=.
+func (h *MovieHandler) listMovies(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	filter, err := h.movieParser.ParseMovieFilter(r)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
+		response.ErrorWithStatus(w, http.StatusBadRequest, err)
 		return
 	}
 
 	movies, total, err := h.movieFlow.ListMovies(ctx, filter)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		response.ErrorFrom(w, err)
 		return
 	}
 
-	pagination := response.Pagination{
-		CurrentPage: filter.GetPage(),
-		PerPage: filter.GetLimit(),
-		TotalItems: total,
-		TotalPages: int((total + int64(filter.GetLimit()) - 1) / int64(filter.GetLimit())),
+	if len(filter.Facets) == 0 {
+		response.SuccessWithPagination(w, movies, moviePagination(filter, total))
+		return
 	}
 
-	response.SuccessWithPagination(w, movies, pagination)
+	facets, err := h.movieFlow.ListMovieFacets(ctx, filter)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.SuccessWithFacets(w, movies, moviePagination(filter, total), facets)
 }
 
-func (h *MovieHandler) SearchMovies(w http.ResponseWriter, r *http.Request) {
+// ListDeletedMovies lists soft deleted movies with the filters of ListMovies.
+func (h *MovieHandler) ListDeletedMovies(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	filter, err := h.movieParser.ParseMovieFilter(r)
+	if err != nil {
+		response.ErrorWithStatus(w, http.StatusBadRequest, err)
+		return
+	}
+
+	movies, total, err := h.movieFlow.ListDeletedMovies(ctx, filter)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.SuccessWithPagination(w, movies, moviePagination(filter, total))
+}
+
+func (h *MovieHandler) SuggestMovies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	prefix, limit, err := h.movieParser.ParseSuggest(r)
 	if err != nil {
 		response.Error(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	movies, total, err := h.movieFlow.ListMovies(ctx, filter)
+	suggestions, err := h.movieFlow.SuggestMovies(ctx, prefix, limit)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		response.ErrorFrom(w, err)
 		return
 	}
 
-	pagination := response.Pagination{
+	response.Success(w, suggestions)
+}
+
+func moviePagination(filter *entity.MovieFilter, total int64) response.Pagination {
+	return response.Pagination{
 		CurrentPage: filter.GetPage(),
-		PerPage: filter.GetLimit(),
-		TotalItems: total,
-		TotalPages: int((total + int64(filter.GetLimit()) - 1) / int64(filter.GetLimit())),
+		PerPage:     filter.GetLimit(),
+		TotalItems:  total,
+		TotalPages:  int((total + int64(filter.GetLimit()) - 1) / int64(filter.GetLimit())),
+	}
+}
+
+func (h *MovieHandler) GetMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_MOVIE_ID)
+		return
+	}
+
+	include, err := h.movieParser.ParseMovieInclude(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	response.SuccessWithPagination(w, movies, pagination)
+	movie, err := h.movieFlow.GetMovieDetail(ctx, id, include)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", MovieETag(movie.Version))
+
+	for i := range movie.Files {
+		info, err := h.storage.Stat(ctx, movie.Files[i].Path)
+		if err != nil {
+			log.Printf("Failed to stat movie file %s: %v", movie.Files[i].Path, err)
+			continue
+		}
+		movie.Files[i].Size = info.Size
+	}
+
+	response.Success(w, movie)
+}
+
+func (h *MovieHandler) StreamMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_MOVIE_ID)
+		return
+	}
+
+	movie, err := h.movieFlow.GetStreamableMovie(ctx, id)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	if movie.FilePath == "" {
+		response.Error(w, http.StatusNotFound, constant.ERROR_MOVIE_FILE_NOT_FOUND)
+		return
+	}
+
+	info, err := h.storage.Stat(ctx, movie.FilePath)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			response.Error(w, http.StatusNotFound, constant.ERROR_MOVIE_FILE_NOT_FOUND)
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := stream.ServeObject(w, r, h.storage, info); err != nil {
+		log.Printf("Failed to stream movie %d: %v", id, err)
+	}
 }
 
 func (h *MovieHandler) UpdateMovie(w http.ResponseWriter, r *http.Request) {
@@ -119,20 +420,131 @@ func (h *MovieHandler) UpdateMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	versions, err := h.movieParser.ParseIfMatch(r)
+	if err != nil {
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
+		return
+	}
+
 	request, err := h.movieParser.ParseUpdateMovie(r)
 	if err != nil {
-		response.Error(w, http.StatusBadRequest, err.Error())
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
 		return
 	}
 
 	request.ID = id
 
-	updatedMovie, err := h.movieFlow.UpdateMovie(ctx, request)
+	var updatedMovie *entity.Movie
+	err = eachIfMatchVersion(versions, func(version int) error {
+		request.Version = version
+		updatedMovie, err = h.movieFlow.UpdateMovie(ctx, request)
+		return err
+	})
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		response.ErrorFrom(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", MovieETag(updatedMovie.Version))
+	response.Success(w, updatedMovie)
+}
+
+func (h *MovieHandler) PatchMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_MOVIE_ID)
+		return
+	}
+
+	versions, err := h.movieParser.ParseIfMatch(r)
+	if err != nil {
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
+		return
+	}
+
+	patch, err := h.movieParser.ParseMoviePatch(r)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			w.Header().Set("Accept-Patch", AcceptPatch)
+		}
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
+		return
+	}
+
+	var patchedMovie *entity.Movie
+	err = eachIfMatchVersion(versions, func(version int) error {
+		patch.Version = version
+		patchedMovie, err = h.movieFlow.PatchMovie(ctx, id, patch)
+		return err
+	})
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", MovieETag(patchedMovie.Version))
+	response.Success(w, patchedMovie)
+}
+
+// ReplaceMovieFile stores a new video for a movie. The previous video stays
+// in storage as a prior version; the new one is removed again if the movie
+// cannot be updated.
+func (h *MovieHandler) ReplaceMovieFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Checked before the video is stored; movieFlow enforces it again.
+	if err := auth.Authorize(ctx, auth.ActionUpdateMovie); err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_MOVIE_ID)
+		return
+	}
+
+	versions, err := h.movieParser.ParseIfMatch(r)
+	if err != nil {
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
+		return
+	}
+
+	movieData, file, err := h.movieParser.ParseMovieFile(r)
+	if err != nil {
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
+		return
+	}
+
+	status, err := h.storeMovieFile(ctx, movieData, file, constant.MOVIE_UPLOAD_PATH)
+	if err != nil {
+		response.ErrorWithStatus(w, status, err)
+		return
+	}
+
+	var updatedMovie *entity.Movie
+	defer func() {
+		if updatedMovie == nil {
+			h.discardMovieFile(ctx, movieData.FilePath)
+		}
+	}()
+
+	movieData.ID = id
+	movieData.Media = h.probeMovieFile(ctx, movieData.FilePath)
+
+	err = eachIfMatchVersion(versions, func(version int) error {
+		movieData.Version = version
+		updatedMovie, err = h.movieFlow.ReplaceMovieFile(ctx, movieData)
+		return err
+	})
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", MovieETag(updatedMovie.Version))
 	response.Success(w, updatedMovie)
 }
 
@@ -145,11 +557,57 @@ func (h *MovieHandler) DeleteMovie(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.movieFlow.DeleteMovie(ctx, id)
+	versions, err := h.movieParser.ParseIfMatch(r)
 	if err != nil {
-		response.Error(w, http.StatusInternalServerError, err.Error())
+		response.ErrorWithStatus(w, parseErrorStatus(err), err)
+		return
+	}
+
+	permanent, err := h.movieParser.ParsePermanent(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if permanent {
+		err := eachIfMatchVersion(versions, func(version int) error {
+			return h.movieFlow.PurgeMovie(ctx, id, version)
+		})
+		if err != nil {
+			response.ErrorFrom(w, err)
+			return
+		}
+
+		response.Success(w, constant.MOVIE_PURGED_SUCCESSFULLY)
+		return
+	}
+
+	err = eachIfMatchVersion(versions, func(version int) error {
+		return h.movieFlow.DeleteMovie(ctx, id, version)
+	})
+	if err != nil {
+		response.ErrorFrom(w, err)
 		return
 	}
 
 	response.Success(w, constant.MOVIE_DELETED_SUCCESSFULLY)
 }
+
+func (h *MovieHandler) RestoreMovie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_MOVIE_ID)
+		return
+	}
+
+	movie, err := h.movieFlow.RestoreMovie(ctx, id)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", MovieETag(movie.Version))
+	response.Success(w, movie)
+}
@@ -5,15 +5,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/auth"
 	"roketin-case-study-challenge2/internal/entity"
 	"roketin-case-study-challenge2/internal/response"
+	"roketin-case-study-challenge2/internal/storage"
+	"roketin-case-study-challenge2/internal/upload"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi"
+	"gorm.io/gorm"
 )
 
 type MockMovieFlow struct {
@@ -38,6 +47,72 @@ func (m *MockMovieFlow) ListMovies(ctx context.Context, filter *entity.MovieFilt
 	return m.movies, m.totalItems, nil
 }
 
+func (m *MockMovieFlow) SuggestMovies(ctx context.Context, prefix string, limit int) ([]entity.Suggestion, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	suggestions := []entity.Suggestion{}
+	for _, mov := range m.movies {
+		if strings.HasPrefix(strings.ToLower(mov.Title), strings.ToLower(prefix)) && len(suggestions) < limit {
+			suggestions = append(suggestions, entity.Suggestion{Kind: entity.SuggestionTitle, Value: mov.Title})
+		}
+	}
+	return suggestions, nil
+}
+
+func (m *MockMovieFlow) ListMovieFacets(ctx context.Context, filter *entity.MovieFilter) (entity.MovieFacets, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	facets := entity.MovieFacets{}
+	for _, facet := range filter.Facets {
+		facets[facet] = countGenres(m.movies)
+	}
+	return facets, nil
+}
+
+func (m *MockMovieFlow) ListDeletedMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	if m.err != nil {
+		return nil, 0, m.err
+	}
+	var deleted []entity.Movie
+	for _, mov := range m.movies {
+		if mov.DeletedAt != nil {
+			deleted = append(deleted, mov)
+		}
+	}
+	return deleted, int64(len(deleted)), nil
+}
+
+func (m *MockMovieFlow) GetMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, mov := range m.movies {
+		if mov.ID == id {
+			movie := mov
+			return &movie, nil
+		}
+	}
+	return nil, newMovieNotFoundError(id)
+}
+
+func (m *MockMovieFlow) GetMovieDetail(ctx context.Context, id int, include entity.MovieInclude) (*entity.MovieDetail, error) {
+	movie, err := m.GetMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	detail := &entity.MovieDetail{Movie: *movie}
+	if include.Files {
+		detail.Files = []entity.MovieFile{{Path: movie.FilePath, MimeType: movie.MimeType}}
+	}
+	return detail, nil
+}
+
+func (m *MockMovieFlow) GetStreamableMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	return m.GetMovie(ctx, id)
+}
+
 func (m *MockMovieFlow) UpdateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
 	if m.err != nil {
 		return nil, m.err
@@ -51,10 +126,56 @@ func (m *MockMovieFlow) UpdateMovie(ctx context.Context, movie *entity.Movie) (*
 	return movie, nil
 }
 
-func (m *MockMovieFlow) DeleteMovie(ctx context.Context, id int) error {
+func (m *MockMovieFlow) PatchMovie(ctx context.Context, id int, patch *MoviePatch) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.GetMovie(ctx, id)
+}
+
+func (m *MockMovieFlow) ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	current, err := m.GetMovie(ctx, movie.ID)
+	if err != nil {
+		return nil, err
+	}
+	current.FilePath = movie.FilePath
+	current.MimeType = movie.MimeType
+	current.Version++
+	return current, nil
+}
+
+func (m *MockMovieFlow) RestoreMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	movie, err := m.GetMovie(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if movie.DeletedAt == nil {
+		return nil, ErrMovieNotDeleted
+	}
+	movie.DeletedAt = nil
+	return movie, nil
+}
+
+func (m *MockMovieFlow) PurgeMovie(ctx context.Context, id int, version int) error {
+	return m.err
+}
+
+func (m *MockMovieFlow) PurgeDeletedMovies(ctx context.Context, dryRun bool) (*PurgeReport, error) {
+	return &PurgeReport{DryRun: dryRun}, m.err
+}
+
+func (m *MockMovieFlow) DeleteMovie(ctx context.Context, id int, version int) error {
 	if m.err != nil {
 		return m.err
 	}
+	for _, movie := range m.movies {
+		if movie.ID == id && version > 0 && movie.Version != version {
+			return ErrVersionMismatch
+		}
+	}
 	return nil
 }
 
@@ -64,11 +185,25 @@ func TestCreateMovieHandler(t *testing.T) {
 		formData     map[string]string
 		fileData     string
 		fileName     string
+		role         string
 		mockError    error
 		wantStatus   int
 		wantResponse bool
 		wantErrorMsg string
 	}{
+		{
+			name: "fail - viewer cannot create movie",
+			formData: map[string]string{
+				"title":            "Test Movie",
+				"duration_minutes": "120",
+			},
+			fileData:     testMP4Content,
+			fileName:     "test.mp4",
+			role:         entity.UserRoleViewer,
+			wantStatus:   http.StatusForbidden,
+			wantResponse: false,
+			wantErrorMsg: "forbidden: role viewer may not perform movie:create",
+		},
 		{
 			name: "success create movie",
 			formData: map[string]string{
@@ -78,7 +213,7 @@ func TestCreateMovieHandler(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData:     "test content",
+			fileData:     testMP4Content,
 			fileName:     "test.mp4",
 			mockError:    nil,
 			wantStatus:   http.StatusOK,
@@ -93,10 +228,10 @@ func TestCreateMovieHandler(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData:     "test content",
+			fileData:     testMP4Content,
 			fileName:     "test.mp4",
 			mockError:    nil,
-			wantStatus:   http.StatusBadRequest,
+			wantStatus:   http.StatusUnprocessableEntity,
 			wantResponse: false,
 			wantErrorMsg: "title is required",
 		},
@@ -109,10 +244,10 @@ func TestCreateMovieHandler(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData:     "test content",
+			fileData:     testMP4Content,
 			fileName:     "test.mp4",
 			mockError:    nil,
-			wantStatus:   http.StatusBadRequest,
+			wantStatus:   http.StatusUnprocessableEntity,
 			wantResponse: false,
 			wantErrorMsg: "duration must be a number",
 		},
@@ -126,12 +261,12 @@ func TestCreateMovieHandler(t *testing.T) {
 				"genres":           "Action",
 			},
 			mockError:    nil,
-			wantStatus:   http.StatusBadRequest,
+			wantStatus:   http.StatusUnprocessableEntity,
 			wantResponse: false,
 			wantErrorMsg: "movie file is required",
 		},
 		{
-			name: "fail - flow error",
+			name: "fail - content does not match extension",
 			formData: map[string]string{
 				"title":            "Test Movie",
 				"description":      "Test Description",
@@ -141,6 +276,35 @@ func TestCreateMovieHandler(t *testing.T) {
 			},
 			fileData:     "test content",
 			fileName:     "test.mp4",
+			mockError:    nil,
+			wantStatus:   http.StatusUnsupportedMediaType,
+			wantResponse: false,
+			wantErrorMsg: "unsupported media type: file content is not a valid .mp4 video",
+		},
+		{
+			name: "fail - content does not match extension and empty title",
+			formData: map[string]string{
+				"title":            "",
+				"duration_minutes": "120",
+			},
+			fileData:     "test content",
+			fileName:     "test.mp4",
+			mockError:    nil,
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantResponse: false,
+			wantErrorMsg: "title is required; unsupported media type: file content is not a valid .mp4 video",
+		},
+		{
+			name: "fail - flow error",
+			formData: map[string]string{
+				"title":            "Test Movie",
+				"description":      "Test Description",
+				"duration_minutes": "120",
+				"artists":          "Test Artist",
+				"genres":           "Action",
+			},
+			fileData:     testMP4Content,
+			fileName:     "test.mp4",
 			mockError:    fmt.Errorf("failed to create movie"),
 			wantStatus:   http.StatusInternalServerError,
 			wantResponse: false,
@@ -172,6 +336,11 @@ func TestCreateMovieHandler(t *testing.T) {
 
 			req := httptest.NewRequest("POST", "/api/movies", body)
 			req.Header.Set("Content-Type", writer.FormDataContentType())
+			role := test.role
+			if role == "" {
+				role = entity.UserRoleProgrammer
+			}
+			req = req.WithContext(contextWithRole(role))
 
 			rr := httptest.NewRecorder()
 
@@ -179,7 +348,8 @@ func TestCreateMovieHandler(t *testing.T) {
 				err: test.mockError,
 			}
 
-			handler := NewMovieHandler(NewMovieParser(), mockFlow)
+			store := storage.NewLocalStorage(t.TempDir())
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, store, nil)
 
 			handler.CreateMovie(rr, req)
 
@@ -187,12 +357,16 @@ func TestCreateMovieHandler(t *testing.T) {
 				t.Errorf("CreateMovie() status = %v, want %v", rr.Code, test.wantStatus)
 			}
 
-			var resp response.Response
-			err := json.NewDecoder(rr.Body).Decode(&resp)
-			if err != nil {
-				t.Fatal(err)
+			wantFiles := 0
+			if test.wantResponse {
+				wantFiles = 1
+			}
+			if objects, _ := store.List(req.Context(), "uploads/"); len(objects) != wantFiles {
+				t.Errorf("CreateMovie() left %v files, want %v", len(objects), wantFiles)
 			}
 
+			resp, problem := decodeResponse(t, rr)
+
 			if test.wantResponse {
 				if resp.Status != "success" {
 					t.Errorf("CreateMovie() response status = %v, want success", resp.Status)
@@ -201,17 +375,106 @@ func TestCreateMovieHandler(t *testing.T) {
 					t.Error("CreateMovie() response data is nil")
 				}
 			} else {
-				if resp.Status != "error" {
-					t.Errorf("CreateMovie() response status = %v, want error", resp.Status)
+				if problem.Status != rr.Code {
+					t.Errorf("CreateMovie() problem status = %v, want %v", problem.Status, rr.Code)
 				}
-				if resp.Message != test.wantErrorMsg {
-					t.Errorf("CreateMovie() error message = %v, want %v", resp.Message, test.wantErrorMsg)
+				if problem.Detail != test.wantErrorMsg {
+					t.Errorf("CreateMovie() error message = %v, want %v", problem.Detail, test.wantErrorMsg)
 				}
 			}
 		})
 	}
 }
 
+type panickingMovieFlow struct {
+	MockMovieFlow
+}
+
+func (m *panickingMovieFlow) CreateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	panic("database went away")
+}
+
+func TestCreateMovieHandlerPanicRemovesStagedFile(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("title", "Test Movie")
+	part, err := writer.CreateFormFile("movie_file", "test.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(testMP4Content))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/movies", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = req.WithContext(contextWithRole(entity.UserRoleProgrammer))
+
+	store := storage.NewLocalStorage(t.TempDir())
+	handler := NewMovieHandler(NewMovieParser(), &panickingMovieFlow{}, store, nil)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("CreateMovie() did not panic")
+			}
+		}()
+		handler.CreateMovie(httptest.NewRecorder(), req)
+	}()
+
+	if objects, _ := store.List(context.Background(), "uploads/"); len(objects) != 0 {
+		t.Errorf("CreateMovie() left files %v, want none", objects)
+	}
+}
+
+func TestCreateMovieHandlerFieldErrors(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("duration_minutes", "abc")
+	writer.WriteField("artists", strings.Repeat("a", 256))
+	writer.WriteField("genres", "Action, "+strings.Repeat("g", 101))
+	part, err := writer.CreateFormFile("movie_file", "test.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(testMP4Content))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/api/movies", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req = req.WithContext(contextWithRole(entity.UserRoleProgrammer))
+
+	rr := httptest.NewRecorder()
+
+	handler := NewMovieHandler(NewMovieParser(), &MockMovieFlow{}, storage.NewLocalStorage(t.TempDir()), nil)
+
+	handler.CreateMovie(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("CreateMovie() status = %v, want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+
+	if contentType := rr.Header().Get("Content-Type"); contentType != response.ProblemContentType {
+		t.Errorf("CreateMovie() content type = %v, want %v", contentType, response.ProblemContentType)
+	}
+
+	_, problem := decodeResponse(t, rr)
+
+	want := []string{
+		"title:required",
+		"duration_minutes:not_a_number",
+		"movie_file:extension_not_allowed",
+		"artists:too_long",
+		"genres:too_long",
+	}
+	var got []string
+	for _, fieldErr := range problem.Errors {
+		got = append(got, fieldErr.Field+":"+fieldErr.Code)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("CreateMovie() field errors = %v, want %v", got, want)
+	}
+}
+
 func TestListMoviesHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -222,6 +485,8 @@ func TestListMoviesHandler(t *testing.T) {
 		wantStatus     int
 		wantResponse   bool
 		wantErrorMsg   string
+		wantPosition   int
+		wantFacets     string
 	}{
 		{
 			name: "success list movies",
@@ -269,6 +534,58 @@ func TestListMoviesHandler(t *testing.T) {
 			wantStatus:     http.StatusOK,
 			wantResponse:   true,
 		},
+		{
+			name: "success list movies with facets",
+			queryParams: map[string]string{
+				"facets": "genre",
+			},
+			mockMovies: []entity.Movie{
+				{ID: 1, Title: "Movie 1", Genres: "Action, Drama"},
+				{ID: 2, Title: "Movie 2", Genres: "Drama"},
+			},
+			mockTotalItems: 2,
+			wantStatus:     http.StatusOK,
+			wantResponse:   true,
+			wantFacets:     "map[genre:[map[count:2 value:Drama] map[count:1 value:Action]]]",
+		},
+		{
+			name: "fail - filter query nested too deeply",
+			queryParams: map[string]string{
+				"filter": strings.Repeat("(", 100) + "genre:drama" + strings.Repeat(")", 100),
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: false,
+			wantErrorMsg: "invalid query at position 65: query nests deeper than 64 levels",
+			wantPosition: 65,
+		},
+		{
+			name: "fail - filter query too long",
+			queryParams: map[string]string{
+				"filter": strings.Repeat("(", 1_000_000),
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: false,
+			wantErrorMsg: "invalid query at position 1025: query is longer than 1024 characters",
+			wantPosition: 1025,
+		},
+		{
+			name: "fail - unknown sort key",
+			queryParams: map[string]string{
+				"sort": "-rating",
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: false,
+			wantErrorMsg: `cannot sort by "rating", expected title, duration, created_at, updated_at or relevance`,
+		},
+		{
+			name: "fail - unknown facet",
+			queryParams: map[string]string{
+				"facets": "rating",
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: false,
+			wantErrorMsg: `facet "rating" is not valid, expected genre, artist, duration or year`,
+		},
 		{
 			name: "fail - invalid page",
 			queryParams: map[string]string{
@@ -279,6 +596,16 @@ func TestListMoviesHandler(t *testing.T) {
 			wantResponse: false,
 			wantErrorMsg: "page number must be greater than 0: -1",
 		},
+		{
+			name: "fail - invalid filter query",
+			queryParams: map[string]string{
+				"filter": "genre:drama OR",
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: false,
+			wantErrorMsg: "invalid query at position 15: unexpected end of query, expected a term",
+			wantPosition: 15,
+		},
 		{
 			name:         "fail - flow error",
 			mockError:    fmt.Errorf("failed to get list movies"),
@@ -309,7 +636,7 @@ func TestListMoviesHandler(t *testing.T) {
 				totalItems: test.mockTotalItems,
 			}
 
-			handler := NewMovieHandler(NewMovieParser(), mockFlow)
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
 
 			handler.ListMovies(rr, req)
 
@@ -317,11 +644,7 @@ func TestListMoviesHandler(t *testing.T) {
 				t.Errorf("ListMovies() status = %v, want %v", rr.Code, test.wantStatus)
 			}
 
-			var resp response.Response
-			err := json.NewDecoder(rr.Body).Decode(&resp)
-			if err != nil {
-				t.Fatal(err)
-			}
+			resp, problem := decodeResponse(t, rr)
 
 			if test.wantResponse {
 				if resp.Status != "success" {
@@ -340,12 +663,23 @@ func TestListMoviesHandler(t *testing.T) {
 				if pagination["total_items"].(float64) != float64(test.mockTotalItems) {
 					t.Errorf("ListMovies() total items = %v, want %v", pagination["total_items"], test.mockTotalItems)
 				}
+
+				facets, ok := responseData["facets"]
+				if test.wantFacets == "" && ok {
+					t.Errorf("ListMovies() facets = %v, want none", facets)
+				}
+				if test.wantFacets != "" && fmt.Sprint(facets) != test.wantFacets {
+					t.Errorf("ListMovies() facets = %v, want %v", facets, test.wantFacets)
+				}
 			} else {
-				if resp.Status != "error" {
-					t.Errorf("ListMovies() response status = %v, want error", resp.Status)
+				if problem.Status != rr.Code {
+					t.Errorf("ListMovies() problem status = %v, want %v", problem.Status, rr.Code)
+				}
+				if problem.Detail != test.wantErrorMsg {
+					t.Errorf("ListMovies() error message = %v, want %v", problem.Detail, test.wantErrorMsg)
 				}
-				if resp.Message != test.wantErrorMsg {
-					t.Errorf("ListMovies() error message = %v, want %v", resp.Message, test.wantErrorMsg)
+				if problem.Position != test.wantPosition {
+					t.Errorf("ListMovies() error position = %v, want %v", problem.Position, test.wantPosition)
 				}
 			}
 		})
@@ -399,10 +733,32 @@ func TestUpdateMovieHandler(t *testing.T) {
 				"title":            "Updated Movie",
 				"duration_minutes": "abc",
 			},
-			wantStatus:   http.StatusBadRequest,
+			wantStatus:   http.StatusUnprocessableEntity,
 			wantResponse: false,
 			wantErrorMsg: "duration must be a number",
 		},
+		{
+			name:    "fail - movie not found",
+			movieID: "99",
+			formData: map[string]string{
+				"title": "Updated Movie",
+			},
+			mockError:    newMovieNotFoundError(99),
+			wantStatus:   http.StatusNotFound,
+			wantResponse: false,
+			wantErrorMsg: "movie with ID 99 not found",
+		},
+		{
+			name:    "fail - validation error",
+			movieID: "1",
+			formData: map[string]string{
+				"duration_minutes": "0",
+			},
+			mockError:    apperror.Validation("duration must be greater than 0"),
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantResponse: false,
+			wantErrorMsg: "duration must be greater than 0",
+		},
 		{
 			name:    "fail - flow error",
 			movieID: "1",
@@ -442,7 +798,7 @@ func TestUpdateMovieHandler(t *testing.T) {
 				err: test.mockError,
 			}
 
-			handler := NewMovieHandler(NewMovieParser(), mockFlow)
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
 
 			handler.UpdateMovie(rr, req)
 
@@ -450,11 +806,7 @@ func TestUpdateMovieHandler(t *testing.T) {
 				t.Errorf("UpdateMovie() status = %v, want %v", rr.Code, test.wantStatus)
 			}
 
-			var resp response.Response
-			err := json.NewDecoder(rr.Body).Decode(&resp)
-			if err != nil {
-				t.Fatal(err)
-			}
+			resp, problem := decodeResponse(t, rr)
 
 			if test.wantResponse {
 				if resp.Status != "success" {
@@ -464,24 +816,155 @@ func TestUpdateMovieHandler(t *testing.T) {
 					t.Error("UpdateMovie() response data is nil")
 				}
 			} else {
-				if resp.Status != "error" {
-					t.Errorf("UpdateMovie() response status = %v, want error", resp.Status)
+				if problem.Status != rr.Code {
+					t.Errorf("UpdateMovie() problem status = %v, want %v", problem.Status, rr.Code)
 				}
-				if resp.Message != test.wantErrorMsg {
-					t.Errorf("UpdateMovie() error message = %v, want %v", resp.Message, test.wantErrorMsg)
+				if problem.Detail != test.wantErrorMsg {
+					t.Errorf("UpdateMovie() error message = %v, want %v", problem.Detail, test.wantErrorMsg)
 				}
 			}
 		})
 	}
 }
 
+func TestMovieHandlerETags(t *testing.T) {
+	mockFlow := &MockMovieFlow{movies: []entity.Movie{{ID: 1, Title: "Film", Version: 4}}}
+	handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
+	routes := handler.Routes()
+
+	do := func(method, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/1", strings.NewReader(`{"description": "New"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		req = req.WithContext(contextWithRole(entity.UserRoleProgrammer))
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := do(http.MethodGet, "")
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"4"` {
+		t.Errorf("GET status = %v, ETag = %q, want 200 with \"4\"", rr.Code, rr.Header().Get("ETag"))
+	}
+
+	rr = do(http.MethodPatch, `"4"`)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"4"` {
+		t.Errorf("PATCH status = %v, ETag = %q", rr.Code, rr.Header().Get("ETag"))
+	}
+
+	rr = do(http.MethodPut, `W/"4"`)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("PUT with weak ETag status = %v, want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+
+	rr = do(http.MethodDelete, `"2", "3"`)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag list status = %v, want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+
+	rr = do(http.MethodDelete, `"3", "4"`)
+	if rr.Code != http.StatusOK {
+		t.Errorf("DELETE with ETag list status = %v, want %v", rr.Code, http.StatusOK)
+	}
+
+	rr = do(http.MethodDelete, `"3", 4`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("DELETE with malformed ETag list status = %v, want %v", rr.Code, http.StatusBadRequest)
+	}
+
+	mockFlow.err = ErrVersionMismatch
+	rr = do(http.MethodDelete, `"3"`)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Errorf("DELETE with stale ETag status = %v, want %v", rr.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func TestPatchMovieHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		mockError       error
+		wantStatus      int
+		wantAcceptPatch bool
+	}{
+		{
+			name:        "success merge patch",
+			contentType: MergePatchContentType,
+			body:        `{"description": null}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "success json patch",
+			contentType: JSONPatchContentType,
+			body:        `[{"op": "remove", "path": "/description"}]`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "fail - invalid json patch operation",
+			contentType: JSONPatchContentType,
+			body:        `[{"op": "rename", "path": "/title"}]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "fail - merge patch is not an object",
+			contentType: MergePatchContentType,
+			body:        `["title"]`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:            "fail - form body",
+			contentType:     "application/x-www-form-urlencoded",
+			body:            "title=New",
+			wantStatus:      http.StatusUnsupportedMediaType,
+			wantAcceptPatch: true,
+		},
+		{
+			name:        "fail - validation error",
+			contentType: MergePatchContentType,
+			body:        `{"title": null}`,
+			mockError:   apperror.Field("title", apperror.CodeRequired, "title is required"),
+			wantStatus:  http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/1", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			req = req.WithContext(contextWithRole(entity.UserRoleProgrammer))
+			rr := httptest.NewRecorder()
+
+			mockFlow := &MockMovieFlow{
+				movies: []entity.Movie{{ID: 1, Title: "Film"}},
+				err:    test.mockError,
+			}
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Errorf("PatchMovie() status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+
+			if got := rr.Header().Get("Accept-Patch"); (got == AcceptPatch) != test.wantAcceptPatch {
+				t.Errorf("PatchMovie() Accept-Patch = %q", got)
+			}
+		})
+	}
+}
+
 func TestDeleteMovieHandler(t *testing.T) {
 	tests := []struct {
 		name         string
 		movieID      string
+		query        string
 		mockError    error
 		wantStatus   int
 		wantResponse bool
+		wantData     string
 		wantErrorMsg string
 	}{
 		{
@@ -489,6 +972,32 @@ func TestDeleteMovieHandler(t *testing.T) {
 			movieID:      "1",
 			wantStatus:   http.StatusOK,
 			wantResponse: true,
+			wantData:     "Movie deleted successfully",
+		},
+		{
+			name:         "success permanently delete movie",
+			movieID:      "1",
+			query:        "?permanent=true",
+			wantStatus:   http.StatusOK,
+			wantResponse: true,
+			wantData:     "Movie permanently deleted",
+		},
+		{
+			name:         "fail - invalid permanent",
+			movieID:      "1",
+			query:        "?permanent=maybe",
+			wantStatus:   http.StatusBadRequest,
+			wantResponse: false,
+			wantErrorMsg: "permanent must be true or false: 'maybe'",
+		},
+		{
+			name:         "fail - programmer cannot purge",
+			movieID:      "1",
+			query:        "?permanent=1",
+			mockError:    fmt.Errorf("%w: role programmer may not perform movie:purge", auth.ErrForbidden),
+			wantStatus:   http.StatusForbidden,
+			wantResponse: false,
+			wantErrorMsg: "forbidden: role programmer may not perform movie:purge",
 		},
 		{
 			name:         "fail - invalid movie ID",
@@ -498,7 +1007,23 @@ func TestDeleteMovieHandler(t *testing.T) {
 			wantErrorMsg: "invalid movie ID",
 		},
 		{
-			name:         "fail - flow error",
+			name:         "fail - forbidden",
+			movieID:      "1",
+			mockError:    fmt.Errorf("%w: role viewer may not perform movie:delete", auth.ErrForbidden),
+			wantStatus:   http.StatusForbidden,
+			wantResponse: false,
+			wantErrorMsg: "forbidden: role viewer may not perform movie:delete",
+		},
+		{
+			name:         "fail - movie not found",
+			movieID:      "99",
+			mockError:    newMovieNotFoundError(99),
+			wantStatus:   http.StatusNotFound,
+			wantResponse: false,
+			wantErrorMsg: "movie with ID 99 not found",
+		},
+		{
+			name:         "fail - flow error",
 			movieID:      "1",
 			mockError:    fmt.Errorf("failed to delete movie"),
 			wantStatus:   http.StatusInternalServerError,
@@ -509,7 +1034,7 @@ func TestDeleteMovieHandler(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			req := httptest.NewRequest("DELETE", "/api/movies/"+test.movieID, nil)
+			req := httptest.NewRequest("DELETE", "/api/movies/"+test.movieID+test.query, nil)
 
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("id", test.movieID)
@@ -521,7 +1046,7 @@ func TestDeleteMovieHandler(t *testing.T) {
 				err: test.mockError,
 			}
 
-			handler := NewMovieHandler(NewMovieParser(), mockFlow)
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
 
 			handler.DeleteMovie(rr, req)
 
@@ -529,24 +1054,717 @@ func TestDeleteMovieHandler(t *testing.T) {
 				t.Errorf("DeleteMovie() status = %v, want %v", rr.Code, test.wantStatus)
 			}
 
-			var resp response.Response
-			err := json.NewDecoder(rr.Body).Decode(&resp)
-			if err != nil {
-				t.Fatal(err)
-			}
+			resp, problem := decodeResponse(t, rr)
 
 			if test.wantResponse {
 				if resp.Status != "success" {
 					t.Errorf("DeleteMovie() response status = %v, want success", resp.Status)
 				}
+				if resp.Data != test.wantData {
+					t.Errorf("DeleteMovie() data = %v, want %v", resp.Data, test.wantData)
+				}
+			} else {
+				if problem.Status != rr.Code {
+					t.Errorf("DeleteMovie() problem status = %v, want %v", problem.Status, rr.Code)
+				}
+				if problem.Detail != test.wantErrorMsg {
+					t.Errorf("DeleteMovie() error message = %v, want %v", problem.Detail, test.wantErrorMsg)
+				}
+			}
+		})
+	}
+}
+
+type MockUploadFlow struct {
+	uploads  map[string]entity.Upload
+	contents map[string]string
+	storage  storage.Storage
+}
+
+func (m *MockUploadFlow) CreateUpload(ctx context.Context, length int64, metadata string) (*entity.Upload, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockUploadFlow) GetUpload(ctx context.Context, id string) (*entity.Upload, error) {
+	pending, ok := m.uploads[id]
+	if !ok {
+		return nil, upload.ErrUploadNotFound
+	}
+	return &pending, nil
+}
+
+func (m *MockUploadFlow) WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (*entity.Upload, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockUploadFlow) TerminateUpload(ctx context.Context, id string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockUploadFlow) ClaimUpload(ctx context.Context, id string, baseUploadPath string) (string, error) {
+	pending, ok := m.uploads[id]
+	if !ok {
+		return "", upload.ErrUploadNotFound
+	}
+	if !pending.IsComplete() {
+		return "", upload.ErrUploadIncomplete
+	}
+	delete(m.uploads, id)
+	key := baseUploadPath + "/" + upload.FileName(&pending)
+	if err := m.storage.Put(ctx, key, strings.NewReader(m.contents[id]), int64(len(m.contents[id]))); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+func (m *MockUploadFlow) CleanupExpired(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockUploadFlow) MaxSize() int64 {
+	return 0
+}
+
+func TestCreateMovieHandlerFromUpload(t *testing.T) {
+	tests := []struct {
+		name         string
+		uploadID     string
+		wantStatus   int
+		wantFilePath string
+		wantErrorMsg string
+	}{
+		{
+			name:         "success create movie from upload",
+			uploadID:     "complete",
+			wantStatus:   http.StatusOK,
+			wantFilePath: "uploads/staging/film.mp4",
+		},
+		{
+			name:         "fail - incomplete upload",
+			uploadID:     "partial",
+			wantStatus:   http.StatusBadRequest,
+			wantErrorMsg: "upload is not complete",
+		},
+		{
+			name:         "fail - unknown upload",
+			uploadID:     "missing",
+			wantStatus:   http.StatusBadRequest,
+			wantErrorMsg: "upload not found",
+		},
+		{
+			name:         "fail - invalid upload file extension",
+			uploadID:     "text",
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantErrorMsg: "file extension .txt is not allowed",
+		},
+		{
+			name:         "fail - upload content does not match extension",
+			uploadID:     "fake",
+			wantStatus:   http.StatusUnsupportedMediaType,
+			wantErrorMsg: "unsupported media type: file content is not a valid .mp4 video",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Set("title", "Test Movie")
+			form.Set("duration_minutes", "120")
+			form.Set("upload_id", test.uploadID)
+
+			req := httptest.NewRequest("POST", "/api/movies", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req = req.WithContext(contextWithRole(entity.UserRoleProgrammer))
+
+			rr := httptest.NewRecorder()
+
+			store := storage.NewLocalStorage(t.TempDir())
+
+			mockUploads := &MockUploadFlow{
+				uploads: map[string]entity.Upload{
+					"complete": {ID: "complete", Length: 4, Offset: 4, Metadata: "filename ZmlsbS5tcDQ="},
+					"partial":  {ID: "partial", Length: 4, Offset: 2, Metadata: "filename ZmlsbS5tcDQ="},
+					"text":     {ID: "text", Length: 4, Offset: 4, Metadata: "filename bm90ZXMudHh0"},
+					"fake":     {ID: "fake", Length: 4, Offset: 4, Metadata: "filename ZmlsbS5tcDQ="},
+				},
+				contents: map[string]string{
+					"complete": testMP4Content,
+					"fake":     "test content",
+				},
+				storage: store,
+			}
+
+			handler := NewMovieHandler(NewMovieParser(), &MockMovieFlow{}, store, mockUploads)
+
+			handler.CreateMovie(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Errorf("CreateMovie() status = %v, want %v", rr.Code, test.wantStatus)
+			}
+
+			resp, problem := decodeResponse(t, rr)
+
+			if test.wantErrorMsg != "" {
+				if problem.Detail != test.wantErrorMsg {
+					t.Errorf("CreateMovie() error message = %v, want %v", problem.Detail, test.wantErrorMsg)
+				}
+				return
+			}
+
+			data := resp.Data.(map[string]interface{})
+			if data["file_path"] != test.wantFilePath {
+				t.Errorf("CreateMovie() file path = %v, want %v", data["file_path"], test.wantFilePath)
+			}
+			if data["mime_type"] != "video/mp4" {
+				t.Errorf("CreateMovie() mime type = %v, want video/mp4", data["mime_type"])
+			}
+		})
+	}
+}
+
+func TestReplaceMovieFileHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		fileName     string
+		fileData     string
+		role         string
+		mockError    error
+		wantStatus   int
+		wantErrorMsg string
+	}{
+		{
+			name:       "success replace movie file",
+			fileName:   "new.mp4",
+			fileData:   testMP4Content,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "fail - viewer cannot replace movie file",
+			fileName:     "new.mp4",
+			fileData:     testMP4Content,
+			role:         entity.UserRoleViewer,
+			wantStatus:   http.StatusForbidden,
+			wantErrorMsg: "forbidden: role viewer may not perform movie:update",
+		},
+		{
+			name:         "fail - no file",
+			wantStatus:   http.StatusUnprocessableEntity,
+			wantErrorMsg: "movie file is required",
+		},
+		{
+			name:         "fail - content does not match extension",
+			fileName:     "new.mp4",
+			fileData:     "test content",
+			wantStatus:   http.StatusUnsupportedMediaType,
+			wantErrorMsg: "unsupported media type: file content is not a valid .mp4 video",
+		},
+		{
+			name:         "fail - movie changed meanwhile",
+			fileName:     "new.mp4",
+			fileData:     testMP4Content,
+			mockError:    ErrVersionMismatch,
+			wantStatus:   http.StatusPreconditionFailed,
+			wantErrorMsg: "movie has been modified since it was read",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			if test.fileName != "" {
+				part, err := writer.CreateFormFile("movie_file", test.fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				part.Write([]byte(test.fileData))
+			}
+			writer.Close()
+
+			req := httptest.NewRequest(http.MethodPut, "/1/file", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			req.Header.Set("If-Match", `"1"`)
+			role := test.role
+			if role == "" {
+				role = entity.UserRoleProgrammer
+			}
+			req = req.WithContext(contextWithRole(role))
+
+			rr := httptest.NewRecorder()
+
+			store := storage.NewLocalStorage(t.TempDir())
+			store.Put(context.Background(), "uploads/old.mp4", strings.NewReader(testMP4Content), int64(len(testMP4Content)))
+
+			mockFlow := &MockMovieFlow{
+				movies: []entity.Movie{{ID: 1, Title: "Film", FilePath: "uploads/old.mp4", Version: 1}},
+				err:    test.mockError,
+			}
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, store, nil)
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Fatalf("ReplaceMovieFile() status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+
+			objects, err := store.List(context.Background(), "uploads/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, problem := decodeResponse(t, rr)
+
+			if test.wantErrorMsg != "" {
+				if problem.Detail != test.wantErrorMsg {
+					t.Errorf("ReplaceMovieFile() error message = %v, want %v", problem.Detail, test.wantErrorMsg)
+				}
+				if len(objects) != 1 || objects[0].Key != "uploads/old.mp4" {
+					t.Errorf("ReplaceMovieFile() stored objects = %+v, want only the old file", objects)
+				}
+				return
+			}
+
+			data := resp.Data.(map[string]interface{})
+			if data["file_path"] == "uploads/old.mp4" || data["mime_type"] != "video/mp4" {
+				t.Errorf("ReplaceMovieFile() = %v, want the new file", data)
+			}
+			if rr.Header().Get("ETag") != `"2"` {
+				t.Errorf("ReplaceMovieFile() ETag = %q, want \"2\"", rr.Header().Get("ETag"))
+			}
+			if len(objects) != 2 {
+				t.Errorf("ReplaceMovieFile() stored objects = %+v, want the old and the new file", objects)
+			}
+		})
+	}
+}
+
+// interruptedMovieFlow fails or panics while replacing a movie file after
+// the client went away.
+type interruptedMovieFlow struct {
+	*MockMovieFlow
+	cancel context.CancelFunc
+	panics bool
+}
+
+func (m *interruptedMovieFlow) ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	m.cancel()
+	if m.panics {
+		panic("replace failed")
+	}
+	return nil, ctx.Err()
+}
+
+// cancelAwareStorage fails deletes under a cancelled context, as remote
+// storage does.
+type cancelAwareStorage struct {
+	storage.Storage
+}
+
+func (s cancelAwareStorage) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+func TestReplaceMovieFileHandlerCleansUpAfterDisconnect(t *testing.T) {
+	for _, panics := range []bool{false, true} {
+		t.Run(fmt.Sprintf("panics=%v", panics), func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			part, _ := writer.CreateFormFile("movie_file", "new.mp4")
+			part.Write([]byte(testMP4Content))
+			writer.Close()
+
+			ctx, cancel := context.WithCancel(contextWithRole(entity.UserRoleProgrammer))
+			defer cancel()
+			req := httptest.NewRequest(http.MethodPut, "/1/file", body).WithContext(ctx)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+
+			local := storage.NewLocalStorage(t.TempDir())
+			local.Put(context.Background(), "uploads/old.mp4", strings.NewReader(testMP4Content), int64(len(testMP4Content)))
+
+			mockFlow := &interruptedMovieFlow{
+				MockMovieFlow: &MockMovieFlow{movies: []entity.Movie{{ID: 1, Title: "Film", FilePath: "uploads/old.mp4", Version: 1}}},
+				cancel:        cancel,
+				panics:        panics,
+			}
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, cancelAwareStorage{local}, nil)
+
+			func() {
+				defer func() { recover() }()
+				handler.Routes().ServeHTTP(httptest.NewRecorder(), req)
+			}()
+
+			objects, err := local.List(context.Background(), "uploads/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(objects) != 1 || objects[0].Key != "uploads/old.mp4" {
+				t.Errorf("stored objects = %+v, want only the old file", objects)
+			}
+		})
+	}
+}
+
+func TestMovieTrashHandlers(t *testing.T) {
+	deletedAt := &gorm.DeletedAt{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		role       string
+		wantStatus int
+		wantIDs    []float64
+	}{
+		{name: "success list trash", method: http.MethodGet, path: "/trash?title=Gone", wantStatus: http.StatusOK, wantIDs: []float64{2}},
+		{name: "fail - viewer cannot list trash", method: http.MethodGet, path: "/trash", role: entity.UserRoleViewer, wantStatus: http.StatusForbidden},
+		{name: "fail - invalid page", method: http.MethodGet, path: "/trash?page=0", wantStatus: http.StatusBadRequest},
+		{name: "success restore movie", method: http.MethodPost, path: "/2/restore", wantStatus: http.StatusOK, wantIDs: []float64{2}},
+		{name: "fail - movie not deleted", method: http.MethodPost, path: "/1/restore", wantStatus: http.StatusConflict},
+		{name: "fail - movie not found", method: http.MethodPost, path: "/99/restore", wantStatus: http.StatusNotFound},
+		{name: "fail - invalid movie ID", method: http.MethodPost, path: "/abc/restore", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockFlow := &MockMovieFlow{
+				movies: []entity.Movie{{ID: 1, Title: "Kept"}, {ID: 2, Title: "Gone", DeletedAt: deletedAt}},
+			}
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
+
+			role := test.role
+			if role == "" {
+				role = entity.UserRoleProgrammer
+			}
+			if role == entity.UserRoleViewer {
+				mockFlow.err = fmt.Errorf("%w: role viewer may not perform movie:restore", apperror.ErrForbidden)
+			}
+
+			req := httptest.NewRequest(test.method, test.path, nil)
+			req = req.WithContext(contextWithRole(role))
+			rr := httptest.NewRecorder()
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+			if test.wantIDs == nil {
+				return
+			}
+
+			resp, _ := decodeResponse(t, rr)
+			data := resp.Data.(map[string]interface{})
+
+			var ids []float64
+			if items, ok := data["data"].([]interface{}); ok {
+				for _, item := range items {
+					ids = append(ids, item.(map[string]interface{})["id"].(float64))
+				}
 			} else {
-				if resp.Status != "error" {
-					t.Errorf("DeleteMovie() response status = %v, want error", resp.Status)
+				ids = append(ids, data["id"].(float64))
+				if _, ok := data["deleted_at"]; ok {
+					t.Errorf("restored movie deleted_at = %v, want none", data["deleted_at"])
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.wantIDs) {
+				t.Errorf("movie IDs = %v, want %v", ids, test.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSuggestMoviesHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantValues []string
+	}{
+		{name: "success", path: "/suggest?prefix=am", wantStatus: http.StatusOK, wantValues: []string{"Amélie", "American Beauty"}},
+		{name: "success with limit", path: "/suggest?prefix=am&limit=1", wantStatus: http.StatusOK, wantValues: []string{"Amélie"}},
+		{name: "fail - missing prefix", path: "/suggest", wantStatus: http.StatusBadRequest},
+		{name: "fail - invalid limit", path: "/suggest?prefix=am&limit=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockFlow := &MockMovieFlow{
+				movies: []entity.Movie{{ID: 1, Title: "Amélie"}, {ID: 2, Title: "American Beauty"}, {ID: 3, Title: "Heat"}},
+			}
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, storage.NewLocalStorage(t.TempDir()), nil)
+
+			req := httptest.NewRequest(http.MethodGet, test.path, nil)
+			req = req.WithContext(contextWithRole(entity.UserRoleViewer))
+			rr := httptest.NewRecorder()
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Fatalf("status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+			if test.wantValues == nil {
+				return
+			}
+
+			resp, _ := decodeResponse(t, rr)
+			var values []string
+			for _, item := range resp.Data.([]interface{}) {
+				suggestion := item.(map[string]interface{})
+				if suggestion["kind"] != entity.SuggestionTitle {
+					t.Errorf("suggestion kind = %v, want %v", suggestion["kind"], entity.SuggestionTitle)
+				}
+				values = append(values, suggestion["value"].(string))
+			}
+			if fmt.Sprint(values) != fmt.Sprint(test.wantValues) {
+				t.Errorf("suggestions = %v, want %v", values, test.wantValues)
+			}
+		})
+	}
+}
+
+func TestMovieRoutesTimeout(t *testing.T) {
+	handler := NewMovieHandler(NewMovieParser(), &MockMovieFlow{}, storage.NewLocalStorage(t.TempDir()), nil)
+
+	untimed := map[string]bool{
+		"POST /":            true,
+		"PUT /{id}/file":    true,
+		"GET /{id}/stream":  true,
+		"HEAD /{id}/stream": true,
+	}
+
+	err := chi.Walk(handler.Routes(), func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = method + " " + route
+		if timed := len(middlewares) > 0; timed == untimed[route] {
+			t.Errorf("route %s has a timeout: %v, want %v", route, timed, !untimed[route])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+}
+
+func TestGetMovieHandler(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	store.Put(context.Background(), "uploads/film.mp4", strings.NewReader("0123456789"), 10)
+
+	tests := []struct {
+		name         string
+		movieID      string
+		query        string
+		wantStatus   int
+		wantFileSize float64
+	}{
+		{
+			name:       "success get movie",
+			movieID:    "1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "success get movie with files",
+			movieID:      "1",
+			query:        "?include=files,credits",
+			wantStatus:   http.StatusOK,
+			wantFileSize: 10,
+		},
+		{
+			name:       "fail - unknown include",
+			movieID:    "1",
+			query:      "?include=reviews",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fail - movie not found",
+			movieID:    "99",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "fail - invalid movie ID",
+			movieID:    "abc",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockFlow := &MockMovieFlow{
+				movies: []entity.Movie{{ID: 1, Title: "Film", Artists: "Jane Doe, John Roe", FilePath: "uploads/film.mp4", MimeType: "video/mp4"}},
+			}
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, store, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/"+test.movieID+test.query, nil)
+			req = req.WithContext(contextWithRole(entity.UserRoleViewer))
+			rr := httptest.NewRecorder()
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Fatalf("GetMovie() status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+
+			resp, _ := decodeResponse(t, rr)
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+
+			data := resp.Data.(map[string]interface{})
+			if data["title"] != "Film" {
+				t.Errorf("GetMovie() title = %v, want Film", data["title"])
+			}
+			artists := []interface{}{"Jane Doe", "John Roe"}
+			if !reflect.DeepEqual(data["artists"], artists) || !reflect.DeepEqual(data["genres"], []interface{}{}) {
+				t.Errorf("GetMovie() artists = %v, genres = %v, want JSON arrays", data["artists"], data["genres"])
+			}
+
+			files, _ := data["files"].([]interface{})
+			if test.wantFileSize == 0 {
+				if len(files) != 0 {
+					t.Errorf("GetMovie() files = %v, want none", files)
+				}
+				return
+			}
+			if len(files) != 1 || files[0].(map[string]interface{})["size"] != test.wantFileSize {
+				t.Errorf("GetMovie() files = %v, want one file of %v bytes", files, test.wantFileSize)
+			}
+		})
+	}
+}
+
+func TestCreateMovieHandlerJSON(t *testing.T) {
+	body := `{"title": "Test Movie", "duration_minutes": 120, "artists": ["Jane Doe"], "genres": ["Action", "Drama"], "upload_id": "complete"}`
+
+	req := httptest.NewRequest("POST", "/api/movies", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(contextWithRole(entity.UserRoleProgrammer))
+
+	rr := httptest.NewRecorder()
+
+	store := storage.NewLocalStorage(t.TempDir())
+	mockUploads := &MockUploadFlow{
+		uploads: map[string]entity.Upload{
+			"complete": {ID: "complete", Length: 4, Offset: 4, Metadata: "filename ZmlsbS5tcDQ="},
+		},
+		contents: map[string]string{"complete": testMP4Content},
+		storage:  store,
+	}
+
+	handler := NewMovieHandler(NewMovieParser(), &MockMovieFlow{}, store, mockUploads)
+
+	handler.CreateMovie(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("CreateMovie() status = %v, want %v: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	resp, _ := decodeResponse(t, rr)
+	data := resp.Data.(map[string]interface{})
+	genres := []interface{}{"Action", "Drama"}
+	if !reflect.DeepEqual(data["genres"], genres) || data["file_path"] != "uploads/staging/film.mp4" {
+		t.Errorf("CreateMovie() = %v", data)
+	}
+}
+
+func TestStreamMovieHandler(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	store.Put(context.Background(), "uploads/film.mp4", strings.NewReader("0123456789"), 10)
+
+	tests := []struct {
+		name       string
+		movieID    string
+		rangeValue string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "success stream full movie",
+			movieID:    "1",
+			wantStatus: http.StatusOK,
+			wantBody:   "0123456789",
+		},
+		{
+			name:       "success stream range",
+			movieID:    "1",
+			rangeValue: "bytes=2-4",
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "234",
+		},
+		{
+			name:       "fail - movie not found",
+			movieID:    "99",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "fail - file missing from storage",
+			movieID:    "2",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "fail - invalid movie ID",
+			movieID:    "invalid",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/movies/"+test.movieID+"/stream", nil)
+			if test.rangeValue != "" {
+				req.Header.Set("Range", test.rangeValue)
+			}
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", test.movieID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			rr := httptest.NewRecorder()
+
+			mockFlow := &MockMovieFlow{
+				movies: []entity.Movie{
+					{ID: 1, Title: "Movie 1", FilePath: "uploads/film.mp4"},
+					{ID: 2, Title: "Movie 2", FilePath: "uploads/gone.mp4"},
+				},
+			}
+
+			handler := NewMovieHandler(NewMovieParser(), mockFlow, store, nil)
+
+			handler.StreamMovie(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Errorf("StreamMovie() status = %v, want %v", rr.Code, test.wantStatus)
+			}
+
+			if test.wantBody != "" {
+				if rr.Body.String() != test.wantBody {
+					t.Errorf("StreamMovie() body = %q, want %q", rr.Body.String(), test.wantBody)
 				}
-				if resp.Message != test.wantErrorMsg {
-					t.Errorf("DeleteMovie() error message = %v, want %v", resp.Message, test.wantErrorMsg)
+				if rr.Header().Get("Content-Type") != "video/mp4" {
+					t.Errorf("StreamMovie() Content-Type = %q, want video/mp4", rr.Header().Get("Content-Type"))
 				}
 			}
 		})
 	}
 }
+
+// decodeResponse decodes a success body into a Response and an error body
+// into a Problem.
+func decodeResponse(t *testing.T, rr *httptest.ResponseRecorder) (response.Response, response.Problem) {
+	t.Helper()
+
+	var resp response.Response
+	var problem response.Problem
+
+	var err error
+	if rr.Header().Get("Content-Type") == response.ProblemContentType {
+		err = json.NewDecoder(rr.Body).Decode(&problem)
+	} else {
+		err = json.NewDecoder(rr.Body).Decode(&resp)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp, problem
+}
@@ -1,69 +1,207 @@
 package movie
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
 	"roketin-case-study-challenge2/internal"
+	"roketin-case-study-challenge2/internal/apperror"
 	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/genre"
+	"roketin-case-study-challenge2/internal/probe"
+	"roketin-case-study-challenge2/internal/search"
 	"strconv"
 	"strings"
 )
 
 type MovieParserInterface interface {
-	ParseCreateMovie(r *http.Request) (*entity.Movie, *multipart.FileHeader, error)
+	ParseCreateMovie(r *http.Request) (*entity.Movie, *MovieFileInput, error)
 	ParseMovieFilter(r *http.Request) (*entity.MovieFilter, error)
 	ParseUpdateMovie(r *http.Request) (*entity.Movie, error)
+	ParseMovieFile(r *http.Request) (*entity.Movie, *MovieFileInput, error)
+	ParseMovieInclude(r *http.Request) (entity.MovieInclude, error)
+	ParseMoviePatch(r *http.Request) (*MoviePatch, error)
+	ParseIfMatch(r *http.Request) ([]int, error)
+	ParsePermanent(r *http.Request) (bool, error)
+	ParseSuggest(r *http.Request) (string, int, error)
 }
 
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// Limits of the varchar(255) columns of movies.
+const (
+	maxMovieTitleLength = 255
+	maxMovieListLength  = 255
+)
+
+// Default and largest number of suggestions of a suggest request.
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
 type MovieParser struct {
 }
 
+// MovieFileInput holds the video of a create or file replace request: either
+// a file sent in the same multipart request or the ID of a completed
+// resumable upload.
+type MovieFileInput struct {
+	Header   *multipart.FileHeader
+	UploadID string
+}
+
 func NewMovieParser() MovieParserInterface {
 	return &MovieParser{}
 }
 
-func (p *MovieParser) ParseCreateMovie(r *http.Request) (*entity.Movie, *multipart.FileHeader, error) {
+func (p *MovieParser) ParseCreateMovie(r *http.Request) (*entity.Movie, *MovieFileInput, error) {
+	if isJSONRequest(r) {
+		return p.parseCreateMovieJSON(r)
+	}
+
+	errs := &apperror.ValidationErrors{}
+
 	title := r.PostFormValue("title")
 	if title == "" {
-		return nil, nil, fmt.Errorf("title is required")
+		errs.Add("title", apperror.CodeRequired, "title is required")
 	}
 
 	description := r.PostFormValue("description")
-	durationStr := r.PostFormValue("duration_minutes")
-	duration, err := strconv.Atoi(durationStr)
+	duration := parseDuration(errs, r.PostFormValue("duration_minutes"))
+	artists := entity.NameList(internal.CleanCsvString(r.PostFormValue("artists")))
+	genres := entity.NameList(internal.CleanCsvString(r.PostFormValue("genres")))
+
+	uploadID := strings.TrimSpace(r.PostFormValue("upload_id"))
+
+	file, mimeType, err := parseMovieFileField(r, errs, uploadID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("duration must be a number")
+		return nil, nil, err
 	}
 
-	artists := r.PostFormValue("artists")
-	genres := r.PostFormValue("genres")
+	movieData := &entity.Movie{
+		Title:       title,
+		Description: description,
+		Duration:    duration,
+		Artists:     artists,
+		Genres:      genres,
+		MimeType:    mimeType,
+	}
 
-	_, file, err := r.FormFile("movie_file")
+	ValidateMovieFields(errs, movieData)
+	if err := errs.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return movieData, &MovieFileInput{Header: file, UploadID: uploadID}, nil
+}
+
+// ParseMovieFile reads the new video of a movie, a multipart movie_file or
+// the upload_id of a form or JSON body. The movie holds the sniffed type.
+func (p *MovieParser) ParseMovieFile(r *http.Request) (*entity.Movie, *MovieFileInput, error) {
+	if isJSONRequest(r) {
+		return p.parseMovieFileJSON(r)
+	}
+
+	errs := &apperror.ValidationErrors{}
+	uploadID := strings.TrimSpace(r.PostFormValue("upload_id"))
+
+	file, mimeType, err := parseMovieFileField(r, errs, uploadID)
 	if err != nil {
-		if err == http.ErrMissingFile {
-			return nil, nil, fmt.Errorf("movie file is required")
+		return nil, nil, err
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return &entity.Movie{MimeType: mimeType}, &MovieFileInput{Header: file, UploadID: uploadID}, nil
+}
+
+// parseMovieFileField checks the movie_file of a multipart request against
+// uploadID and returns it with its sniffed MIME type.
+func parseMovieFileField(r *http.Request, errs *apperror.ValidationErrors, uploadID string) (*multipart.FileHeader, string, error) {
+	_, file, err := r.FormFile("movie_file")
+	if err != nil && err != http.ErrMissingFile && err != http.ErrNotMultipart {
+		return nil, "", fmt.Errorf("failed to get movie file: %w", err)
+	}
+
+	var mimeType string
+	switch {
+	case file == nil && uploadID == "":
+		errs.Add("movie_file", apperror.CodeRequired, "movie file is required")
+	case file != nil && uploadID != "":
+		errs.Add("movie_file", apperror.CodeMutuallyExclusive, "provide either movie_file or upload_id, not both")
+	case file != nil:
+		if err := ValidateMovieFileName(file.Filename); err != nil {
+			errs.AddError("movie_file", apperror.CodeExtensionNotAllowed, err)
+			break
 		}
 
-		return nil, nil, fmt.Errorf("failed to get movie file: %w", err)
+		mimeType, err = sniffMovieFile(file)
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			errs.AddError("movie_file", apperror.CodeUnsupportedMediaType, err)
+		} else if err != nil {
+			return nil, "", err
+		}
 	}
 
-	allowedExtensions := map[string]bool{".mp4": true, ".mov": true, ".mkv": true, ".avi": true}
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !allowedExtensions[ext] {
-		return nil, nil, fmt.Errorf("file extension %s is not allowed", ext)
+	return file, mimeType, nil
+}
+
+// parseDuration records a field error for a duration that is not a number.
+// An empty duration is 0, which is filled in from the probed video.
+func parseDuration(errs *apperror.ValidationErrors, value string) int {
+	if value == "" {
+		return 0
 	}
 
-	movieData := &entity.Movie{
-		Title:       title,
-		Description: description,
-		Duration:    duration,
-		Artists:     internal.CleanCsvString(artists),
-		Genres:      internal.CleanCsvString(genres),
+	duration, err := strconv.Atoi(value)
+	if err != nil {
+		errs.Add("duration_minutes", apperror.CodeNotANumber, "duration must be a number")
+		return 0
 	}
 
-	return movieData, file, nil
+	return duration
+}
+
+// ValidateMovieFields records the field errors of the metadata of a movie.
+// Empty fields are left to the caller, as they are optional on update.
+func ValidateMovieFields(errs *apperror.ValidationErrors, movie *entity.Movie) {
+	if len(movie.Title) > maxMovieTitleLength {
+		errs.Add("title", apperror.CodeTooLong, "title must be at most %d characters", maxMovieTitleLength)
+	}
+
+	if movie.Duration < 0 {
+		errs.Add("duration_minutes", apperror.CodeOutOfRange, "duration must not be negative")
+	}
+
+	if len(movie.Artists) > maxMovieListLength {
+		errs.Add("artists", apperror.CodeTooLong, "artists must be at most %d characters", maxMovieListLength)
+	}
+
+	if len(movie.Genres) > maxMovieListLength {
+		errs.Add("genres", apperror.CodeTooLong, "genres must be at most %d characters", maxMovieListLength)
+	}
+
+	for _, name := range genre.SplitNames(string(movie.Genres)) {
+		if len(name) > genre.MaxNameLength {
+			errs.Add("genres", apperror.CodeTooLong, "genre %q must be at most %d characters", name, genre.MaxNameLength)
+		}
+	}
+}
+
+func ValidateMovieFileName(fileName string) error {
+	allowedExtensions := map[string]bool{".mp4": true, ".mov": true, ".mkv": true, ".avi": true}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !allowedExtensions[ext] {
+		return fmt.Errorf("file extension %s is not allowed", ext)
+	}
+
+	return nil
 }
 
 func (p *MovieParser) ParseMovieFilter(r *http.Request) (*entity.MovieFilter, error) {
@@ -71,8 +209,6 @@ func (p *MovieParser) ParseMovieFilter(r *http.Request) (*entity.MovieFilter, er
 
 	title := query.Get("title")
 	description := query.Get("description")
-	genres := query.Get("genre")
-	artists := query.Get("artist")
 	pageStr := query.Get("page")
 	limitStr := query.Get("limit")
 
@@ -100,38 +236,293 @@ func (p *MovieParser) ParseMovieFilter(r *http.Request) (*entity.MovieFilter, er
 		limit = l
 	}
 
+	facets, err := parseMovieFacets(query["facets"])
+	if err != nil {
+		return nil, err
+	}
+
+	fullText := strings.TrimSpace(query.Get("q"))
+
+	sorts, err := parseMovieSort(query["sort"], fullText != "")
+	if err != nil {
+		return nil, err
+	}
+
+	var expr search.Node
+	if value := strings.TrimSpace(query.Get("filter")); value != "" {
+		if expr, err = parseMovieQuery(value); err != nil {
+			return nil, err
+		}
+	}
+
 	return &entity.MovieFilter{
+		Query:       fullText,
+		Expr:        expr,
 		Title:       title,
 		Description: description,
-		Genres:      strings.Split(internal.CleanCsvString(genres), ","),
-		Artists:     strings.Split(internal.CleanCsvString(artists), ","),
+		Genres:      splitQueryValues(query["genre"]),
+		Artists:     splitQueryValues(query["artist"]),
+		Facets:      facets,
+		Sort:        sorts,
 		Page:        page,
 		Limit:       limit,
 	}, nil
 }
 
+// movieSortKeys are the keys ?sort= accepts.
+var movieSortKeys = map[string]bool{
+	entity.SortTitle:     true,
+	entity.SortDuration:  true,
+	entity.SortCreatedAt: true,
+	entity.SortUpdatedAt: true,
+	entity.SortRelevance: true,
+}
+
+// parseMovieSort reads ?sort=-duration,title: keys sort ascending, or
+// descending with a leading '-'. relevance puts the best matches of a
+// full-text query first and -relevance last.
+func parseMovieSort(values []string, fullText bool) ([]entity.MovieSort, error) {
+	var sorts []entity.MovieSort
+	errs := &apperror.ValidationErrors{}
+	seen := map[string]bool{}
+
+	for _, value := range splitQueryValues(values) {
+		desc := strings.HasPrefix(value, "-")
+		key := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+"))
+
+		switch {
+		case !movieSortKeys[key]:
+			errs.Add("sort", apperror.CodeInvalid, "cannot sort by %q, expected title, duration, created_at, updated_at or relevance", key)
+			continue
+		case key == entity.SortRelevance && !fullText:
+			errs.Add("sort", apperror.CodeInvalid, "cannot sort by relevance without a full-text query q")
+			continue
+		case seen[key]:
+			errs.Add("sort", apperror.CodeInvalid, "cannot sort by %s more than once", key)
+			continue
+		}
+		seen[key] = true
+
+		if key == entity.SortRelevance {
+			desc = !desc
+		}
+		sorts = append(sorts, entity.MovieSort{Key: key, Desc: desc})
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return sorts, nil
+}
+
+// parseMovieFacets reads ?facets=genre,artist,duration,year. Repeated facets
+// are counted once.
+func parseMovieFacets(values []string) ([]string, error) {
+	var facets []string
+	seen := map[string]bool{}
+	for _, value := range splitQueryValues(values) {
+		facet := strings.ToLower(value)
+		switch facet {
+		case entity.FacetGenre, entity.FacetArtist, entity.FacetDuration, entity.FacetYear:
+		default:
+			return nil, fmt.Errorf("facet %q is not valid, expected genre, artist, duration or year", value)
+		}
+
+		if !seen[facet] {
+			seen[facet] = true
+			facets = append(facets, facet)
+		}
+	}
+
+	return facets, nil
+}
+
+// ParseMovieInclude reads ?include=files,credits,stats.
+func (p *MovieParser) ParseMovieInclude(r *http.Request) (entity.MovieInclude, error) {
+	var include entity.MovieInclude
+	for _, value := range splitQueryValues(r.URL.Query()["include"]) {
+		switch strings.ToLower(value) {
+		case "files":
+			include.Files = true
+		case "credits":
+			include.Credits = true
+		case "stats":
+			include.Stats = true
+		default:
+			return include, fmt.Errorf("include %q is not valid, expected files, credits or stats", value)
+		}
+	}
+
+	return include, nil
+}
+
+// maxIfMatchTags caps the entity tags of an If-Match list, as each one
+// may cost a conditional write.
+const maxIfMatchTags = 16
+
+// ParseIfMatch returns the movie versions of an If-Match header: none
+// without the header, AnyVersion for "*" and otherwise the versions listed,
+// any of which may match. Entity tags that no movie version can match, such
+// as weak tags, are skipped; if none is left it fails with
+// ErrVersionMismatch.
+func (p *MovieParser) ParseIfMatch(r *http.Request) ([]int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch header {
+	case "":
+		return nil, nil
+	case "*":
+		return []int{AnyVersion}, nil
+	}
+
+	var versions []int
+	tags := 0
+	for rest := header; rest != ""; {
+		weak := strings.HasPrefix(rest, "W/")
+		rest = strings.TrimPrefix(rest, "W/")
+
+		if rest == "" || rest[0] != '"' {
+			return nil, fmt.Errorf("If-Match is not a list of entity tags: %s", header)
+		}
+		end := strings.IndexByte(rest[1:], '"') + 1
+		if end == 0 {
+			return nil, fmt.Errorf("If-Match is not a list of entity tags: %s", header)
+		}
+		tag := rest[1:end]
+		rest = strings.TrimSpace(rest[end+1:])
+
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("If-Match is not a list of entity tags: %s", header)
+			}
+			rest = strings.TrimSpace(rest[1:])
+		}
+
+		if tags++; tags > maxIfMatchTags {
+			return nil, fmt.Errorf("If-Match lists more than %d entity tags", maxIfMatchTags)
+		}
+
+		version, err := strconv.Atoi(tag)
+		if weak || err != nil || version <= 0 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	if len(versions) == 0 {
+		return nil, ErrVersionMismatch
+	}
+
+	return versions, nil
+}
+
+// ParsePermanent reads ?permanent= of a delete request.
+func (p *MovieParser) ParsePermanent(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("permanent")
+	if value == "" {
+		return false, nil
+	}
+
+	permanent, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("permanent must be true or false: '%s'", value)
+	}
+
+	return permanent, nil
+}
+
+// MovieETag is the entity tag of a version of a movie.
+func MovieETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// splitQueryValues accepts both repeated parameters and comma separated
+// lists, e.g. genre=Drama&genre=Comedy or genre=Drama,Comedy.
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		cleaned := internal.CleanCsvString(value)
+		if cleaned == "" {
+			continue
+		}
+		result = append(result, strings.Split(cleaned, ",")...)
+	}
+
+	return result
+}
+
 func (p *MovieParser) ParseUpdateMovie(r *http.Request) (*entity.Movie, error) {
-	title := r.PostFormValue("title")
-	description := r.PostFormValue("description")
-	durationStr := r.PostFormValue("duration_minutes")
-	artists := r.PostFormValue("artists")
-	genres := r.PostFormValue("genres")
+	if isJSONRequest(r) {
+		return p.parseUpdateMovieJSON(r)
+	}
+
+	errs := &apperror.ValidationErrors{}
 
 	movieData := &entity.Movie{
-		Title:       title,
-		Description: description,
-		Artists:     artists,
-		Genres:      genres,
+		Title:       r.PostFormValue("title"),
+		Description: r.PostFormValue("description"),
+		Duration:    parseDuration(errs, r.PostFormValue("duration_minutes")),
+		Artists:     entity.NameList(internal.CleanCsvString(r.PostFormValue("artists"))),
+		Genres:      entity.NameList(internal.CleanCsvString(r.PostFormValue("genres"))),
+	}
+
+	ValidateMovieFields(errs, movieData)
+	if err := errs.Err(); err != nil {
+		return nil, err
 	}
 
-	if durationStr != "" {
-		duration, err := strconv.Atoi(durationStr)
+	return movieData, nil
+}
+
+func sniffMovieFile(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open movie file: %w", err)
+	}
+	defer src.Close()
+
+	header := make([]byte, probe.SniffLength)
+	n, err := io.ReadFull(src, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read movie file: %w", err)
+	}
+
+	return DetectMovieMimeType(header[:n], file.Filename)
+}
+
+// DetectMovieMimeType checks the file signature against the claimed
+// extension and returns the MIME type of the detected container.
+func DetectMovieMimeType(header []byte, fileName string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	container, err := probe.Sniff(header)
+	if err != nil || !probe.MatchesExtension(container, ext) {
+		return "", fmt.Errorf("%w: file content is not a valid %s video", ErrUnsupportedMediaType, ext)
+	}
+
+	return probe.MimeType(container), nil
+}
+
+// ParseSuggest reads ?prefix= and ?limit= of a suggest request.
+func (p *MovieParser) ParseSuggest(r *http.Request) (string, int, error) {
+	query := r.URL.Query()
+
+	prefix := strings.TrimSpace(query.Get("prefix"))
+	if prefix == "" {
+		return "", 0, errors.New("prefix is required")
+	}
+
+	limit := defaultSuggestLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
 		if err != nil {
-			return nil, fmt.Errorf("duration must be a number")
+			return "", 0, fmt.Errorf("limit number is not valid: '%s'", limitStr)
 		}
-
-		movieData.Duration = duration
+		if l <= 0 || l > maxSuggestLimit {
+			return "", 0, fmt.Errorf("limit number must be between 1 and %d: %d", maxSuggestLimit, l)
+		}
+		limit = l
 	}
 
-	return movieData, nil
+	return prefix, limit, nil
 }
@@ -0,0 +1,201 @@
+package movie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/jsonpatch"
+	"strings"
+)
+
+// maxJSONBodySize bounds the metadata of a JSON request; the video itself is
+// sent through a resumable upload.
+const maxJSONBodySize = 1 << 20
+
+// movieBody is the JSON form of the metadata of a movie. Artists and genres
+// are lists instead of the comma separated values of form requests.
+type movieBody struct {
+	Title           string   `json:"title"`
+	Description     string   `json:"description"`
+	DurationMinutes int      `json:"duration_minutes"`
+	Artists         []string `json:"artists"`
+	Genres          []string `json:"genres"`
+	UploadID        string   `json:"upload_id,omitempty"`
+}
+
+// ParseMoviePatch reads the body of a PATCH request, which is either an
+// RFC 7396 merge patch or a list of RFC 6902 operations.
+func (p *MovieParser) ParseMoviePatch(r *http.Request) (*MoviePatch, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxJSONBodySize))
+
+	switch mediaType {
+	case MergePatchContentType, "application/json":
+		var merge interface{}
+		if err := decoder.Decode(&merge); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		if _, ok := merge.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("merge patch must be a JSON object")
+		}
+		return &MoviePatch{Merge: merge}, nil
+	case JSONPatchContentType:
+		var ops []jsonpatch.Operation
+		if err := decoder.Decode(&ops); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		for _, op := range ops {
+			if err := op.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		return &MoviePatch{Operations: ops}, nil
+	default:
+		return nil, fmt.Errorf("%w: PATCH expects %s", ErrUnsupportedMediaType, AcceptPatch)
+	}
+}
+
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func (p *MovieParser) parseCreateMovieJSON(r *http.Request) (*entity.Movie, *MovieFileInput, error) {
+	errs := &apperror.ValidationErrors{}
+
+	body, err := decodeMovieBody(r, errs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if body.Title == "" {
+		errs.Add("title", apperror.CodeRequired, "title is required")
+	}
+
+	movieData := body.toMovie(errs)
+
+	uploadID := strings.TrimSpace(body.UploadID)
+	if uploadID == "" {
+		errs.Add("upload_id", apperror.CodeRequired, "upload_id is required")
+	}
+
+	ValidateMovieFields(errs, movieData)
+	if err := errs.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return movieData, &MovieFileInput{UploadID: uploadID}, nil
+}
+
+func (p *MovieParser) parseUpdateMovieJSON(r *http.Request) (*entity.Movie, error) {
+	errs := &apperror.ValidationErrors{}
+
+	body, err := decodeMovieBody(r, errs)
+	if err != nil {
+		return nil, err
+	}
+
+	if body.UploadID != "" {
+		errs.Add("upload_id", apperror.CodeInvalid, "upload_id can only be given when creating a movie")
+	}
+
+	movieData := body.toMovie(errs)
+
+	ValidateMovieFields(errs, movieData)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return movieData, nil
+}
+
+func (p *MovieParser) parseMovieFileJSON(r *http.Request) (*entity.Movie, *MovieFileInput, error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxJSONBodySize))
+	decoder.DisallowUnknownFields()
+
+	var body struct {
+		UploadID string `json:"upload_id"`
+	}
+	if err := decoder.Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	uploadID := strings.TrimSpace(body.UploadID)
+	if uploadID == "" {
+		return nil, nil, apperror.Field("upload_id", apperror.CodeRequired, "upload_id is required")
+	}
+
+	return &entity.Movie{}, &MovieFileInput{UploadID: uploadID}, nil
+}
+
+func decodeMovieBody(r *http.Request, errs *apperror.ValidationErrors) (*movieBody, error) {
+	return decodeMovieJSON(http.MaxBytesReader(nil, r.Body, maxJSONBodySize), errs)
+}
+
+// decodeMovieJSON reads a JSON movie. A value of the wrong type is recorded
+// as a field error; malformed JSON and unknown fields fail the request.
+func decodeMovieJSON(reader io.Reader, errs *apperror.ValidationErrors) (*movieBody, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+
+	var body movieBody
+	err := decoder.Decode(&body)
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		field := strings.SplitN(typeErr.Field, ".", 2)[0]
+		if field == "duration_minutes" {
+			errs.Add(field, apperror.CodeNotANumber, "duration must be a number")
+		} else {
+			errs.Add(field, apperror.CodeInvalid, "%s must be %s", field, movieBodyTypes[field])
+		}
+	case err != nil:
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+
+	return &body, nil
+}
+
+var movieBodyTypes = map[string]string{
+	"title":            "a string",
+	"description":      "a string",
+	"duration_minutes": "a number",
+	"artists":          "a list of strings",
+	"genres":           "a list of strings",
+	"upload_id":        "a string",
+}
+
+func (b *movieBody) toMovie(errs *apperror.ValidationErrors) *entity.Movie {
+	return &entity.Movie{
+		Title:       b.Title,
+		Description: b.Description,
+		Duration:    b.DurationMinutes,
+		Artists:     joinList(errs, "artists", b.Artists),
+		Genres:      joinList(errs, "genres", b.Genres),
+	}
+}
+
+// joinList stores a list as the comma separated value of its column, so
+// entries must not contain commas themselves.
+func joinList(errs *apperror.ValidationErrors, field string, values []string) entity.NameList {
+	var parts []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if strings.Contains(value, ",") {
+			errs.Add(field, apperror.CodeInvalid, "%s entry %q must not contain a comma", field, value)
+			continue
+		}
+		parts = append(parts, value)
+	}
+
+	return entity.NameList(strings.Join(parts, ","))
+}
@@ -0,0 +1,70 @@
+package movie
+
+import (
+	"roketin-case-study-challenge2/internal/search"
+	"strconv"
+)
+
+// movieQueryFields are the fields of a filter query, mapped to whether they
+// compare numerically and so accept <, <=, >, >= and =.
+var movieQueryFields = map[string]bool{
+	"title":       false,
+	"description": false,
+	"genre":       false,
+	"artist":      false,
+	"duration":    true,
+}
+
+// parseMovieQuery parses the filter= query of a movie search. Terms without
+// a field match the title or description.
+func parseMovieQuery(input string) (search.Node, error) {
+	node, err := search.ParseQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateMovieQuery(node); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func validateMovieQuery(node search.Node) error {
+	switch n := node.(type) {
+	case search.And:
+		if err := validateMovieQuery(n.Left); err != nil {
+			return err
+		}
+		return validateMovieQuery(n.Right)
+	case search.Or:
+		if err := validateMovieQuery(n.Left); err != nil {
+			return err
+		}
+		return validateMovieQuery(n.Right)
+	case search.Not:
+		return validateMovieQuery(n.Node)
+	case search.Term:
+		if n.Field == "" {
+			return nil
+		}
+
+		numeric, ok := movieQueryFields[n.Field]
+		if !ok {
+			return search.NewSyntaxError(n.Pos, "unknown field %q, expected title, description, genre, artist or duration", n.Field)
+		}
+
+		if !numeric {
+			if n.Op != "" {
+				return search.NewSyntaxError(n.Pos, "%s cannot be compared with %s", n.Field, n.Op)
+			}
+			return nil
+		}
+
+		if minutes, err := strconv.Atoi(n.Value); err != nil || minutes < 0 {
+			return search.NewSyntaxError(n.ValuePos, "%s must be a whole number of minutes, got %q", n.Field, n.Value)
+		}
+	}
+
+	return nil
+}
@@ -2,12 +2,23 @@ package movie
 
 import (
 	"bytes"
+	"errors"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"roketin-case-study-challenge2/internal"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/search"
 	"strconv"
+	"strings"
 	"testing"
 )
 
+const testMP4Content = "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isommp41"
+
 func TestParseCreateMovie(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -26,7 +37,7 @@ func TestParseCreateMovie(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData: "test content",
+			fileData: testMP4Content,
 			fileName: "test.mp4",
 			wantErr:  false,
 		},
@@ -39,7 +50,7 @@ func TestParseCreateMovie(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData:   "test content",
+			fileData:   testMP4Content,
 			fileName:   "test.mp4",
 			wantErr:    true,
 			errMessage: "title is required",
@@ -53,7 +64,7 @@ func TestParseCreateMovie(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData:   "test content",
+			fileData:   testMP4Content,
 			fileName:   "test.mp4",
 			wantErr:    true,
 			errMessage: "duration must be a number",
@@ -79,11 +90,39 @@ func TestParseCreateMovie(t *testing.T) {
 				"artists":          "Test Artist",
 				"genres":           "Action",
 			},
-			fileData:   "test content",
+			fileData:   testMP4Content,
 			fileName:   "test.txt",
 			wantErr:    true,
 			errMessage: "file extension .txt is not allowed",
 		},
+		{
+			name: "fail - content does not match extension",
+			formData: map[string]string{
+				"title":            "Test Movie",
+				"description":      "Test Description",
+				"duration_minutes": "120",
+				"artists":          "Test Artist",
+				"genres":           "Action",
+			},
+			fileData:   "test content",
+			fileName:   "test.mp4",
+			wantErr:    true,
+			errMessage: "unsupported media type: file content is not a valid .mp4 video",
+		},
+		{
+			name: "fail - mp4 content renamed to avi",
+			formData: map[string]string{
+				"title":            "Test Movie",
+				"description":      "Test Description",
+				"duration_minutes": "120",
+				"artists":          "Test Artist",
+				"genres":           "Action",
+			},
+			fileData:   testMP4Content,
+			fileName:   "test.avi",
+			wantErr:    true,
+			errMessage: "unsupported media type: file content is not a valid .avi video",
+		},
 	}
 
 	for _, test := range tests {
@@ -144,6 +183,10 @@ func TestParseCreateMovie(t *testing.T) {
 			if file == nil {
 				t.Error("ParseCreateMovie() file is nil")
 			}
+
+			if movie.MimeType != "video/mp4" {
+				t.Errorf("ParseCreateMovie() mime type = %v, want video/mp4", movie.MimeType)
+			}
 		})
 	}
 }
@@ -157,7 +200,10 @@ func TestParseMovieFilter(t *testing.T) {
 		wantPage    int
 		wantLimit   int
 		wantTitle   string
+		wantQuery   string
 		wantGenres  []string
+		wantFacets  []string
+		wantSort    []entity.MovieSort
 	}{
 		{
 			name: "success - with all parameters",
@@ -173,6 +219,16 @@ func TestParseMovieFilter(t *testing.T) {
 			wantTitle:  "Test Movie",
 			wantGenres: []string{"Action", "Drama"},
 		},
+		{
+			name: "success - comma separated genres",
+			queryParams: map[string][]string{
+				"genre": {"Action, Drama", "Comedy"},
+			},
+			wantErr:    false,
+			wantPage:   1,
+			wantLimit:  10,
+			wantGenres: []string{"Action", "Drama", "Comedy"},
+		},
 		{
 			name: "success - without page and limit",
 			queryParams: map[string][]string{
@@ -184,6 +240,75 @@ func TestParseMovieFilter(t *testing.T) {
 			wantTitle:  "Test Movie",
 			wantGenres: []string{},
 		},
+		{
+			name: "success - full-text query",
+			queryParams: map[string][]string{
+				"q": {"  space odyssey "},
+			},
+			wantErr:    false,
+			wantPage:   1,
+			wantLimit:  10,
+			wantQuery:  "space odyssey",
+			wantGenres: []string{},
+		},
+		{
+			name: "success - facets",
+			queryParams: map[string][]string{
+				"facets": {"Genre,duration", "genre", "year"},
+			},
+			wantErr:    false,
+			wantPage:   1,
+			wantLimit:  10,
+			wantGenres: []string{},
+			wantFacets: []string{"genre", "duration", "year"},
+		},
+		{
+			name: "success - sort",
+			queryParams: map[string][]string{
+				"sort": {"-duration,title", "+updated_at"},
+			},
+			wantErr:    false,
+			wantPage:   1,
+			wantLimit:  10,
+			wantGenres: []string{},
+			wantSort: []entity.MovieSort{
+				{Key: entity.SortDuration, Desc: true},
+				{Key: entity.SortTitle},
+				{Key: entity.SortUpdatedAt},
+			},
+		},
+		{
+			name: "success - sort by relevance",
+			queryParams: map[string][]string{
+				"q":    {"space"},
+				"sort": {"relevance,-created_at"},
+			},
+			wantErr:    false,
+			wantPage:   1,
+			wantLimit:  10,
+			wantQuery:  "space",
+			wantGenres: []string{},
+			wantSort: []entity.MovieSort{
+				{Key: entity.SortRelevance, Desc: true},
+				{Key: entity.SortCreatedAt, Desc: true},
+			},
+		},
+		{
+			name: "fail - invalid sort keys",
+			queryParams: map[string][]string{
+				"sort": {"rating,-relevance,title,TITLE"},
+			},
+			wantErr:    true,
+			errMessage: `cannot sort by "rating", expected title, duration, created_at, updated_at or relevance; cannot sort by relevance without a full-text query q; cannot sort by title more than once`,
+		},
+		{
+			name: "fail - unknown facet",
+			queryParams: map[string][]string{
+				"facets": {"genre,rating"},
+			},
+			wantErr:    true,
+			errMessage: `facet "rating" is not valid, expected genre, artist, duration or year`,
+		},
 		{
 			name: "fail - invalid page",
 			queryParams: map[string][]string{
@@ -249,6 +374,10 @@ func TestParseMovieFilter(t *testing.T) {
 				t.Errorf("ParseMovieFilter() title = %v, want %v", filter.Title, test.wantTitle)
 			}
 
+			if filter.Query != test.wantQuery {
+				t.Errorf("ParseMovieFilter() query = %v, want %v", filter.Query, test.wantQuery)
+			}
+
 			if len(filter.Genres) != len(test.wantGenres) {
 				t.Errorf("ParseMovieFilter() genres length = %v, want %v", len(filter.Genres), len(test.wantGenres))
 			}
@@ -258,6 +387,50 @@ func TestParseMovieFilter(t *testing.T) {
 					t.Errorf("ParseMovieFilter() genre[%d] = %v, want %v", i, genre, test.wantGenres[i])
 				}
 			}
+
+			if !reflect.DeepEqual(filter.Sort, test.wantSort) {
+				t.Errorf("ParseMovieFilter() sort = %v, want %v", filter.Sort, test.wantSort)
+			}
+
+			if strings.Join(filter.Facets, ",") != strings.Join(test.wantFacets, ",") {
+				t.Errorf("ParseMovieFilter() facets = %v, want %v", filter.Facets, test.wantFacets)
+			}
+		})
+	}
+}
+
+func TestParseMovieFilterQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr string
+	}{
+		{name: "success", filter: `genre:drama AND (artist:"Jane Doe" OR artist:kim) -genre:horror duration:<20`},
+		{name: "success - bare terms", filter: "space OR odyssey"},
+		{name: "fail - unknown field", filter: "genre:drama year:1999", wantErr: `invalid query at position 13: unknown field "year", expected title, description, genre, artist or duration`},
+		{name: "fail - duration is not a number", filter: "duration:>long", wantErr: `invalid query at position 11: duration must be a whole number of minutes, got "long"`},
+		{name: "fail - text field compared", filter: "-title:<b", wantErr: "invalid query at position 2: title cannot be compared with <"},
+		{name: "fail - syntax", filter: "(genre:drama", wantErr: "invalid query at position 1: unclosed '('"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?"+url.Values{"filter": {test.filter}}.Encode(), nil)
+
+			filter, err := NewMovieParser().ParseMovieFilter(req)
+			if test.wantErr != "" {
+				var syntaxErr *search.SyntaxError
+				if !errors.As(err, &syntaxErr) || err.Error() != test.wantErr {
+					t.Errorf("ParseMovieFilter() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMovieFilter() error = %v", err)
+			}
+			if filter.Expr == nil {
+				t.Error("ParseMovieFilter() expr = nil, want a query")
+			}
 		})
 	}
 }
@@ -358,15 +531,294 @@ func TestParseUpdateMovie(t *testing.T) {
 						t.Errorf("ParseUpdateMovie() duration = %v, want %v", movie.Duration, duration)
 					}
 				case "artists":
-					if movie.Artists != value {
-						t.Errorf("ParseUpdateMovie() artists = %v, want %v", movie.Artists, value)
+					if want := internal.CleanCsvString(value); string(movie.Artists) != want {
+						t.Errorf("ParseUpdateMovie() artists = %v, want %v", movie.Artists, want)
 					}
 				case "genres":
-					if movie.Genres != value {
-						t.Errorf("ParseUpdateMovie() genres = %v, want %v", movie.Genres, value)
+					if want := internal.CleanCsvString(value); string(movie.Genres) != want {
+						t.Errorf("ParseUpdateMovie() genres = %v, want %v", movie.Genres, want)
 					}
 				}
 			}
 		})
 	}
 }
+
+func TestParseMovieInclude(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    entity.MovieInclude
+		wantErr bool
+	}{
+		{name: "no include", query: "", want: entity.MovieInclude{}},
+		{name: "comma separated", query: "include=files,stats", want: entity.MovieInclude{Files: true, Stats: true}},
+		{name: "repeated", query: "include=credits&include=Files", want: entity.MovieInclude{Files: true, Credits: true}},
+		{name: "unknown", query: "include=reviews", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/?"+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			include, err := NewMovieParser().ParseMovieInclude(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseMovieInclude() error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if include != test.want {
+				t.Errorf("ParseMovieInclude() = %+v, want %+v", include, test.want)
+			}
+		})
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		want        []int
+		wantErr     bool
+		wantVersion bool
+	}{
+		{name: "no header", header: "", want: nil},
+		{name: "any", header: "*", want: []int{AnyVersion}},
+		{name: "version", header: `"7"`, want: []int{7}},
+		{name: "list", header: `"7", "8"`, want: []int{7, 8}},
+		{name: "list without spaces", header: `"7","8"`, want: []int{7, 8}},
+		{name: "list with weak tag", header: `W/"7", "8"`, want: []int{8}},
+		{name: "list with foreign tag", header: `"abc", "8"`, want: []int{8}},
+		{name: "weak tag", header: `W/"7"`, wantErr: true, wantVersion: true},
+		{name: "foreign tag", header: `"abc"`, wantErr: true, wantVersion: true},
+		{name: "unquoted", header: "7", wantErr: true},
+		{name: "unquoted in list", header: `"7", 8`, wantErr: true},
+		{name: "unterminated", header: `"7`, wantErr: true},
+		{name: "trailing comma", header: `"7",`, want: []int{7}},
+		{name: "too many tags", header: strings.Repeat(`"1", `, maxIfMatchTags) + `"2"`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("PUT", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.header != "" {
+				req.Header.Set("If-Match", test.header)
+			}
+
+			versions, err := NewMovieParser().ParseIfMatch(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseIfMatch() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				if errors.Is(err, ErrVersionMismatch) != test.wantVersion {
+					t.Errorf("ParseIfMatch() error = %v, version mismatch %v", err, test.wantVersion)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(versions, test.want) {
+				t.Errorf("ParseIfMatch() = %v, want %v", versions, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSuggest(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantPrefix string
+		wantLimit  int
+		wantErr    bool
+	}{
+		{name: "default limit", query: "prefix=+ame+", wantPrefix: "ame", wantLimit: 10},
+		{name: "limit", query: "prefix=ame&limit=5", wantPrefix: "ame", wantLimit: 5},
+		{name: "missing prefix", query: "limit=5", wantErr: true},
+		{name: "blank prefix", query: "prefix=+", wantErr: true},
+		{name: "invalid limit", query: "prefix=ame&limit=x", wantErr: true},
+		{name: "limit too large", query: "prefix=ame&limit=51", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/suggest?"+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prefix, limit, err := NewMovieParser().ParseSuggest(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseSuggest() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if prefix != test.wantPrefix || limit != test.wantLimit {
+				t.Errorf("ParseSuggest() = %q, %d, want %q, %d", prefix, limit, test.wantPrefix, test.wantLimit)
+			}
+		})
+	}
+}
+
+func TestParseMovieFileJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		wantUpload     string
+		wantErr        bool
+		wantValidation bool
+	}{
+		{name: "success", body: `{"upload_id": " abc "}`, wantUpload: "abc"},
+		{name: "fail - missing upload", body: `{}`, wantErr: true, wantValidation: true},
+		{name: "fail - metadata given", body: `{"upload_id": "abc", "title": "New"}`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/1/file", strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			_, file, err := NewMovieParser().ParseMovieFile(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseMovieFile() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				if errors.Is(err, apperror.ErrValidation) != test.wantValidation {
+					t.Errorf("ParseMovieFile() error = %v, validation error %v", err, test.wantValidation)
+				}
+				return
+			}
+
+			if file.UploadID != test.wantUpload {
+				t.Errorf("ParseMovieFile() upload ID = %q, want %q", file.UploadID, test.wantUpload)
+			}
+		})
+	}
+}
+
+func TestParseMovieJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		update      bool
+		body        string
+		wantErr     bool
+		wantFields  []string
+		wantArtists entity.NameList
+		wantGenres  entity.NameList
+		wantUpload  string
+	}{
+		{
+			name:        "success create movie",
+			body:        `{"title": "Test Movie", "duration_minutes": 120, "artists": ["Jane Doe", " John Roe "], "genres": ["Action", "Drama"], "upload_id": "abc"}`,
+			wantArtists: "Jane Doe,John Roe",
+			wantGenres:  "Action,Drama",
+			wantUpload:  "abc",
+		},
+		{
+			name:       "fail - create without title and upload",
+			body:       `{"description": "Test Description"}`,
+			wantErr:    true,
+			wantFields: []string{"title:required", "upload_id:required"},
+		},
+		{
+			name:       "fail - wrong types",
+			body:       `{"title": "Test Movie", "duration_minutes": "abc", "upload_id": "abc"}`,
+			wantErr:    true,
+			wantFields: []string{"duration_minutes:not_a_number"},
+		},
+		{
+			name:       "fail - artists as string",
+			body:       `{"title": "Test Movie", "artists": "Jane Doe, John Roe", "upload_id": "abc"}`,
+			wantErr:    true,
+			wantFields: []string{"artists:invalid"},
+		},
+		{
+			name:       "fail - entry with comma",
+			body:       `{"title": "Test Movie", "genres": ["Action, Drama"], "upload_id": "abc"}`,
+			wantErr:    true,
+			wantFields: []string{"genres:invalid"},
+		},
+		{
+			name:    "fail - unknown field",
+			body:    `{"title": "Test Movie", "movie_file": "film.mp4"}`,
+			wantErr: true,
+		},
+		{
+			name:    "fail - malformed body",
+			body:    `{"title": `,
+			wantErr: true,
+		},
+		{
+			name:        "success update movie",
+			update:      true,
+			body:        `{"duration_minutes": 90, "genres": ["Comedy"]}`,
+			wantGenres:  "Comedy",
+			wantArtists: "",
+		},
+		{
+			name:       "fail - update with upload",
+			update:     true,
+			body:       `{"upload_id": "abc"}`,
+			wantErr:    true,
+			wantFields: []string{"upload_id:invalid"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", strings.NewReader(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+			parser := NewMovieParser()
+
+			var movie *entity.Movie
+			var file *MovieFileInput
+			if test.update {
+				movie, err = parser.ParseUpdateMovie(req)
+			} else {
+				movie, file, err = parser.ParseCreateMovie(req)
+			}
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parse error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				var verr *apperror.ValidationErrors
+				if len(test.wantFields) == 0 {
+					if errors.As(err, &verr) {
+						t.Errorf("parse error = %v, want a non-validation error", err)
+					}
+					return
+				}
+				if !errors.As(err, &verr) {
+					t.Fatalf("parse error = %v, want validation errors", err)
+				}
+				var got []string
+				for _, field := range verr.Fields {
+					got = append(got, field.Field+":"+field.Code)
+				}
+				if strings.Join(got, ",") != strings.Join(test.wantFields, ",") {
+					t.Errorf("field errors = %v, want %v", got, test.wantFields)
+				}
+				return
+			}
+
+			if movie.Artists != test.wantArtists || movie.Genres != test.wantGenres {
+				t.Errorf("artists = %q, genres = %q, want %q and %q", movie.Artists, movie.Genres, test.wantArtists, test.wantGenres)
+			}
+
+			if !test.update && file.UploadID != test.wantUpload {
+				t.Errorf("upload ID = %q, want %q", file.UploadID, test.wantUpload)
+			}
+		})
+	}
+}
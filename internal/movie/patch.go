@@ -0,0 +1,106 @@
+package movie
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/jsonpatch"
+	"sort"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+
+	// AcceptPatch lists the patch formats of PATCH /api/movies/{id}.
+	AcceptPatch = MergePatchContentType + ", " + JSONPatchContentType
+)
+
+// MoviePatch is a partial update of a movie, either an RFC 7396 merge patch
+// or RFC 6902 operations. Both apply to the JSON form of the movie.
+type MoviePatch struct {
+	Merge      interface{}
+	Operations []jsonpatch.Operation
+
+	// Version is the version the patch is based on, see AnyVersion.
+	Version int
+}
+
+func (p *MoviePatch) apply(doc interface{}) (interface{}, error) {
+	if p.Operations == nil {
+		return jsonpatch.Merge(doc, p.Merge), nil
+	}
+
+	patched, err := jsonpatch.Apply(doc, p.Operations)
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return nil, apperror.Wrap(apperror.ErrConflict, err, "%s", err.Error())
+	case err != nil:
+		return nil, apperror.Wrap(apperror.ErrValidation, err, "%s", err.Error())
+	}
+
+	return patched, nil
+}
+
+// movieDocument returns the metadata of movie in the shape of a JSON create
+// request, which is what patches are applied to.
+func movieDocument(movie *entity.Movie) (interface{}, error) {
+	body := movieBody{
+		Title:           movie.Title,
+		Description:     movie.Description,
+		DurationMinutes: movie.Duration,
+		Artists:         movie.Artists.Names(),
+		Genres:          movie.Genres.Names(),
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// decodePatchedMovie reads a patched document back into a movie body.
+// Members that are not movie fields are recorded as field errors.
+func decodePatchedMovie(doc interface{}, errs *apperror.ValidationErrors) *movieBody {
+	object, ok := doc.(map[string]interface{})
+	if !ok {
+		errs.Add("", apperror.CodeInvalid, "patched movie must be a JSON object")
+		return &movieBody{}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, known := movieBodyTypes[key]; !known || key == "upload_id" {
+			errs.Add(key, apperror.CodeInvalid, "%s is not a field of a movie", key)
+			delete(object, key)
+		}
+	}
+
+	data, err := json.Marshal(object)
+	if err != nil {
+		errs.Add("", apperror.CodeInvalid, "patched movie is not valid JSON")
+		return &movieBody{}
+	}
+
+	body, err := decodeMovieJSON(bytes.NewReader(data), errs)
+	if err != nil {
+		errs.Add("", apperror.CodeInvalid, "patched movie is not valid JSON")
+		return &movieBody{}
+	}
+
+	return body
+}
@@ -0,0 +1,100 @@
+package movie
+
+import (
+	"context"
+	"errors"
+	"log"
+	"roketin-case-study-challenge2/internal/auth"
+	"time"
+)
+
+// PurgeReport lists the movies removed by PurgeDeletedMovies, or the movies
+// a dry run would remove.
+type PurgeReport struct {
+	DryRun bool
+	Before time.Time
+	Movies []PurgedMovie
+}
+
+type PurgedMovie struct {
+	ID        int
+	Title     string
+	DeletedAt time.Time
+	Files     []string
+}
+
+// PurgeMovie permanently removes a movie and its stored videos.
+func (f *movieFlow) PurgeMovie(ctx context.Context, id int, version int) error {
+	if err := auth.Authorize(ctx, auth.ActionPurgeMovie); err != nil {
+		return err
+	}
+
+	version, err := f.checkVersion(version)
+	if err != nil {
+		return err
+	}
+
+	paths, err := f.movieRepo.PurgeMovie(ctx, id, version)
+	if err != nil {
+		return err
+	}
+
+	f.invalidateSearchTerms()
+	f.deleteFiles(ctx, paths)
+	return nil
+}
+
+// PurgeDeletedMovies permanently removes the movies that were soft deleted
+// longer than the retention window ago. A dry run only reports them.
+func (f *movieFlow) PurgeDeletedMovies(ctx context.Context, dryRun bool) (*PurgeReport, error) {
+	report := &PurgeReport{DryRun: dryRun, Before: f.now().Add(-f.cfg.Retention)}
+
+	movies, err := f.movieRepo.ListPurgeableMovies(ctx, report.Before)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, movie := range movies {
+		purged := PurgedMovie{ID: movie.ID, Title: movie.Title}
+		if movie.DeletedAt != nil {
+			purged.DeletedAt = movie.DeletedAt.Time
+		}
+
+		if dryRun {
+			if movie.FilePath != "" {
+				purged.Files = append(purged.Files, movie.FilePath)
+			}
+			versions, err := f.movieRepo.GetMovieFileVersions(ctx, movie.ID)
+			if err != nil {
+				return report, err
+			}
+			for _, version := range versions {
+				purged.Files = append(purged.Files, version.FilePath)
+			}
+		} else {
+			purged.Files, err = f.movieRepo.PurgeDeletedMovie(ctx, movie.ID, report.Before)
+			if errors.Is(err, ErrMovieNotPurgeable) {
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			f.invalidateSearchTerms()
+			f.deleteFiles(ctx, purged.Files)
+		}
+
+		report.Movies = append(report.Movies, purged)
+	}
+
+	return report, nil
+}
+
+// deleteFiles removes videos whose rows are already gone. Failures are only
+// logged, as the purge itself has happened.
+func (f *movieFlow) deleteFiles(ctx context.Context, paths []string) {
+	for _, path := range paths {
+		if err := f.storage.Delete(ctx, path); err != nil {
+			log.Printf("Failed to delete movie file %s: %v", path, err)
+		}
+	}
+}
@@ -2,12 +2,42 @@ package movie
 
 import (
 	"context"
+	"roketin-case-study-challenge2/internal/apperror"
 	"roketin-case-study-challenge2/internal/entity"
+	"time"
 )
 
+var (
+	ErrMovieNotFound   = apperror.New(apperror.ErrNotFound, "movie not found")
+	ErrVersionMismatch = apperror.New(apperror.ErrPreconditionFailed, "movie has been modified since it was read")
+	ErrMovieNotDeleted = apperror.New(apperror.ErrConflict, "movie is not deleted")
+	// ErrMovieNotPurgeable is returned by PurgeDeletedMovie for a movie that
+	// was restored, or deleted again, since it was listed.
+	ErrMovieNotPurgeable = apperror.New(apperror.ErrConflict, "movie is no longer due to be purged")
+)
+
+func newMovieNotFoundError(id int) error {
+	return apperror.Wrap(apperror.ErrNotFound, ErrMovieNotFound, "movie with ID %d not found", id)
+}
+
 type MovieRepository interface {
 	ListMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error)
-	CreateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
+	ListDeletedMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error)
+	GetMovie(ctx context.Context, id int) (*entity.Movie, error)
+	GetMovieCredits(ctx context.Context, id int) ([]entity.Credit, error)
+	GetMovieStats(ctx context.Context, id int) (*entity.MovieStats, error)
+	ListSearchTerms(ctx context.Context) ([]entity.Suggestion, error)
+	ListMovieFacets(ctx context.Context, filter *entity.MovieFilter) (entity.MovieFacets, error)
+	// CreateMovie runs beforeCommit inside the transaction inserting movie,
+	// which is rolled back when it fails.
+	CreateMovie(ctx context.Context, movie *entity.Movie, beforeCommit func() error) (*entity.Movie, error)
 	UpdateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
-	DeleteMovie(ctx context.Context, id int) error
-}
\ No newline at end of file
+	ReplaceMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
+	ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error)
+	GetMovieFileVersions(ctx context.Context, id int) ([]entity.MovieFileVersion, error)
+	DeleteMovie(ctx context.Context, id int, version int) error
+	RestoreMovie(ctx context.Context, id int) (*entity.Movie, error)
+	ListPurgeableMovies(ctx context.Context, deletedBefore time.Time) ([]entity.Movie, error)
+	PurgeMovie(ctx context.Context, id int, version int) ([]string, error)
+	PurgeDeletedMovie(ctx context.Context, id int, deletedBefore time.Time) ([]string, error)
+}
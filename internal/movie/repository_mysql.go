@@ -1,12 +1,20 @@
 package movie
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"roketin-case-study-challenge2/internal/apperror"
 	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/search"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type mySQLMovieRepository struct {
@@ -19,21 +27,108 @@ func NewMySQLMovieRepository(db *gorm.DB) MovieRepository {
 	}
 }
 
-func (r *mySQLMovieRepository) CreateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
-	result := r.db.WithContext(ctx).Create(movie)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to create movie: %w", result.Error)
+func (r *mySQLMovieRepository) CreateMovie(ctx context.Context, movie *entity.Movie, beforeCommit func() error) (*entity.Movie, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(movie).Error; err != nil {
+			return fmt.Errorf("failed to create movie: %w", err)
+		}
+
+		if beforeCommit != nil {
+			return beforeCommit()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return movie, nil
 }
 
 func (r *mySQLMovieRepository) ListMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	query := r.db.WithContext(ctx).Model(&entity.Movie{})
+
+	return r.findMovies(query, filter, "created_at DESC")
+}
+
+// ListDeletedMovies lists soft deleted movies, most recently deleted first.
+func (r *mySQLMovieRepository) ListDeletedMovies(ctx context.Context, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	query := r.db.WithContext(ctx).Unscoped().Model(&entity.Movie{}).Where("movies.deleted_at IS NOT NULL")
+
+	return r.findMovies(query, filter, "deleted_at DESC")
+}
+
+// fullTextMatch matches the FULLTEXT index of movies. MySQL ranks natural
+// language queries with a BM25 style weighting.
+const fullTextMatch = "MATCH (movies.title, movies.description, movies.artists, movies.genres) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// findMovies narrows query down by filter and returns a page of it in order.
+func (r *mySQLMovieRepository) findMovies(query *gorm.DB, filter *entity.MovieFilter, order string) ([]entity.Movie, int64, error) {
 	var movies []entity.Movie
 	var total int64
 
-	query := r.db.WithContext(ctx).Model(&entity.Movie{})
+	query = r.filterMovies(query, filter)
+
+	if filter.Query != "" {
+		if r.db.Dialector.Name() != "mysql" {
+			return rankMovies(query, filter)
+		}
+		query = query.Where(fullTextMatch, filter.Query)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get total movies: %w", err)
+	}
+
+	page := filter.GetPage()
+	limit := filter.GetLimit()
+	offset := (page - 1) * limit
+
+	if filter.Query != "" {
+		query = query.Select("movies.*, "+fullTextMatch+" AS score", filter.Query)
+		order = "score DESC"
+	}
+
+	result := query.Order(movieOrder(filter.Sort, order)).Limit(limit).Offset(offset).Find(&movies)
+	if result.Error != nil {
+		return nil, 0, fmt.Errorf("failed to get movies: %w", result.Error)
+	}
 
+	return movies, total, nil
+}
+
+// movieSortColumns are the columns of the sort keys of a movie search.
+var movieSortColumns = map[string]string{
+	entity.SortTitle:     "movies.title",
+	entity.SortDuration:  "movies.duration",
+	entity.SortCreatedAt: "movies.created_at",
+	entity.SortUpdatedAt: "movies.updated_at",
+	entity.SortRelevance: "score",
+}
+
+// movieOrder is the ORDER BY of sorts, or of order if there are none. Equal
+// movies are ordered by ID so that pages do not overlap.
+func movieOrder(sorts []entity.MovieSort, order string) string {
+	if len(sorts) == 0 {
+		return order + ", movies.id ASC"
+	}
+
+	columns := make([]string, len(sorts))
+	for i, key := range sorts {
+		direction := " ASC"
+		if key.Desc {
+			direction = " DESC"
+		}
+		columns[i] = movieSortColumns[key.Key] + direction
+	}
+
+	return strings.Join(columns, ", ") + ", movies.id ASC"
+}
+
+// filterMovies narrows query down by the conditions of filter but its
+// full-text query.
+func (r *mySQLMovieRepository) filterMovies(query *gorm.DB, filter *entity.MovieFilter) *gorm.DB {
 	if filter.Title != "" {
 		query = query.Where("LOWER(title) LIKE ?", "%"+strings.ToLower(filter.Title)+"%")
 	}
@@ -43,57 +138,496 @@ func (r *mySQLMovieRepository) ListMovies(ctx context.Context, filter *entity.Mo
 	}
 
 	if len(filter.Genres) > 0 {
-		var conditions []string
-		var values []interface{}
-
-		for _, genre := range filter.Genres {
-			conditions = append(conditions, "genres LIKE ?")
-			values = append(values, "%"+genre+"%")
+		names := make([]string, len(filter.Genres))
+		for i, genre := range filter.Genres {
+			names[i] = strings.ToLower(genre)
 		}
 
-		query = query.Where(strings.Join(conditions, " OR "), values...)
+		movieIDs := r.db.Table("movie_genres").
+			Select("movie_genres.movie_id").
+			Joins("JOIN genres ON genres.id = movie_genres.genre_id").
+			Where("LOWER(genres.name) IN ?", names)
+
+		query = query.Where("movies.id IN (?)", movieIDs)
 	}
 
 	if len(filter.Artists) > 0 {
-		var conditions []string
+		var personConditions []string
+		var legacyConditions []string
 		var values []interface{}
 
 		for _, artist := range filter.Artists {
-			conditions = append(conditions, "artists LIKE ?")
-			values = append(values, "%"+artist+"%")
+			personConditions = append(personConditions, "LOWER(people.name) LIKE ?")
+			legacyConditions = append(legacyConditions, "LOWER(movies.artists) LIKE ?")
+			values = append(values, "%"+strings.ToLower(artist)+"%")
 		}
 
-		query = query.Where(strings.Join(conditions, " OR "), values...)
+		credited := r.db.Table("credits").
+			Select("credits.movie_id").
+			Joins("JOIN people ON people.id = credits.person_id").
+			Where(strings.Join(personConditions, " OR "), values...)
+
+		// The free-text artists column still matches movies that have no
+		// credits recorded yet.
+		query = query.Where(r.db.Where("movies.id IN (?)", credited).Or(strings.Join(legacyConditions, " OR "), values...))
 	}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to get total movies: %w", err)
+	if filter.Expr != nil {
+		condition, args := movieQueryCondition(filter.Expr)
+		query = query.Where(condition, args...)
+	}
+
+	return query
+}
+
+// movieQueryCondition translates a filter query into a SQL condition. Genres
+// match exactly and the other text fields by substring, like the query
+// parameters of the same name.
+func movieQueryCondition(node search.Node) (string, []interface{}) {
+	switch n := node.(type) {
+	case search.And:
+		left, leftArgs := movieQueryCondition(n.Left)
+		right, rightArgs := movieQueryCondition(n.Right)
+		return "(" + left + " AND " + right + ")", append(leftArgs, rightArgs...)
+	case search.Or:
+		left, leftArgs := movieQueryCondition(n.Left)
+		right, rightArgs := movieQueryCondition(n.Right)
+		return "(" + left + " OR " + right + ")", append(leftArgs, rightArgs...)
+	case search.Not:
+		condition, args := movieQueryCondition(n.Node)
+		return "NOT (" + condition + ")", args
+	case search.Term:
+		return movieTermCondition(n)
+	default:
+		return "FALSE", nil
+	}
+}
+
+func movieTermCondition(term search.Term) (string, []interface{}) {
+	like := "%" + strings.ToLower(term.Value) + "%"
+
+	switch term.Field {
+	case "title":
+		return "LOWER(movies.title) LIKE ?", []interface{}{like}
+	case "description":
+		return "LOWER(movies.description) LIKE ?", []interface{}{like}
+	case "genre":
+		return "movies.id IN (SELECT movie_genres.movie_id FROM movie_genres JOIN genres ON genres.id = movie_genres.genre_id WHERE LOWER(genres.name) = ?)",
+			[]interface{}{strings.ToLower(term.Value)}
+	case "artist":
+		return "(movies.id IN (SELECT credits.movie_id FROM credits JOIN people ON people.id = credits.person_id WHERE LOWER(people.name) LIKE ?) OR LOWER(movies.artists) LIKE ?)",
+			[]interface{}{like, like}
+	case "duration":
+		op := term.Op
+		if op == "" {
+			op = "="
+		}
+		minutes, _ := strconv.Atoi(term.Value)
+		return "movies.duration " + op + " ?", []interface{}{minutes}
+	default:
+		return "(LOWER(movies.title) LIKE ? OR LOWER(movies.description) LIKE ?)", []interface{}{like, like}
+	}
+}
+
+// rankMovies runs a full-text query on databases without FULLTEXT indexes.
+// The movies matching the other filters are indexed in process and ranked
+// with BM25, which suits the small catalogues those databases hold.
+func rankMovies(query *gorm.DB, filter *entity.MovieFilter) ([]entity.Movie, int64, error) {
+	var candidates []entity.Movie
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get movies: %w", err)
+	}
+
+	byID := make(map[int]entity.Movie, len(candidates))
+	for _, movie := range candidates {
+		byID[movie.ID] = movie
+	}
+
+	hits := indexMovies(candidates).Search(filter.Query)
+	movies := make([]entity.Movie, len(hits))
+	for i, hit := range hits {
+		movies[i] = byID[hit.ID]
+		movies[i].Score = hit.Score
+	}
+	if len(filter.Sort) > 0 {
+		sortMovies(movies, filter.Sort)
 	}
 
-	page := filter.GetPage()
 	limit := filter.GetLimit()
-	offset := (page - 1) * limit
+	offset := min((filter.GetPage()-1)*limit, len(movies))
 
-	result := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&movies)
-	if result.Error != nil {
-		return nil, 0, fmt.Errorf("failed to get movies: %w", result.Error)
+	return movies[offset:min(offset+limit, len(movies))], int64(len(movies)), nil
+}
+
+// sortMovies orders movies in process like movieOrder. Titles compare
+// case-insensitively like the default MySQL collation.
+func sortMovies(movies []entity.Movie, sorts []entity.MovieSort) {
+	sort.SliceStable(movies, func(a, b int) bool {
+		for _, s := range sorts {
+			var c int
+			switch s.Key {
+			case entity.SortTitle:
+				c = strings.Compare(strings.ToLower(movies[a].Title), strings.ToLower(movies[b].Title))
+			case entity.SortDuration:
+				c = cmp.Compare(movies[a].Duration, movies[b].Duration)
+			case entity.SortCreatedAt:
+				c = movies[a].CreatedAt.Compare(movies[b].CreatedAt)
+			case entity.SortUpdatedAt:
+				c = movies[a].UpdatedAt.Compare(movies[b].UpdatedAt)
+			case entity.SortRelevance:
+				c = cmp.Compare(movies[a].Score, movies[b].Score)
+			}
+
+			if c != 0 {
+				return (c < 0) != s.Desc
+			}
+		}
+
+		return movies[a].ID < movies[b].ID
+	})
+}
+
+func indexMovies(movies []entity.Movie) *search.Index {
+	index := search.NewIndex()
+	for _, movie := range movies {
+		index.Add(movie.ID, movie.Title, movie.Description, string(movie.Artists), string(movie.Genres))
 	}
+	return index
+}
 
-	return movies, total, nil
+// maxFacetValues caps the genres and artists listed per facet, the most
+// frequent first.
+const maxFacetValues = 20
+
+// durationBuckets are the ranges of the duration facet, each holding the
+// durations below to minutes not held by the ranges before. The last range
+// is open.
+var durationBuckets = []struct {
+	label string
+	to    int
+}{
+	{label: "0-29", to: 30},
+	{label: "30-59", to: 60},
+	{label: "60-89", to: 90},
+	{label: "90-119", to: 120},
+	{label: "120+"},
+}
+
+// ListMovieFacets counts the facets of filter over all movies matching it.
+func (r *mySQLMovieRepository) ListMovieFacets(ctx context.Context, filter *entity.MovieFilter) (entity.MovieFacets, error) {
+	db := r.db.WithContext(ctx)
+
+	query := r.filterMovies(db.Model(&entity.Movie{}), filter)
+	if filter.Query != "" {
+		if r.db.Dialector.Name() != "mysql" {
+			ids, err := rankedMovieIDs(query, filter.Query)
+			if err != nil {
+				return nil, err
+			}
+			query = db.Model(&entity.Movie{}).Where("movies.id IN ?", ids)
+		} else {
+			query = query.Where(fullTextMatch, filter.Query)
+		}
+	}
+	movieIDs := query.Select("movies.id")
+
+	facets := entity.MovieFacets{}
+	for _, facet := range filter.Facets {
+		var counts []entity.FacetCount
+		var err error
+
+		switch facet {
+		case entity.FacetGenre:
+			err = db.Table("movie_genres").
+				Select("genres.name AS value, COUNT(*) AS count").
+				Joins("JOIN genres ON genres.id = movie_genres.genre_id").
+				Where("movie_genres.movie_id IN (?)", movieIDs).
+				Group("genres.name").
+				Order("count DESC, value ASC").
+				Limit(maxFacetValues).
+				Scan(&counts).Error
+		case entity.FacetArtist:
+			counts, err = r.countArtists(db, movieIDs)
+		case entity.FacetDuration:
+			counts, err = r.countDurations(db, movieIDs)
+		case entity.FacetYear:
+			err = db.Model(&entity.Movie{}).
+				Select("CAST(EXTRACT(YEAR FROM movies.created_at) AS CHAR) AS value, COUNT(*) AS count").
+				Where("movies.id IN (?)", movieIDs).
+				Group("value").
+				Order("value DESC").
+				Scan(&counts).Error
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to count %s facet: %w", facet, err)
+		}
+
+		if counts == nil {
+			counts = []entity.FacetCount{}
+		}
+		facets[facet] = counts
+	}
+
+	return facets, nil
+}
+
+// rankedMovieIDs returns the IDs of the movies of query matching a
+// full-text query, see rankMovies.
+func rankedMovieIDs(query *gorm.DB, fullText string) ([]int, error) {
+	var candidates []entity.Movie
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get movies: %w", err)
+	}
+
+	hits := indexMovies(candidates).Search(fullText)
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	return ids, nil
+}
+
+// countArtists counts credited people like the artist= filter matches them,
+// together with the free-text artists of movies without credits.
+func (r *mySQLMovieRepository) countArtists(db *gorm.DB, movieIDs *gorm.DB) ([]entity.FacetCount, error) {
+	var credited []entity.FacetCount
+	err := db.Table("credits").
+		Select("people.name AS value, COUNT(DISTINCT credits.movie_id) AS count").
+		Joins("JOIN people ON people.id = credits.person_id").
+		Where("credits.movie_id IN (?)", movieIDs).
+		Group("people.name").
+		Scan(&credited).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var artistLists []string
+	err = db.Model(&entity.Movie{}).
+		Where("movies.id IN (?)", movieIDs).
+		Where("movies.id NOT IN (?)", db.Table("credits").Select("credits.movie_id")).
+		Where("movies.artists <> ''").
+		Pluck("movies.artists", &artistLists).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []entity.FacetCount
+	index := map[string]int{}
+	add := func(name string, count int64) {
+		key := strings.ToLower(name)
+		if i, ok := index[key]; ok {
+			counts[i].Count += count
+			return
+		}
+		index[key] = len(counts)
+		counts = append(counts, entity.FacetCount{Value: name, Count: count})
+	}
+
+	for _, count := range credited {
+		add(count.Value, count.Count)
+	}
+	for _, artists := range artistLists {
+		for _, name := range strings.Split(artists, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				add(name, 1)
+			}
+		}
+	}
+
+	sort.SliceStable(counts, func(a, b int) bool {
+		if counts[a].Count != counts[b].Count {
+			return counts[a].Count > counts[b].Count
+		}
+		return counts[a].Value < counts[b].Value
+	})
+	if len(counts) > maxFacetValues {
+		counts = counts[:maxFacetValues]
+	}
+
+	return counts, nil
+}
+
+// countDurations counts movies per durationBuckets range, empty ranges
+// included.
+func (r *mySQLMovieRepository) countDurations(db *gorm.DB, movieIDs *gorm.DB) ([]entity.FacetCount, error) {
+	var cases []string
+	var args []interface{}
+	for _, bucket := range durationBuckets {
+		if bucket.to == 0 {
+			continue
+		}
+		cases = append(cases, "WHEN movies.duration < ? THEN ?")
+		args = append(args, bucket.to, bucket.label)
+	}
+	args = append(args, durationBuckets[len(durationBuckets)-1].label)
+	bucket := "CASE " + strings.Join(cases, " ") + " ELSE ? END"
+
+	var rows []entity.FacetCount
+	err := db.Model(&entity.Movie{}).
+		Select(bucket+" AS value, COUNT(*) AS count", args...).
+		Where("movies.id IN (?)", movieIDs).
+		Group("value").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]entity.FacetCount, len(durationBuckets))
+	for i, bucket := range durationBuckets {
+		counts[i].Value = bucket.label
+		for _, row := range rows {
+			if row.Value == bucket.label {
+				counts[i].Count = row.Count
+			}
+		}
+	}
+
+	return counts, nil
 }
 
+func (r *mySQLMovieRepository) GetMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	var movie entity.Movie
+	err := r.db.WithContext(ctx).First(&movie, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, newMovieNotFoundError(id)
+		}
+		return nil, fmt.Errorf("failed to get movie: %w", err)
+	}
+
+	return &movie, nil
+}
+
+func (r *mySQLMovieRepository) GetMovieCredits(ctx context.Context, id int) ([]entity.Credit, error) {
+	var credits []entity.Credit
+	err := r.db.WithContext(ctx).Preload("Person").Where("movie_id = ?", id).Order("role ASC, id ASC").Find(&credits).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie credits: %w", err)
+	}
+
+	return credits, nil
+}
+
+func (r *mySQLMovieRepository) GetMovieStats(ctx context.Context, id int) (*entity.MovieStats, error) {
+	var roles []struct {
+		Role  string
+		Count int64
+	}
+	err := r.db.WithContext(ctx).Model(&entity.Credit{}).
+		Select("role, COUNT(*) AS count").
+		Where("movie_id = ?", id).
+		Group("role").
+		Scan(&roles).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count movie credits: %w", err)
+	}
+
+	stats := &entity.MovieStats{CreditsByRole: make(map[string]int64, len(roles))}
+	for _, role := range roles {
+		stats.CreditsByRole[role.Role] = role.Count
+		stats.CreditCount += role.Count
+	}
+
+	err = r.db.WithContext(ctx).Table("movie_genres").Where("movie_id = ?", id).Count(&stats.GenreCount).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count movie genres: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ListSearchTerms returns the titles of movies, the genres and the artists,
+// credited people as well as the free-text artists of movies.
+func (r *mySQLMovieRepository) ListSearchTerms(ctx context.Context) ([]entity.Suggestion, error) {
+	db := r.db.WithContext(ctx)
+
+	var titles, genres, people, artistLists []string
+	if err := db.Model(&entity.Movie{}).Distinct().Pluck("title", &titles).Error; err != nil {
+		return nil, fmt.Errorf("failed to get movie titles: %w", err)
+	}
+	if err := db.Model(&entity.Genre{}).Pluck("name", &genres).Error; err != nil {
+		return nil, fmt.Errorf("failed to get genres: %w", err)
+	}
+	if err := db.Model(&entity.Person{}).Pluck("name", &people).Error; err != nil {
+		return nil, fmt.Errorf("failed to get people: %w", err)
+	}
+	if err := db.Model(&entity.Movie{}).Where("artists <> ''").Distinct().Pluck("artists", &artistLists).Error; err != nil {
+		return nil, fmt.Errorf("failed to get movie artists: %w", err)
+	}
+
+	terms := make([]entity.Suggestion, 0, len(titles)+len(genres)+len(people))
+	for _, title := range titles {
+		terms = append(terms, entity.Suggestion{Kind: entity.SuggestionTitle, Value: title})
+	}
+	for _, name := range genres {
+		terms = append(terms, entity.Suggestion{Kind: entity.SuggestionGenre, Value: name})
+	}
+
+	seen := map[string]bool{}
+	for _, name := range append(people, strings.Split(strings.Join(artistLists, ","), ",")...) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		terms = append(terms, entity.Suggestion{Kind: entity.SuggestionArtist, Value: name})
+	}
+
+	return terms, nil
+}
+
+// movieMetadataColumns are the columns ReplaceMovie writes, zero or not.
+var movieMetadataColumns = []string{"title", "description", "duration", "artists", "genres", "duration_mismatch", "updated_at"}
+
+// UpdateMovie writes the non-zero fields of movie.
 func (r *mySQLMovieRepository) UpdateMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	return r.saveMovie(ctx, movie, nil)
+}
+
+// ReplaceMovie writes all metadata of movie, so fields can be cleared.
+func (r *mySQLMovieRepository) ReplaceMovie(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	return r.saveMovie(ctx, movie, movieMetadataColumns)
+}
+
+func (r *mySQLMovieRepository) saveMovie(ctx context.Context, movie *entity.Movie, columns []string) (*entity.Movie, error) {
 	if movie.ID == 0 {
-		return nil, fmt.Errorf("movie ID is required")
+		return nil, apperror.Validation("movie ID is required")
 	}
 
-	result := r.db.WithContext(ctx).Model(&entity.Movie{}).Where("id = ?", movie.ID).Updates(movie)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to update movie: %w", result.Error)
-	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&entity.Movie{}).Omit(clause.Associations, "version").Where("id = ?", movie.ID)
+		if movie.Version > 0 {
+			query = query.Where("version = ?", movie.Version)
+		}
+		if columns != nil {
+			query = query.Select(columns)
+		}
 
-	if result.RowsAffected == 0 {
-		return nil, fmt.Errorf("movie with ID %d not found", movie.ID)
+		result := query.Updates(movie)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update movie: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return missingMovieError(tx, movie.ID)
+		}
+
+		err := tx.Model(&entity.Movie{}).Where("id = ?", movie.ID).UpdateColumn("version", gorm.Expr("version + 1")).Error
+		if err != nil {
+			return fmt.Errorf("failed to update movie version: %w", err)
+		}
+
+		if movie.GenreList != nil {
+			if err := tx.Model(movie).Association("GenreList").Replace(movie.GenreList); err != nil {
+				return fmt.Errorf("failed to update movie genres: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	var updatedMovie entity.Movie
@@ -104,15 +638,232 @@ func (r *mySQLMovieRepository) UpdateMovie(ctx context.Context, movie *entity.Mo
 	return &updatedMovie, nil
 }
 
-func (r *mySQLMovieRepository) DeleteMovie(ctx context.Context, id int) error {
-	result := r.db.WithContext(ctx).Delete(&entity.Movie{}, id)
+// movieFileColumns are the columns ReplaceMovieFile writes.
+var movieFileColumns = []string{
+	"file_path", "mime_type", "duration", "duration_mismatch", "version", "updated_at",
+	"media_container", "media_duration_seconds", "media_width", "media_height",
+	"media_video_codec", "media_audio_codec", "media_bitrate", "media_frame_rate",
+}
+
+// ReplaceMovieFile points movie at its new video and records the video it
+// used before as a MovieFileVersion, both or neither.
+func (r *mySQLMovieRepository) ReplaceMovieFile(ctx context.Context, movie *entity.Movie) (*entity.Movie, error) {
+	if movie.ID == 0 {
+		return nil, apperror.Validation("movie ID is required")
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current entity.Movie
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, movie.ID).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return newMovieNotFoundError(movie.ID)
+			}
+			return fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		if movie.Version > 0 && current.Version != movie.Version {
+			return ErrVersionMismatch
+		}
+
+		if current.FilePath != "" {
+			previous := entity.MovieFileVersion{
+				MovieID:    current.ID,
+				FilePath:   current.FilePath,
+				MimeType:   current.MimeType,
+				Media:      current.Media,
+				ReplacedAt: movie.UpdatedAt,
+			}
+			if err := tx.Create(&previous).Error; err != nil {
+				return fmt.Errorf("failed to keep previous movie file: %w", err)
+			}
+		}
+
+		movie.Version = current.Version + 1
+		err = tx.Model(&entity.Movie{}).Where("id = ?", movie.ID).Select(movieFileColumns).Updates(movie).Error
+		if err != nil {
+			return fmt.Errorf("failed to update movie file: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetMovie(ctx, movie.ID)
+}
+
+func (r *mySQLMovieRepository) GetMovieFileVersions(ctx context.Context, id int) ([]entity.MovieFileVersion, error) {
+	var versions []entity.MovieFileVersion
+	err := r.db.WithContext(ctx).Where("movie_id = ?", id).Order("replaced_at DESC, id DESC").Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie file versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// DeleteMovie soft deletes a movie and moves its version on. A version above
+// 0 must match the stored version, as for updates.
+func (r *mySQLMovieRepository) DeleteMovie(ctx context.Context, id int, version int) error {
+	query := r.db.WithContext(ctx).Model(&entity.Movie{}).Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.UpdateColumns(map[string]any{
+		"deleted_at": r.db.NowFunc(),
+		"version":    gorm.Expr("version + 1"),
+	})
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete movie: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("movie with ID %d not found", id)
+		return missingMovieError(r.db.WithContext(ctx), id)
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// RestoreMovie undoes the soft delete of a movie and moves its version on.
+func (r *mySQLMovieRepository) RestoreMovie(ctx context.Context, id int) (*entity.Movie, error) {
+	result := r.db.WithContext(ctx).Unscoped().Model(&entity.Movie{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to restore movie: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.WithContext(ctx).Model(&entity.Movie{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check movie: %w", err)
+		}
+		if count == 0 {
+			return nil, newMovieNotFoundError(id)
+		}
+		return nil, ErrMovieNotDeleted
+	}
+
+	return r.GetMovie(ctx, id)
+}
+
+// ListPurgeableMovies lists the movies soft deleted before deletedBefore.
+func (r *mySQLMovieRepository) ListPurgeableMovies(ctx context.Context, deletedBefore time.Time) ([]entity.Movie, error) {
+	var movies []entity.Movie
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+		Order("deleted_at ASC").
+		Find(&movies).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purgeable movies: %w", err)
+	}
+
+	return movies, nil
+}
+
+// PurgeMovie removes a movie, deleted or not, with its genre links, credits
+// and file versions. It returns the storage keys of the videos the movie
+// referenced, which the caller deletes once the rows are gone.
+func (r *mySQLMovieRepository) PurgeMovie(ctx context.Context, id int, version int) ([]string, error) {
+	return r.purgeMovie(ctx, id, func(tx *gorm.DB) (*entity.Movie, error) {
+		var movie entity.Movie
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&movie, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, newMovieNotFoundError(id)
+			}
+			return nil, fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		if version > 0 && movie.Version != version {
+			return nil, ErrVersionMismatch
+		}
+
+		return &movie, nil
+	})
+}
+
+// PurgeDeletedMovie purges a movie like PurgeMovie if it is still soft
+// deleted since before deletedBefore. The check holds the row lock, so a
+// movie restored after ListPurgeableMovies fails with ErrMovieNotPurgeable.
+func (r *mySQLMovieRepository) PurgeDeletedMovie(ctx context.Context, id int, deletedBefore time.Time) ([]string, error) {
+	return r.purgeMovie(ctx, id, func(tx *gorm.DB) (*entity.Movie, error) {
+		var movie entity.Movie
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			First(&movie, id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrMovieNotPurgeable
+			}
+			return nil, fmt.Errorf("failed to get movie: %w", err)
+		}
+
+		return &movie, nil
+	})
+}
+
+// purgeMovie removes the movie lock returns, which locks its row first.
+func (r *mySQLMovieRepository) purgeMovie(ctx context.Context, id int, lock func(tx *gorm.DB) (*entity.Movie, error)) ([]string, error) {
+	var paths []string
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		movie, err := lock(tx)
+		if err != nil {
+			return err
+		}
+
+		var versions []entity.MovieFileVersion
+		if err := tx.Where("movie_id = ?", id).Find(&versions).Error; err != nil {
+			return fmt.Errorf("failed to get movie file versions: %w", err)
+		}
+
+		if movie.FilePath != "" {
+			paths = append(paths, movie.FilePath)
+		}
+		for _, version := range versions {
+			paths = append(paths, version.FilePath)
+		}
+
+		if err := tx.Where("movie_id = ?", id).Delete(&entity.MovieFileVersion{}).Error; err != nil {
+			return fmt.Errorf("failed to delete movie file versions: %w", err)
+		}
+
+		if err := tx.Where("movie_id = ?", id).Delete(&entity.Credit{}).Error; err != nil {
+			return fmt.Errorf("failed to delete movie credits: %w", err)
+		}
+
+		if err := tx.Exec("DELETE FROM movie_genres WHERE movie_id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to delete movie genres: %w", err)
+		}
+
+		if err := tx.Unscoped().Delete(&entity.Movie{}, id).Error; err != nil {
+			return fmt.Errorf("failed to purge movie: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// missingMovieError tells apart a conditional write that matched no row
+// because the movie is gone from one whose version has moved on.
+func missingMovieError(db *gorm.DB, id int) error {
+	var count int64
+	if err := db.Model(&entity.Movie{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check movie: %w", err)
+	}
+
+	if count == 0 {
+		return newMovieNotFoundError(id)
+	}
+
+	return ErrVersionMismatch
+}
@@ -3,11 +3,14 @@ package movie
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"regexp"
 	"testing"
 	"time"
 
 	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/search"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"gorm.io/driver/mysql"
@@ -43,10 +46,11 @@ func TestCreateMovieRepository(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name    string
-		movie   *entity.Movie
-		mockSQL func()
-		wantErr bool
+		name         string
+		movie        *entity.Movie
+		beforeCommit func() error
+		mockSQL      func()
+		wantErr      bool
 	}{
 		{
 			name: "success create movie",
@@ -88,13 +92,25 @@ func TestCreateMovieRepository(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name:         "fail create movie - file promotion rolls back",
+			movie:        &entity.Movie{Title: "Test Movie", FilePath: "uploads/test.mp4"},
+			beforeCommit: func() error { return errors.New("failed to promote movie file") },
+			mockSQL: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movies`")).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.mockSQL()
 
-			movie, err := repo.CreateMovie(ctx, test.movie)
+			movie, err := repo.CreateMovie(ctx, test.movie, test.beforeCommit)
 
 			if (err != nil) != test.wantErr {
 				t.Errorf("CreateMovie() error = %v, wantErr %v", err, test.wantErr)
@@ -173,6 +189,99 @@ func TestListMoviesRepository(t *testing.T) {
 			wantTotal: 1,
 			wantErr:   false,
 		},
+		{
+			name: "success list movies with exact genre filter",
+			filter: &entity.MovieFilter{
+				Genres: []string{"Drama"},
+				Page:   1,
+				Limit:  10,
+			},
+			mockSQL: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE movies.id IN (SELECT movie_genres.movie_id FROM `movie_genres` JOIN genres ON genres.id = movie_genres.genre_id WHERE LOWER(genres.name) IN (?))")).
+					WithArgs("drama").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+				rows := sqlmock.NewRows([]string{"id", "title", "description", "duration", "artists", "genres", "file_path", "created_at", "updated_at"}).
+					AddRow(2, "Movie 2", "Desc 2", 130, "Artist 2", "Drama", "path2.mp4", time.Now(), time.Now())
+
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE movies.id IN (SELECT")).
+					WithArgs("drama", 10).
+					WillReturnRows(rows)
+			},
+			wantCount: 1,
+			wantTotal: 1,
+			wantErr:   false,
+		},
+		{
+			name: "success list movies with artist filter",
+			filter: &entity.MovieFilter{
+				Artists: []string{"Jane"},
+				Page:    1,
+				Limit:   10,
+			},
+			mockSQL: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE (movies.id IN (SELECT credits.movie_id FROM `credits` JOIN people ON people.id = credits.person_id WHERE LOWER(people.name) LIKE ?) OR LOWER(movies.artists) LIKE ?)")).
+					WithArgs("%jane%", "%jane%").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+				rows := sqlmock.NewRows([]string{"id", "title", "description", "duration", "artists", "genres", "file_path", "created_at", "updated_at"}).
+					AddRow(1, "Movie 1", "Desc 1", 120, "Jane Doe", "Action", "path1.mp4", time.Now(), time.Now())
+
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE (movies.id IN (SELECT")).
+					WithArgs("%jane%", "%jane%", 10).
+					WillReturnRows(rows)
+			},
+			wantCount: 1,
+			wantTotal: 1,
+			wantErr:   false,
+		},
+		{
+			name: "success list movies with filter query",
+			filter: &entity.MovieFilter{
+				Expr:  mustParseQuery(t, "genre:drama -duration:>=120 OR kim"),
+				Page:  1,
+				Limit: 10,
+			},
+			mockSQL: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE (((movies.id IN (SELECT movie_genres.movie_id FROM movie_genres JOIN genres ON genres.id = movie_genres.genre_id WHERE LOWER(genres.name) = ?) AND NOT (movies.duration >= ?)) OR (LOWER(movies.title) LIKE ? OR LOWER(movies.description) LIKE ?)))")).
+					WithArgs("drama", 120, "%kim%", "%kim%").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+				rows := sqlmock.NewRows([]string{"id", "title", "description", "duration", "artists", "genres", "file_path", "created_at", "updated_at"}).
+					AddRow(2, "Movie 2", "Desc 2", 95, "Artist 2", "Drama", "path2.mp4", time.Now(), time.Now())
+
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE (((movies.id IN (SELECT")).
+					WithArgs("drama", 120, "%kim%", "%kim%", 10).
+					WillReturnRows(rows)
+			},
+			wantCount: 1,
+			wantTotal: 1,
+			wantErr:   false,
+		},
+		{
+			name: "success full-text query orders by relevance",
+			filter: &entity.MovieFilter{
+				Query: "space drama",
+				Page:  1,
+				Limit: 10,
+			},
+			mockSQL: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE MATCH (movies.title, movies.description, movies.artists, movies.genres) AGAINST (? IN NATURAL LANGUAGE MODE)")).
+					WithArgs("space drama").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+				rows := sqlmock.NewRows([]string{"id", "title", "description", "duration", "artists", "genres", "file_path", "created_at", "updated_at", "score"}).
+					AddRow(2, "Space Drama", "Desc 2", 130, "Artist 2", "Drama", "path2.mp4", time.Now(), time.Now(), 1.8).
+					AddRow(1, "Space", "Desc 1", 120, "Artist 1", "Action", "path1.mp4", time.Now(), time.Now(), 0.6)
+
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT movies.*, MATCH (movies.title, movies.description, movies.artists, movies.genres) AGAINST (? IN NATURAL LANGUAGE MODE) AS score FROM `movies` WHERE MATCH")+".*"+regexp.QuoteMeta("ORDER BY score DESC, movies.id ASC")).
+					WithArgs("space drama", "space drama", 10).
+					WillReturnRows(rows)
+			},
+			wantCount: 2,
+			wantTotal: 2,
+			wantErr:   false,
+		},
 		{
 			name: "fail list movies - database error",
 			filter: &entity.MovieFilter{
@@ -213,3 +322,641 @@ func TestListMoviesRepository(t *testing.T) {
 		})
 	}
 }
+
+func mustParseQuery(t *testing.T, input string) search.Node {
+	t.Helper()
+
+	node, err := search.ParseQuery(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+// otherDialector stands in for a database without FULLTEXT indexes.
+type otherDialector struct {
+	gorm.Dialector
+}
+
+func (otherDialector) Name() string {
+	return "other"
+}
+
+func TestListMoviesRepositoryRanksInProcess(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database connection: %v", err)
+	}
+
+	db, err := gorm.Open(otherDialector{mysql.New(mysql.Config{Conn: mockDB, SkipInitializeWithVersion: true})}, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open GORM DB: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "description", "artists", "genres"}).
+		AddRow(1, "Garden Party", "A comedy", "Jane Doe", "Comedy").
+		AddRow(2, "Space Odyssey", "A voyage through space", "Kim", "Drama").
+		AddRow(3, "Space", "", "", "Action")
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`deleted_at` IS NULL")).
+		WillReturnRows(rows)
+
+	movies, total, err := repo.ListMovies(context.Background(), &entity.MovieFilter{Query: "space", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListMovies() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	if total != 2 {
+		t.Errorf("ListMovies() total = %v, want 2", total)
+	}
+	if len(movies) != 1 || movies[0].ID != 3 || movies[0].Score <= 0 {
+		t.Errorf("ListMovies() = %+v, want movie 3 with a score", movies)
+	}
+}
+
+func TestListMoviesRepositorySort(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	tests := []struct {
+		name      string
+		filter    *entity.MovieFilter
+		wantOrder string
+	}{
+		{
+			name:      "default",
+			filter:    &entity.MovieFilter{},
+			wantOrder: "ORDER BY created_at DESC, movies.id ASC",
+		},
+		{
+			name:      "several keys",
+			filter:    &entity.MovieFilter{Sort: []entity.MovieSort{{Key: entity.SortDuration, Desc: true}, {Key: entity.SortTitle}}},
+			wantOrder: "ORDER BY movies.duration DESC, movies.title ASC, movies.id ASC",
+		},
+		{
+			name:      "relevance after another key",
+			filter:    &entity.MovieFilter{Query: "space", Sort: []entity.MovieSort{{Key: entity.SortUpdatedAt, Desc: true}, {Key: entity.SortRelevance, Desc: true}}},
+			wantOrder: "ORDER BY movies.updated_at DESC, score DESC, movies.id ASC",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies`")).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+			mock.ExpectQuery(regexp.QuoteMeta(test.wantOrder + " LIMIT ?")).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+			if _, _, err := repo.ListMovies(context.Background(), test.filter); err != nil {
+				t.Fatalf("ListMovies() error = %v", err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestListMoviesRepositorySortsInProcess(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database connection: %v", err)
+	}
+
+	db, err := gorm.Open(otherDialector{mysql.New(mysql.Config{Conn: mockDB, SkipInitializeWithVersion: true})}, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open GORM DB: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "duration"}).
+		AddRow(1, "space", 90).
+		AddRow(2, "Space Odyssey", 140).
+		AddRow(3, "Space", 90).
+		AddRow(4, "Lost in Space", 140)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`deleted_at` IS NULL")).
+		WillReturnRows(rows)
+
+	filter := &entity.MovieFilter{
+		Query: "space",
+		Sort:  []entity.MovieSort{{Key: entity.SortDuration, Desc: true}, {Key: entity.SortTitle}},
+		Limit: 3,
+	}
+	movies, total, err := repo.ListMovies(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("ListMovies() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	var ids []int
+	for _, movie := range movies {
+		ids = append(ids, movie.ID)
+	}
+	if total != 4 || fmt.Sprint(ids) != "[4 2 1]" {
+		t.Errorf("ListMovies() = %v, %d, want [4 2 1], 4", ids, total)
+	}
+}
+
+func TestGetMovieRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		id           int
+		mockSQL      func()
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{
+			name: "success get movie",
+			id:   1,
+			mockSQL: func() {
+				rows := sqlmock.NewRows([]string{"id", "title", "description", "duration", "artists", "genres", "file_path", "created_at", "updated_at"}).
+					AddRow(1, "Movie 1", "Desc 1", 120, "Artist 1", "Action", "path1.mp4", time.Now(), time.Now())
+
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ?")).
+					WithArgs(1, 1).
+					WillReturnRows(rows)
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail get movie - not found",
+			id:   999,
+			mockSQL: func() {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ?")).
+					WithArgs(999, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			wantErr:      true,
+			wantNotFound: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.mockSQL()
+
+			movie, err := repo.GetMovie(ctx, test.id)
+
+			if (err != nil) != test.wantErr {
+				t.Errorf("GetMovie() error = %v, wantErr %v", err, test.wantErr)
+				return
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+
+			if test.wantNotFound && !errors.Is(err, ErrMovieNotFound) {
+				t.Errorf("GetMovie() error = %v, want ErrMovieNotFound", err)
+			}
+
+			if !test.wantErr && movie.ID != test.id {
+				t.Errorf("GetMovie() got ID %v, want %v", movie.ID, test.id)
+			}
+		})
+	}
+}
+
+func TestGetMovieStatsRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT role, COUNT(*) AS count FROM `credits` WHERE movie_id = ? GROUP BY `role`")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "count"}).AddRow("actor", 2).AddRow("director", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movie_genres` WHERE movie_id = ?")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	stats, err := repo.GetMovieStats(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetMovieStats() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	if stats.CreditCount != 3 || stats.CreditsByRole["actor"] != 2 || stats.GenreCount != 2 {
+		t.Errorf("GetMovieStats() = %+v", stats)
+	}
+}
+
+func TestListSearchTermsRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT `title` FROM `movies` WHERE `movies`.`deleted_at` IS NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Amélie"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `name` FROM `genres`")).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Comedy"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `name` FROM `people`")).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Audrey Tautou"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT DISTINCT `artists` FROM `movies` WHERE artists <> '' AND `movies`.`deleted_at` IS NULL")).
+		WillReturnRows(sqlmock.NewRows([]string{"artists"}).AddRow("audrey tautou, Mathieu Kassovitz"))
+
+	terms, err := repo.ListSearchTerms(context.Background())
+	if err != nil {
+		t.Fatalf("ListSearchTerms() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	want := []entity.Suggestion{
+		{Kind: entity.SuggestionTitle, Value: "Amélie"},
+		{Kind: entity.SuggestionGenre, Value: "Comedy"},
+		{Kind: entity.SuggestionArtist, Value: "Audrey Tautou"},
+		{Kind: entity.SuggestionArtist, Value: "Mathieu Kassovitz"},
+	}
+	if fmt.Sprint(terms) != fmt.Sprint(want) {
+		t.Errorf("ListSearchTerms() = %v, want %v", terms, want)
+	}
+}
+
+func TestListMovieFacetsRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	movieIDs := "(SELECT movies.id FROM `movies` WHERE LOWER(title) LIKE ? AND `movies`.`deleted_at` IS NULL)"
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT genres.name AS value, COUNT(*) AS count FROM `movie_genres` JOIN genres ON genres.id = movie_genres.genre_id WHERE movie_genres.movie_id IN "+movieIDs+" GROUP BY `genres`.`name` ORDER BY count DESC, value ASC LIMIT ?")).
+		WithArgs("%space%", maxFacetValues).
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("Sci-Fi", 2).AddRow("Drama", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT people.name AS value, COUNT(DISTINCT credits.movie_id) AS count FROM `credits` JOIN people ON people.id = credits.person_id WHERE credits.movie_id IN " + movieIDs + " GROUP BY `people`.`name`")).
+		WithArgs("%space%").
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("Keir Dullea", 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `movies`.`artists` FROM `movies` WHERE movies.id IN " + movieIDs + " AND movies.id NOT IN (SELECT credits.movie_id FROM `credits`) AND movies.artists <> '' AND `movies`.`deleted_at` IS NULL")).
+		WithArgs("%space%").
+		WillReturnRows(sqlmock.NewRows([]string{"artists"}).AddRow("keir dullea, Gary Lockwood"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT CASE WHEN movies.duration < ? THEN ? WHEN movies.duration < ? THEN ? WHEN movies.duration < ? THEN ? WHEN movies.duration < ? THEN ? ELSE ? END AS value, COUNT(*) AS count FROM `movies` WHERE movies.id IN "+movieIDs+" AND `movies`.`deleted_at` IS NULL GROUP BY `value`")).
+		WithArgs(30, "0-29", 60, "30-59", 90, "60-89", 120, "90-119", "120+", "%space%").
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("90-119", 1).AddRow("120+", 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT CAST(EXTRACT(YEAR FROM movies.created_at) AS CHAR) AS value, COUNT(*) AS count FROM `movies` WHERE movies.id IN " + movieIDs + " AND `movies`.`deleted_at` IS NULL GROUP BY `value` ORDER BY value DESC")).
+		WithArgs("%space%").
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("2024", 3))
+
+	filter := &entity.MovieFilter{
+		Title:  "Space",
+		Facets: []string{entity.FacetGenre, entity.FacetArtist, entity.FacetDuration, entity.FacetYear},
+	}
+	facets, err := repo.ListMovieFacets(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("ListMovieFacets() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	want := entity.MovieFacets{
+		entity.FacetGenre:    {{Value: "Sci-Fi", Count: 2}, {Value: "Drama", Count: 1}},
+		entity.FacetArtist:   {{Value: "Keir Dullea", Count: 2}, {Value: "Gary Lockwood", Count: 1}},
+		entity.FacetDuration: {{Value: "0-29"}, {Value: "30-59"}, {Value: "60-89"}, {Value: "90-119", Count: 1}, {Value: "120+", Count: 2}},
+		entity.FacetYear:     {{Value: "2024", Count: 3}},
+	}
+	if fmt.Sprint(facets) != fmt.Sprint(want) {
+		t.Errorf("ListMovieFacets() = %v, want %v", facets, want)
+	}
+}
+
+func TestReplaceMovieRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `movies` SET `title`=?,`description`=?,`duration`=?,`artists`=?,`genres`=?,`duration_mismatch`=?,`updated_at`=? WHERE id = ? AND version = ? AND `movies`.`deleted_at` IS NULL")).
+		WithArgs("Movie", "", 0, "", "", false, sqlmock.AnyArg(), 1, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `movies` SET `version`=version + 1 WHERE id = ? AND `movies`.`deleted_at` IS NULL")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `movies` SET `updated_at`=? WHERE `movies`.`deleted_at` IS NULL AND `id` = ?")).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `movie_genres` WHERE `movie_genres`.`movie_id` = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ?")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "duration", "version"}).AddRow(1, "Movie", "", 0, 4))
+
+	movie, err := repo.ReplaceMovie(context.Background(), &entity.Movie{ID: 1, Title: "Movie", GenreList: []entity.Genre{}, Version: 3, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("ReplaceMovie() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	if movie.Description != "" || movie.Duration != 0 || movie.Version != 4 {
+		t.Errorf("ReplaceMovie() = %+v, want cleared description and duration at version 4", movie)
+	}
+}
+
+func TestReplaceMovieFileRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ? AND `movies`.`deleted_at` IS NULL ORDER BY `movies`.`id` LIMIT ? FOR UPDATE")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_path", "mime_type", "media_container", "version"}).AddRow(1, "uploads/old.mp4", "video/mp4", "mp4", 2))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `movie_file_versions`")).
+		WithArgs(1, "uploads/old.mp4", "video/mp4", "mp4", 0.0, 0, 0, "", "", 0, 0.0, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE `movies` SET `duration`=?,`file_path`=?,`mime_type`=?,`media_container`=?,`media_duration_seconds`=?,`media_width`=?,`media_height`=?,`media_video_codec`=?,`media_audio_codec`=?,`media_bitrate`=?,`media_frame_rate`=?,`duration_mismatch`=?,`version`=?,`updated_at`=? WHERE id = ?")).
+		WithArgs(0, "uploads/new.mkv", "video/x-matroska", "matroska", 0.0, 0, 0, "", "", 0, 0.0, false, 3, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ?")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_path", "version"}).AddRow(1, "uploads/new.mkv", 3))
+
+	movie, err := repo.ReplaceMovieFile(context.Background(), &entity.Movie{
+		ID:        1,
+		FilePath:  "uploads/new.mkv",
+		MimeType:  "video/x-matroska",
+		Media:     entity.MediaInfo{Container: "matroska"},
+		Version:   2,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("ReplaceMovieFile() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	if movie.FilePath != "uploads/new.mkv" || movie.Version != 3 {
+		t.Errorf("ReplaceMovieFile() = %+v", movie)
+	}
+}
+
+func TestReplaceMovieFileRepositoryVersion(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ?")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_path", "version"}).AddRow(1, "uploads/old.mp4", 3))
+	mock.ExpectRollback()
+
+	_, err = repo.ReplaceMovieFile(context.Background(), &entity.Movie{ID: 1, FilePath: "uploads/new.mkv", Version: 2})
+	if !errors.Is(err, ErrVersionMismatch) {
+		t.Errorf("ReplaceMovieFile() error = %v, want ErrVersionMismatch", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestListDeletedMoviesRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE movies.deleted_at IS NOT NULL AND LOWER(title) LIKE ?")).
+		WithArgs("%movie%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE movies.deleted_at IS NOT NULL AND LOWER(title) LIKE ? ORDER BY deleted_at DESC, movies.id ASC LIMIT ?")).
+		WithArgs("%movie%", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "deleted_at"}).AddRow(1, "Movie", time.Now()))
+
+	movies, total, err := repo.ListDeletedMovies(context.Background(), &entity.MovieFilter{Title: "Movie"})
+	if err != nil {
+		t.Fatalf("ListDeletedMovies() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	if total != 1 || len(movies) != 1 || movies[0].DeletedAt == nil {
+		t.Errorf("ListDeletedMovies() = %+v, %d", movies, total)
+	}
+}
+
+func TestRestoreMovieRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		restored int64
+		exists   int
+		wantErr  error
+	}{
+		{name: "success restore movie", restored: 1},
+		{name: "fail - movie not deleted", exists: 1, wantErr: ErrMovieNotDeleted},
+		{name: "fail - movie not found", exists: 0, wantErr: ErrMovieNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock, err := setupTestDB(t)
+			if err != nil {
+				t.Fatalf("Failed to setup test database: %v", err)
+			}
+
+			repo := NewMySQLMovieRepository(db)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `movies` SET `deleted_at`=?,`version`=version + 1,`updated_at`=? WHERE id = ? AND deleted_at IS NOT NULL")).
+				WithArgs(nil, sqlmock.AnyArg(), 1).
+				WillReturnResult(sqlmock.NewResult(0, test.restored))
+			mock.ExpectCommit()
+			if test.restored == 0 {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE id = ? AND `movies`.`deleted_at` IS NULL")).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(test.exists))
+			} else {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ? AND `movies`.`deleted_at` IS NULL")).
+					WithArgs(1, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Movie"))
+			}
+
+			movie, err := repo.RestoreMovie(context.Background(), 1)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("RestoreMovie() error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr == nil && (movie == nil || movie.Title != "Movie") {
+				t.Errorf("RestoreMovie() = %+v", movie)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestPurgeMovieRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE `movies`.`id` = ? ORDER BY `movies`.`id` LIMIT ? FOR UPDATE")).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_path", "version", "deleted_at"}).AddRow(1, "uploads/current.mp4", 2, time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movie_file_versions` WHERE movie_id = ?")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "movie_id", "file_path"}).AddRow(1, 1, "uploads/previous.mp4"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `movie_file_versions` WHERE movie_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `credits` WHERE movie_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM movie_genres WHERE movie_id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `movies` WHERE `movies`.`id` = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	paths, err := repo.PurgeMovie(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("PurgeMovie() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	if len(paths) != 2 || paths[0] != "uploads/current.mp4" || paths[1] != "uploads/previous.mp4" {
+		t.Errorf("PurgeMovie() paths = %v", paths)
+	}
+}
+
+func TestPurgeDeletedMovieRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLMovieRepository(db)
+	cutoff := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// The movie was restored since it was listed, so nothing is deleted.
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `movies` WHERE (deleted_at IS NOT NULL AND deleted_at < ?) AND `movies`.`id` = ? ORDER BY `movies`.`id` LIMIT ? FOR UPDATE")).
+		WithArgs(cutoff, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	paths, err := repo.PurgeDeletedMovie(context.Background(), 1, cutoff)
+	if !errors.Is(err, ErrMovieNotPurgeable) {
+		t.Errorf("PurgeDeletedMovie() error = %v, want ErrMovieNotPurgeable", err)
+	}
+	if paths != nil {
+		t.Errorf("PurgeDeletedMovie() paths = %v, want none", paths)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteMovieRepositoryVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted int64
+		exists  int
+		wantErr error
+	}{
+		{name: "success delete movie", deleted: 1},
+		{name: "fail - version moved on", exists: 1, wantErr: ErrVersionMismatch},
+		{name: "fail - movie not found", exists: 0, wantErr: ErrMovieNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock, err := setupTestDB(t)
+			if err != nil {
+				t.Fatalf("Failed to setup test database: %v", err)
+			}
+
+			repo := NewMySQLMovieRepository(db)
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE `movies` SET `deleted_at`=?,`version`=version + 1 WHERE id = ? AND version = ? AND `movies`.`deleted_at` IS NULL")).
+				WithArgs(sqlmock.AnyArg(), 1, 2).
+				WillReturnResult(sqlmock.NewResult(0, test.deleted))
+			mock.ExpectCommit()
+			if test.deleted == 0 {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `movies` WHERE id = ? AND `movies`.`deleted_at` IS NULL")).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(test.exists))
+			}
+
+			err = repo.DeleteMovie(context.Background(), 1, 2)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("DeleteMovie() error = %v, want %v", err, test.wantErr)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("There were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package movie
+
+import (
+	"context"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/search"
+	"time"
+)
+
+// searchTermsMaxAge bounds how long the search terms are cached. Movie writes
+// drop them at once, but genres and people also change outside this flow.
+const searchTermsMaxAge = 5 * time.Minute
+
+// searchTerms are the known titles, artists and genres indexed for fuzzy
+// matching. They are built once and shared until a movie is written.
+type searchTerms struct {
+	terms   []entity.Suggestion
+	all     *search.FuzzyIndex
+	byKind  map[string]*search.FuzzyIndex
+	builtAt time.Time
+}
+
+// searchTerms returns the cached search terms, loading them if a movie was
+// written since or they are older than searchTermsMaxAge.
+func (f *movieFlow) searchTerms(ctx context.Context) (*searchTerms, error) {
+	f.termsMu.Lock()
+	defer f.termsMu.Unlock()
+
+	if f.terms != nil && f.now().Sub(f.terms.builtAt) < searchTermsMaxAge {
+		return f.terms, nil
+	}
+
+	terms, err := f.movieRepo.ListSearchTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	built := &searchTerms{
+		terms: terms,
+		all:   search.NewFuzzyIndex(),
+		byKind: map[string]*search.FuzzyIndex{
+			entity.SuggestionTitle:  search.NewFuzzyIndex(),
+			entity.SuggestionArtist: search.NewFuzzyIndex(),
+			entity.SuggestionGenre:  search.NewFuzzyIndex(),
+		},
+		builtAt: f.now(),
+	}
+	for i, term := range terms {
+		built.all.Add(i, term.Value)
+		if index, ok := built.byKind[term.Kind]; ok {
+			index.Add(i, term.Value)
+		}
+	}
+
+	f.terms = built
+	return built, nil
+}
+
+// invalidateSearchTerms drops the cached search terms after a movie write.
+func (f *movieFlow) invalidateSearchTerms() {
+	f.termsMu.Lock()
+	defer f.termsMu.Unlock()
+
+	f.terms = nil
+}
+
+// SuggestMovies completes prefix to known titles, artists and genres. The
+// prefix may lack accents and contain typos, see search.FuzzyIndex.
+func (f *movieFlow) SuggestMovies(ctx context.Context, prefix string, limit int) ([]entity.Suggestion, error) {
+	if err := auth.Authorize(ctx, auth.ActionReadMovie); err != nil {
+		return nil, err
+	}
+
+	terms, err := f.searchTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []entity.Suggestion{}
+	seen := map[entity.Suggestion]bool{}
+	for _, match := range terms.all.Suggest(prefix, 0) {
+		term := terms.terms[match.ID]
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		suggestions = append(suggestions, term)
+		if len(suggestions) == limit {
+			break
+		}
+	}
+
+	return suggestions, nil
+}
+
+// correctFilter replaces the title, artists and genres of a filter with the
+// closest known values, so that misspelled or unaccented names still find
+// their movies. It reports whether anything was replaced.
+func (f *movieFlow) correctFilter(ctx context.Context, filter *entity.MovieFilter) (*entity.MovieFilter, bool, error) {
+	if filter.Title == "" && len(filter.Artists) == 0 && len(filter.Genres) == 0 {
+		return filter, false, nil
+	}
+
+	terms, err := f.searchTerms(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	indexes := terms.byKind
+
+	changed := false
+	correct := func(kind, value string) string {
+		match, ok := indexes[kind].Closest(value)
+		if !ok {
+			return value
+		}
+
+		// Titles and artists match by substring, genres by name.
+		replacement := match.Matched
+		if kind == entity.SuggestionGenre {
+			replacement = match.Value
+		}
+		if replacement != value {
+			changed = true
+		}
+		return replacement
+	}
+
+	corrected := *filter
+	if filter.Title != "" {
+		corrected.Title = correct(entity.SuggestionTitle, filter.Title)
+	}
+
+	corrected.Artists = make([]string, len(filter.Artists))
+	for i, artist := range filter.Artists {
+		corrected.Artists[i] = correct(entity.SuggestionArtist, artist)
+	}
+
+	corrected.Genres = make([]string, len(filter.Genres))
+	for i, name := range filter.Genres {
+		corrected.Genres[i] = correct(entity.SuggestionGenre, name)
+	}
+
+	return &corrected, changed, nil
+}
@@ -0,0 +1,149 @@
+package person
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrInvalidPerson = apperror.New(apperror.ErrValidation, "invalid person")
+
+const (
+	maxNameLength    = 255
+	maxCountryLength = 64
+	maxPhotoURLength = 255
+)
+
+type PersonFlowInterface interface {
+	ListPeople(ctx context.Context, filter *entity.PersonFilter) ([]entity.Person, int64, error)
+	GetPerson(ctx context.Context, id int) (*entity.Person, error)
+	CreatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error)
+	UpdatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error)
+	DeletePerson(ctx context.Context, id int) error
+	GetFilmography(ctx context.Context, personID int) ([]entity.Credit, error)
+	CreateCredit(ctx context.Context, credit *entity.Credit) (*entity.Credit, error)
+	DeleteCredit(ctx context.Context, personID, creditID int) error
+}
+
+type personFlow struct {
+	personRepo PersonRepository
+}
+
+func NewPersonFlow(personRepo PersonRepository) PersonFlowInterface {
+	return &personFlow{
+		personRepo: personRepo,
+	}
+}
+
+func (f *personFlow) ListPeople(ctx context.Context, filter *entity.PersonFilter) ([]entity.Person, int64, error) {
+	return f.personRepo.ListPeople(ctx, filter)
+}
+
+func (f *personFlow) GetPerson(ctx context.Context, id int) (*entity.Person, error) {
+	return f.personRepo.GetPerson(ctx, id)
+}
+
+func (f *personFlow) CreatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error) {
+	if err := auth.Authorize(ctx, auth.ActionManagePeople); err != nil {
+		return nil, err
+	}
+
+	if err := validatePerson(person); err != nil {
+		return nil, err
+	}
+
+	currentTime := time.Now()
+	person.CreatedAt = currentTime
+	person.UpdatedAt = currentTime
+
+	return f.personRepo.CreatePerson(ctx, person)
+}
+
+func (f *personFlow) UpdatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error) {
+	if err := auth.Authorize(ctx, auth.ActionManagePeople); err != nil {
+		return nil, err
+	}
+
+	if person.ID == 0 {
+		return nil, fmt.Errorf("%w: person ID is required", ErrInvalidPerson)
+	}
+
+	if err := validatePerson(person); err != nil {
+		return nil, err
+	}
+
+	person.UpdatedAt = time.Now()
+
+	return f.personRepo.UpdatePerson(ctx, person)
+}
+
+func (f *personFlow) DeletePerson(ctx context.Context, id int) error {
+	if err := auth.Authorize(ctx, auth.ActionManagePeople); err != nil {
+		return err
+	}
+
+	return f.personRepo.DeletePerson(ctx, id)
+}
+
+func (f *personFlow) GetFilmography(ctx context.Context, personID int) ([]entity.Credit, error) {
+	return f.personRepo.GetFilmography(ctx, personID)
+}
+
+func (f *personFlow) CreateCredit(ctx context.Context, credit *entity.Credit) (*entity.Credit, error) {
+	if err := auth.Authorize(ctx, auth.ActionManagePeople); err != nil {
+		return nil, err
+	}
+
+	credit.Role = strings.ToLower(strings.TrimSpace(credit.Role))
+	if !entity.IsCreditRole(credit.Role) {
+		return nil, fmt.Errorf("%w: role must be one of %s", ErrInvalidPerson, strings.Join(entity.CreditRoles, ", "))
+	}
+
+	if credit.MovieID <= 0 {
+		return nil, fmt.Errorf("%w: movie_id is required", ErrInvalidPerson)
+	}
+
+	credit.CreatedAt = time.Now()
+
+	return f.personRepo.CreateCredit(ctx, credit)
+}
+
+func (f *personFlow) DeleteCredit(ctx context.Context, personID, creditID int) error {
+	if err := auth.Authorize(ctx, auth.ActionManagePeople); err != nil {
+		return err
+	}
+
+	return f.personRepo.DeleteCredit(ctx, personID, creditID)
+}
+
+func validatePerson(person *entity.Person) error {
+	person.Name = strings.TrimSpace(person.Name)
+	person.Country = strings.TrimSpace(person.Country)
+	person.PhotoURL = strings.TrimSpace(person.PhotoURL)
+
+	switch {
+	case person.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidPerson)
+	case utf8.RuneCountInString(person.Name) > maxNameLength:
+		return fmt.Errorf("%w: name must be at most %d characters", ErrInvalidPerson, maxNameLength)
+	case utf8.RuneCountInString(person.Country) > maxCountryLength:
+		return fmt.Errorf("%w: country must be at most %d characters", ErrInvalidPerson, maxCountryLength)
+	case len(person.PhotoURL) > maxPhotoURLength:
+		return fmt.Errorf("%w: photo_url must be at most %d characters", ErrInvalidPerson, maxPhotoURLength)
+	}
+
+	if person.PhotoURL != "" {
+		u, err := url.Parse(person.PhotoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: photo_url must be an absolute http(s) URL", ErrInvalidPerson)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package person
+
+import (
+	"context"
+	"errors"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+	"testing"
+)
+
+type MockPersonRepository struct {
+	people  []entity.Person
+	credits []entity.Credit
+	movies  map[int]entity.Movie
+	err     error
+}
+
+func (m *MockPersonRepository) ListPeople(ctx context.Context, filter *entity.PersonFilter) ([]entity.Person, int64, error) {
+	if m.err != nil {
+		return nil, 0, m.err
+	}
+
+	var people []entity.Person
+	for _, p := range m.people {
+		if strings.Contains(strings.ToLower(p.Name), strings.ToLower(filter.Name)) {
+			people = append(people, p)
+		}
+	}
+
+	return people, int64(len(people)), nil
+}
+
+func (m *MockPersonRepository) GetPerson(ctx context.Context, id int) (*entity.Person, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for _, p := range m.people {
+		if p.ID == id {
+			person := p
+			return &person, nil
+		}
+	}
+
+	return nil, ErrPersonNotFound
+}
+
+func (m *MockPersonRepository) CreatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	person.ID = len(m.people) + 1
+	m.people = append(m.people, *person)
+
+	return person, nil
+}
+
+func (m *MockPersonRepository) UpdatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	for i, p := range m.people {
+		if p.ID == person.ID {
+			m.people[i] = *person
+			return person, nil
+		}
+	}
+
+	return nil, ErrPersonNotFound
+}
+
+func (m *MockPersonRepository) DeletePerson(ctx context.Context, id int) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	for i, p := range m.people {
+		if p.ID == id {
+			m.people = append(m.people[:i], m.people[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrPersonNotFound
+}
+
+func (m *MockPersonRepository) GetFilmography(ctx context.Context, personID int) ([]entity.Credit, error) {
+	if _, err := m.GetPerson(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	var credits []entity.Credit
+	for _, c := range m.credits {
+		if c.PersonID == personID {
+			movie := m.movies[c.MovieID]
+			c.Movie = &movie
+			credits = append(credits, c)
+		}
+	}
+
+	return credits, nil
+}
+
+func (m *MockPersonRepository) CreateCredit(ctx context.Context, credit *entity.Credit) (*entity.Credit, error) {
+	if _, err := m.GetPerson(ctx, credit.PersonID); err != nil {
+		return nil, err
+	}
+
+	if _, ok := m.movies[credit.MovieID]; !ok {
+		return nil, ErrMovieNotFound
+	}
+
+	for _, c := range m.credits {
+		if c.MovieID == credit.MovieID && c.PersonID == credit.PersonID && c.Role == credit.Role {
+			return nil, ErrCreditExists
+		}
+	}
+
+	credit.ID = len(m.credits) + 1
+	m.credits = append(m.credits, *credit)
+
+	return credit, nil
+}
+
+func (m *MockPersonRepository) DeleteCredit(ctx context.Context, personID, creditID int) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	for i, c := range m.credits {
+		if c.ID == creditID && c.PersonID == personID {
+			m.credits = append(m.credits[:i], m.credits[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrCreditNotFound
+}
+
+func newMockPersonRepository() *MockPersonRepository {
+	return &MockPersonRepository{
+		people: []entity.Person{{ID: 1, Name: "Jane Doe", Country: "ID"}},
+		movies: map[int]entity.Movie{1: {ID: 1, Title: "Test Movie"}},
+	}
+}
+
+func newCredit(id, personID, movieID int, role string) entity.Credit {
+	return entity.Credit{ID: id, PersonID: personID, MovieID: movieID, Role: role}
+}
+
+func contextWithRole(role string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{UserID: 1, Role: role})
+}
+
+func TestCreatePerson(t *testing.T) {
+	tests := []struct {
+		name    string
+		person  entity.Person
+		wantErr error
+	}{
+		{
+			name:   "success create person",
+			person: entity.Person{Name: " Kim Ji-woon ", Country: "KR", PhotoURL: "https://example.com/kim.jpg"},
+		},
+		{
+			name:    "fail - empty name",
+			person:  entity.Person{Name: "  "},
+			wantErr: ErrInvalidPerson,
+		},
+		{
+			name:    "fail - name too long",
+			person:  entity.Person{Name: strings.Repeat("a", maxNameLength+1)},
+			wantErr: ErrInvalidPerson,
+		},
+		{
+			name:    "fail - relative photo URL",
+			person:  entity.Person{Name: "Kim Ji-woon", PhotoURL: "/kim.jpg"},
+			wantErr: ErrInvalidPerson,
+		},
+		{
+			name:    "fail - non http photo URL",
+			person:  entity.Person{Name: "Kim Ji-woon", PhotoURL: "ftp://example.com/kim.jpg"},
+			wantErr: ErrInvalidPerson,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flow := NewPersonFlow(newMockPersonRepository())
+
+			person, err := flow.CreatePerson(contextWithRole(entity.UserRoleAdmin), &test.person)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("CreatePerson() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreatePerson() error = %v", err)
+			}
+
+			if person.Name != "Kim Ji-woon" {
+				t.Errorf("CreatePerson() name = %q, want trimmed name", person.Name)
+			}
+		})
+	}
+}
+
+func TestCreateCredit(t *testing.T) {
+	tests := []struct {
+		name     string
+		credit   entity.Credit
+		wantErr  error
+		wantRole string
+	}{
+		{
+			name:     "success create credit",
+			credit:   entity.Credit{PersonID: 1, MovieID: 1, Role: " Director "},
+			wantRole: entity.RoleDirector,
+		},
+		{
+			name:    "fail - unknown role",
+			credit:  entity.Credit{PersonID: 1, MovieID: 1, Role: "producer"},
+			wantErr: ErrInvalidPerson,
+		},
+		{
+			name:    "fail - missing movie",
+			credit:  entity.Credit{PersonID: 1, Role: entity.RoleActor},
+			wantErr: ErrInvalidPerson,
+		},
+		{
+			name:    "fail - unknown movie",
+			credit:  entity.Credit{PersonID: 1, MovieID: 99, Role: entity.RoleActor},
+			wantErr: ErrMovieNotFound,
+		},
+		{
+			name:    "fail - unknown person",
+			credit:  entity.Credit{PersonID: 99, MovieID: 1, Role: entity.RoleActor},
+			wantErr: ErrPersonNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flow := NewPersonFlow(newMockPersonRepository())
+
+			credit, err := flow.CreateCredit(contextWithRole(entity.UserRoleAdmin), &test.credit)
+
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Errorf("CreateCredit() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateCredit() error = %v", err)
+			}
+
+			if credit.Role != test.wantRole {
+				t.Errorf("CreateCredit() role = %q, want %q", credit.Role, test.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package person
+
+import (
+	"net/http"
+	"roketin-case-study-challenge2/internal/constant"
+	"roketin-case-study-challenge2/internal/response"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type PersonHandler struct {
+	personParser PersonParserInterface
+	personFlow   PersonFlowInterface
+}
+
+func NewPersonHandler(personParser PersonParserInterface, personFlow PersonFlowInterface) *PersonHandler {
+	return &PersonHandler{
+		personParser: personParser,
+		personFlow:   personFlow,
+	}
+}
+
+func (h *PersonHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", h.ListPeople)
+	r.Post("/", h.CreatePerson)
+	r.Get("/{id}", h.GetPerson)
+	r.Put("/{id}", h.UpdatePerson)
+	r.Delete("/{id}", h.DeletePerson)
+	r.Get("/{id}/filmography", h.GetFilmography)
+	r.Post("/{id}/credits", h.CreateCredit)
+	r.Delete("/{id}/credits/{creditId}", h.DeleteCredit)
+
+	return r
+}
+
+func (h *PersonHandler) ListPeople(w http.ResponseWriter, r *http.Request) {
+	filter, err := h.personParser.ParsePersonFilter(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	people, total, err := h.personFlow.ListPeople(r.Context(), filter)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	pagination := response.Pagination{
+		CurrentPage: filter.GetPage(),
+		PerPage:     filter.GetLimit(),
+		TotalItems:  total,
+		TotalPages:  int((total + int64(filter.GetLimit()) - 1) / int64(filter.GetLimit())),
+	}
+
+	response.SuccessWithPagination(w, people, pagination)
+}
+
+func (h *PersonHandler) GetPerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_PERSON_ID)
+		return
+	}
+
+	person, err := h.personFlow.GetPerson(r.Context(), id)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, person)
+}
+
+func (h *PersonHandler) CreatePerson(w http.ResponseWriter, r *http.Request) {
+	request, err := h.personParser.ParsePerson(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	person, err := h.personFlow.CreatePerson(r.Context(), request)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, person)
+}
+
+func (h *PersonHandler) UpdatePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_PERSON_ID)
+		return
+	}
+
+	request, err := h.personParser.ParsePerson(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	request.ID = id
+
+	person, err := h.personFlow.UpdatePerson(r.Context(), request)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, person)
+}
+
+func (h *PersonHandler) DeletePerson(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_PERSON_ID)
+		return
+	}
+
+	if err := h.personFlow.DeletePerson(r.Context(), id); err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, constant.PERSON_DELETED_SUCCESSFULLY)
+}
+
+func (h *PersonHandler) GetFilmography(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_PERSON_ID)
+		return
+	}
+
+	credits, err := h.personFlow.GetFilmography(r.Context(), id)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, credits)
+}
+
+func (h *PersonHandler) CreateCredit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_PERSON_ID)
+		return
+	}
+
+	request, err := h.personParser.ParseCredit(r)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	request.PersonID = id
+
+	credit, err := h.personFlow.CreateCredit(r.Context(), request)
+	if err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, credit)
+}
+
+func (h *PersonHandler) DeleteCredit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_PERSON_ID)
+		return
+	}
+
+	creditID, err := strconv.Atoi(chi.URLParam(r, "creditId"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, constant.ERROR_INVALID_CREDIT_ID)
+		return
+	}
+
+	if err := h.personFlow.DeleteCredit(r.Context(), id, creditID); err != nil {
+		response.ErrorFrom(w, err)
+		return
+	}
+
+	response.Success(w, constant.CREDIT_DELETED_SUCCESSFULLY)
+}
@@ -0,0 +1,96 @@
+package person
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/response"
+	"strings"
+	"testing"
+)
+
+func TestPersonHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		form       url.Values
+		role       string
+		wantStatus int
+	}{
+		{name: "list people", method: http.MethodGet, path: "/?name=jane", wantStatus: http.StatusOK},
+		{name: "list people with invalid page", method: http.MethodGet, path: "/?page=0", wantStatus: http.StatusBadRequest},
+		{name: "get person", method: http.MethodGet, path: "/1", wantStatus: http.StatusOK},
+		{name: "get missing person", method: http.MethodGet, path: "/99", wantStatus: http.StatusNotFound},
+		{name: "get invalid ID", method: http.MethodGet, path: "/abc", wantStatus: http.StatusBadRequest},
+		{name: "create person", method: http.MethodPost, path: "/", form: url.Values{"name": {"Kim Ji-woon"}, "country": {"KR"}}, wantStatus: http.StatusOK},
+		{name: "create person as jury", method: http.MethodPost, path: "/", form: url.Values{"name": {"Kim Ji-woon"}}, role: entity.UserRoleJury, wantStatus: http.StatusForbidden},
+		{name: "delete credit as viewer", method: http.MethodDelete, path: "/1/credits/1", role: entity.UserRoleViewer, wantStatus: http.StatusForbidden},
+		{name: "filmography as viewer", method: http.MethodGet, path: "/1/filmography", role: entity.UserRoleViewer, wantStatus: http.StatusOK},
+		{name: "create person without name", method: http.MethodPost, path: "/", form: url.Values{"bio": {"Director"}}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "update person", method: http.MethodPut, path: "/1", form: url.Values{"name": {"Jane Q. Doe"}}, wantStatus: http.StatusOK},
+		{name: "update missing person", method: http.MethodPut, path: "/99", form: url.Values{"name": {"Nobody"}}, wantStatus: http.StatusNotFound},
+		{name: "delete person", method: http.MethodDelete, path: "/1", wantStatus: http.StatusOK},
+		{name: "filmography", method: http.MethodGet, path: "/1/filmography", wantStatus: http.StatusOK},
+		{name: "filmography of missing person", method: http.MethodGet, path: "/99/filmography", wantStatus: http.StatusNotFound},
+		{name: "create credit", method: http.MethodPost, path: "/1/credits", form: url.Values{"movie_id": {"1"}, "role": {"actor"}}, wantStatus: http.StatusOK},
+		{name: "create duplicate credit", method: http.MethodPost, path: "/1/credits", form: url.Values{"movie_id": {"1"}, "role": {"director"}}, wantStatus: http.StatusConflict},
+		{name: "create credit with invalid role", method: http.MethodPost, path: "/1/credits", form: url.Values{"movie_id": {"1"}, "role": {"producer"}}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "create credit without movie", method: http.MethodPost, path: "/1/credits", form: url.Values{"role": {"actor"}}, wantStatus: http.StatusBadRequest},
+		{name: "create credit for missing movie", method: http.MethodPost, path: "/1/credits", form: url.Values{"movie_id": {"99"}, "role": {"actor"}}, wantStatus: http.StatusNotFound},
+		{name: "delete credit", method: http.MethodDelete, path: "/1/credits/1", wantStatus: http.StatusOK},
+		{name: "delete missing credit", method: http.MethodDelete, path: "/1/credits/99", wantStatus: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := newMockPersonRepository()
+			mockRepo.credits = append(mockRepo.credits, newCredit(1, 1, 1, "director"))
+			handler := NewPersonHandler(NewPersonParser(), NewPersonFlow(mockRepo))
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.form.Encode()))
+			if test.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			role := test.role
+			if role == "" {
+				role = entity.UserRoleProgrammer
+			}
+			req = req.WithContext(contextWithRole(role))
+			rr := httptest.NewRecorder()
+
+			handler.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Errorf("%s %s status = %v, want %v: %s", test.method, test.path, rr.Code, test.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetFilmographyHandler(t *testing.T) {
+	mockRepo := newMockPersonRepository()
+	mockRepo.credits = append(mockRepo.credits, newCredit(1, 1, 1, "director"), newCredit(2, 1, 1, "writer"))
+	handler := NewPersonHandler(NewPersonParser(), NewPersonFlow(mockRepo))
+
+	rr := httptest.NewRecorder()
+	handler.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/1/filmography", nil))
+
+	var resp response.Response
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	credits, ok := resp.Data.([]interface{})
+	if !ok || len(credits) != 2 {
+		t.Fatalf("GetFilmography() data = %v, want 2 credits", resp.Data)
+	}
+
+	first := credits[0].(map[string]interface{})
+	movie, _ := first["movie"].(map[string]interface{})
+	if first["role"] != "director" || movie["title"] != "Test Movie" {
+		t.Errorf("GetFilmography() first credit = %v", first)
+	}
+}
@@ -0,0 +1,79 @@
+package person
+
+import (
+	"fmt"
+	"net/http"
+	"roketin-case-study-challenge2/internal/entity"
+	"strconv"
+)
+
+type PersonParserInterface interface {
+	ParsePerson(r *http.Request) (*entity.Person, error)
+	ParsePersonFilter(r *http.Request) (*entity.PersonFilter, error)
+	ParseCredit(r *http.Request) (*entity.Credit, error)
+}
+
+type PersonParser struct {
+}
+
+func NewPersonParser() PersonParserInterface {
+	return &PersonParser{}
+}
+
+func (p *PersonParser) ParsePerson(r *http.Request) (*entity.Person, error) {
+	return &entity.Person{
+		Name:     r.PostFormValue("name"),
+		Bio:      r.PostFormValue("bio"),
+		Country:  r.PostFormValue("country"),
+		PhotoURL: r.PostFormValue("photo_url"),
+	}, nil
+}
+
+func (p *PersonParser) ParsePersonFilter(r *http.Request) (*entity.PersonFilter, error) {
+	query := r.URL.Query()
+
+	filter := &entity.PersonFilter{
+		Name: query.Get("name"),
+	}
+
+	if pageStr := query.Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			return nil, fmt.Errorf("page number is not valid: '%s'", pageStr)
+		}
+		if page <= 0 {
+			return nil, fmt.Errorf("page number must be greater than 0: %d", page)
+		}
+		filter.Page = page
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return nil, fmt.Errorf("limit number is not valid: '%s'", limitStr)
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("limit number must be greater than 0: %d", limit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func (p *PersonParser) ParseCredit(r *http.Request) (*entity.Credit, error) {
+	movieIDStr := r.PostFormValue("movie_id")
+	if movieIDStr == "" {
+		return nil, fmt.Errorf("movie_id is required")
+	}
+
+	movieID, err := strconv.Atoi(movieIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("movie_id must be a number")
+	}
+
+	return &entity.Credit{
+		MovieID: movieID,
+		Role:    r.PostFormValue("role"),
+	}, nil
+}
@@ -0,0 +1,27 @@
+package person
+
+import (
+	"context"
+	"roketin-case-study-challenge2/internal/apperror"
+	"roketin-case-study-challenge2/internal/entity"
+)
+
+var (
+	ErrPersonNotFound = apperror.New(apperror.ErrNotFound, "person not found")
+	ErrCreditNotFound = apperror.New(apperror.ErrNotFound, "credit not found")
+	ErrCreditExists   = apperror.New(apperror.ErrConflict, "credit already exists")
+	ErrMovieNotFound  = apperror.New(apperror.ErrNotFound, "movie not found")
+)
+
+type PersonRepository interface {
+	ListPeople(ctx context.Context, filter *entity.PersonFilter) ([]entity.Person, int64, error)
+	GetPerson(ctx context.Context, id int) (*entity.Person, error)
+	CreatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error)
+	UpdatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error)
+	DeletePerson(ctx context.Context, id int) error
+	// GetFilmography returns the credits of a person with their movies,
+	// skipping soft-deleted movies.
+	GetFilmography(ctx context.Context, personID int) ([]entity.Credit, error)
+	CreateCredit(ctx context.Context, credit *entity.Credit) (*entity.Credit, error)
+	DeleteCredit(ctx context.Context, personID, creditID int) error
+}
@@ -0,0 +1,181 @@
+package person
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type mySQLPersonRepository struct {
+	db *gorm.DB
+}
+
+func NewMySQLPersonRepository(db *gorm.DB) PersonRepository {
+	return &mySQLPersonRepository{
+		db: db,
+	}
+}
+
+func (r *mySQLPersonRepository) ListPeople(ctx context.Context, filter *entity.PersonFilter) ([]entity.Person, int64, error) {
+	var people []entity.Person
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.Person{})
+
+	if filter.Name != "" {
+		query = query.Where("LOWER(name) LIKE ?", "%"+strings.ToLower(filter.Name)+"%")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get total people: %w", err)
+	}
+
+	page := filter.GetPage()
+	limit := filter.GetLimit()
+	offset := (page - 1) * limit
+
+	result := query.Order("name ASC").Limit(limit).Offset(offset).Find(&people)
+	if result.Error != nil {
+		return nil, 0, fmt.Errorf("failed to get people: %w", result.Error)
+	}
+
+	return people, total, nil
+}
+
+func (r *mySQLPersonRepository) GetPerson(ctx context.Context, id int) (*entity.Person, error) {
+	var person entity.Person
+	err := r.db.WithContext(ctx).First(&person, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPersonNotFound
+		}
+		return nil, fmt.Errorf("failed to get person: %w", err)
+	}
+
+	return &person, nil
+}
+
+func (r *mySQLPersonRepository) CreatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error) {
+	if err := r.db.WithContext(ctx).Create(person).Error; err != nil {
+		return nil, fmt.Errorf("failed to create person: %w", err)
+	}
+
+	return person, nil
+}
+
+func (r *mySQLPersonRepository) UpdatePerson(ctx context.Context, person *entity.Person) (*entity.Person, error) {
+	result := r.db.WithContext(ctx).Model(&entity.Person{}).Where("id = ?", person.ID).
+		Select("name", "bio", "country", "photo_url", "updated_at").
+		Updates(person)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update person: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, ErrPersonNotFound
+	}
+
+	return r.GetPerson(ctx, person.ID)
+}
+
+func (r *mySQLPersonRepository) DeletePerson(ctx context.Context, id int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("person_id = ?", id).Delete(&entity.Credit{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&entity.Person{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrPersonNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrPersonNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete person: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mySQLPersonRepository) GetFilmography(ctx context.Context, personID int) ([]entity.Credit, error) {
+	if _, err := r.GetPerson(ctx, personID); err != nil {
+		return nil, err
+	}
+
+	var credits []entity.Credit
+	err := r.db.WithContext(ctx).
+		Preload("Movie").
+		Where("person_id = ?", personID).
+		Where("movie_id IN (?)", r.db.Model(&entity.Movie{}).Select("id")).
+		Order("created_at ASC").
+		Find(&credits).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get filmography: %w", err)
+	}
+
+	return credits, nil
+}
+
+func (r *mySQLPersonRepository) CreateCredit(ctx context.Context, credit *entity.Credit) (*entity.Credit, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&entity.Person{}).Where("id = ?", credit.PersonID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrPersonNotFound
+		}
+
+		if err := tx.Model(&entity.Movie{}).Where("id = ?", credit.MovieID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: movie with ID %d not found", ErrMovieNotFound, credit.MovieID)
+		}
+
+		err := tx.Model(&entity.Credit{}).
+			Where("movie_id = ? AND person_id = ? AND role = ?", credit.MovieID, credit.PersonID, credit.Role).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCreditExists
+		}
+
+		return tx.Create(credit).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrPersonNotFound) || errors.Is(err, ErrMovieNotFound) || errors.Is(err, ErrCreditExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create credit: %w", err)
+	}
+
+	return credit, nil
+}
+
+func (r *mySQLPersonRepository) DeleteCredit(ctx context.Context, personID, creditID int) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND person_id = ?", creditID, personID).Delete(&entity.Credit{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete credit: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrCreditNotFound
+	}
+
+	return nil
+}
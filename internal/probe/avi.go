@@ -0,0 +1,174 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+var aviVideoCodecs = map[string]string{
+	"h264": "h264",
+	"x264": "h264",
+	"avc1": "h264",
+	"hevc": "hevc",
+	"h265": "hevc",
+	"xvid": "mpeg4",
+	"divx": "mpeg4",
+	"dx50": "mpeg4",
+	"fmp4": "mpeg4",
+	"mp4v": "mpeg4",
+	"mjpg": "mjpeg",
+	"dvsd": "dv",
+}
+
+var aviAudioCodecs = map[uint16]string{
+	0x0001: "pcm",
+	0x0055: "mp3",
+	0x00FF: "aac",
+	0x2000: "ac3",
+	0x2001: "dts",
+}
+
+type riffChunk struct {
+	id   string
+	data int64
+	end  int64
+}
+
+func probeAVI(src *source) (*Metadata, error) {
+	metadata := &Metadata{Container: ContainerAVI}
+	var microSecPerFrame, totalFrames uint32
+	foundHeader := false
+
+	err := walkRIFF(src, 12, src.size, func(chunk riffChunk) (bool, error) {
+		if chunk.id != "LIST" {
+			return true, nil
+		}
+
+		listType, err := src.read(chunk.data, 4)
+		if err != nil {
+			return false, err
+		}
+		if string(listType) != "hdrl" {
+			return string(listType) != "movi", nil
+		}
+
+		foundHeader = true
+		return false, walkRIFF(src, chunk.data+4, chunk.end, func(child riffChunk) (bool, error) {
+			switch child.id {
+			case "avih":
+				buf, err := src.read(child.data, 40)
+				if err != nil {
+					return false, err
+				}
+				microSecPerFrame = binary.LittleEndian.Uint32(buf[0:4])
+				totalFrames = binary.LittleEndian.Uint32(buf[16:20])
+				metadata.Width = int(binary.LittleEndian.Uint32(buf[32:36]))
+				metadata.Height = int(binary.LittleEndian.Uint32(buf[36:40]))
+			case "LIST":
+				return true, readAVIStream(src, child, metadata)
+			}
+			return true, nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !foundHeader {
+		return nil, fmt.Errorf("%w: avi hdrl list not found", ErrMalformed)
+	}
+
+	if microSecPerFrame > 0 {
+		metadata.Duration, err = secondsToDuration(float64(totalFrames) * float64(microSecPerFrame) / 1e6)
+		if err != nil {
+			return nil, err
+		}
+		if metadata.FrameRate == 0 {
+			metadata.FrameRate = roundFrameRate(1e6 / float64(microSecPerFrame))
+		}
+	}
+
+	return metadata, nil
+}
+
+func readAVIStream(src *source, list riffChunk, metadata *Metadata) error {
+	listType, err := src.read(list.data, 4)
+	if err != nil || string(listType) != "strl" {
+		return err
+	}
+
+	var streamType string
+	return walkRIFF(src, list.data+4, list.end, func(chunk riffChunk) (bool, error) {
+		switch chunk.id {
+		case "strh":
+			buf, err := src.read(chunk.data, 32)
+			if err != nil {
+				return false, err
+			}
+			streamType = string(buf[0:4])
+			scale := binary.LittleEndian.Uint32(buf[20:24])
+			rate := binary.LittleEndian.Uint32(buf[24:28])
+			if streamType == "vids" && scale > 0 && metadata.FrameRate == 0 {
+				metadata.FrameRate = roundFrameRate(float64(rate) / float64(scale))
+			}
+		case "strf":
+			switch streamType {
+			case "vids":
+				buf, err := src.read(chunk.data, 20)
+				if err != nil {
+					return false, err
+				}
+				if metadata.VideoCodec == "" {
+					fourCC := strings.ToLower(string(buf[16:20]))
+					if codec, ok := aviVideoCodecs[fourCC]; ok {
+						metadata.VideoCodec = codec
+					} else {
+						metadata.VideoCodec = strings.TrimSpace(fourCC)
+					}
+				}
+			case "auds":
+				buf, err := src.read(chunk.data, 2)
+				if err != nil {
+					return false, err
+				}
+				if metadata.AudioCodec == "" {
+					formatTag := binary.LittleEndian.Uint16(buf)
+					if codec, ok := aviAudioCodecs[formatTag]; ok {
+						metadata.AudioCodec = codec
+					} else {
+						metadata.AudioCodec = fmt.Sprintf("0x%04x", formatTag)
+					}
+				}
+			}
+		}
+		return true, nil
+	})
+}
+
+// walkRIFF visits the chunks between start and end. The callback returns
+// false to stop the walk early.
+func walkRIFF(src *source, start, end int64, fn func(chunk riffChunk) (bool, error)) error {
+	for offset := start; offset+8 <= end; {
+		header, err := src.read(offset, 8)
+		if err != nil {
+			return err
+		}
+
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+		chunkEnd := offset + 8 + size
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+
+		more, err := fn(riffChunk{id: string(header[0:4]), data: offset + 8, end: chunkEnd})
+		if err != nil || !more {
+			return err
+		}
+
+		// Chunks are padded to an even length.
+		offset = chunkEnd + size%2
+	}
+
+	return nil
+}
@@ -0,0 +1,315 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+const (
+	ebmlIDHeader          = 0x1A45DFA3
+	ebmlIDDocType         = 0x4282
+	ebmlIDSegment         = 0x18538067
+	ebmlIDInfo            = 0x1549A966
+	ebmlIDTimestampScale  = 0x2AD7B1
+	ebmlIDDuration        = 0x4489
+	ebmlIDTracks          = 0x1654AE6B
+	ebmlIDTrackEntry      = 0xAE
+	ebmlIDTrackType       = 0x83
+	ebmlIDCodecID         = 0x86
+	ebmlIDDefaultDuration = 0x23E383
+	ebmlIDVideo           = 0xE0
+	ebmlIDPixelWidth      = 0xB0
+	ebmlIDPixelHeight     = 0xBA
+	ebmlIDCluster         = 0x1F43B675
+
+	matroskaTrackVideo = 1
+	matroskaTrackAudio = 2
+)
+
+var matroskaCodecs = map[string]string{
+	"V_MPEG4/ISO/AVC":  "h264",
+	"V_MPEGH/ISO/HEVC": "hevc",
+	"V_AV1":            "av1",
+	"V_VP8":            "vp8",
+	"V_VP9":            "vp9",
+	"V_MPEG4/ISO/ASP":  "mpeg4",
+	"V_MPEG2":          "mpeg2",
+	"V_PRORES":         "prores",
+	"V_MJPEG":          "mjpeg",
+	"A_AAC":            "aac",
+	"A_OPUS":           "opus",
+	"A_VORBIS":         "vorbis",
+	"A_AC3":            "ac3",
+	"A_EAC3":           "eac3",
+	"A_DTS":            "dts",
+	"A_FLAC":           "flac",
+	"A_MPEG/L3":        "mp3",
+	"A_PCM/INT/LIT":    "pcm",
+}
+
+type ebmlElement struct {
+	id   uint32
+	data int64
+	end  int64
+}
+
+type matroskaTrack struct {
+	kind            uint64
+	codec           string
+	width           int
+	height          int
+	defaultDuration uint64
+}
+
+func probeMatroska(src *source) (*Metadata, error) {
+	metadata := &Metadata{Container: ContainerMatroska}
+	timestampScale := uint64(1000000)
+	var duration float64
+
+	err := walkEBML(src, 0, src.size, func(element ebmlElement) (bool, error) {
+		switch element.id {
+		case ebmlIDHeader:
+			return true, walkEBML(src, element.data, element.end, func(child ebmlElement) (bool, error) {
+				if child.id == ebmlIDDocType {
+					docType, err := readEBMLString(src, child)
+					if err != nil {
+						return false, err
+					}
+					if docType == "webm" {
+						metadata.Container = ContainerWebM
+					}
+				}
+				return true, nil
+			})
+		case ebmlIDSegment:
+			return false, walkEBML(src, element.data, element.end, func(child ebmlElement) (bool, error) {
+				switch child.id {
+				case ebmlIDInfo:
+					return true, walkEBML(src, child.data, child.end, func(info ebmlElement) (bool, error) {
+						var err error
+						switch info.id {
+						case ebmlIDTimestampScale:
+							timestampScale, err = readEBMLUint(src, info)
+						case ebmlIDDuration:
+							duration, err = readEBMLFloat(src, info)
+						}
+						return true, err
+					})
+				case ebmlIDTracks:
+					return true, walkEBML(src, child.data, child.end, func(entry ebmlElement) (bool, error) {
+						if entry.id != ebmlIDTrackEntry {
+							return true, nil
+						}
+						track := &matroskaTrack{}
+						if err := readMatroskaTrack(src, entry, track); err != nil {
+							return false, err
+						}
+						applyMatroskaTrack(track, metadata)
+						return true, nil
+					})
+				case ebmlIDCluster:
+					// Media data follows; everything needed precedes it.
+					return false, nil
+				}
+				return true, nil
+			})
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata.Duration, err = secondsToDuration(duration * float64(timestampScale) / 1e9)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+func readMatroskaTrack(src *source, entry ebmlElement, track *matroskaTrack) error {
+	return walkEBML(src, entry.data, entry.end, func(element ebmlElement) (bool, error) {
+		var err error
+		switch element.id {
+		case ebmlIDTrackType:
+			track.kind, err = readEBMLUint(src, element)
+		case ebmlIDCodecID:
+			track.codec, err = readEBMLString(src, element)
+		case ebmlIDDefaultDuration:
+			track.defaultDuration, err = readEBMLUint(src, element)
+		case ebmlIDVideo:
+			err = walkEBML(src, element.data, element.end, func(video ebmlElement) (bool, error) {
+				switch video.id {
+				case ebmlIDPixelWidth:
+					width, err := readEBMLUint(src, video)
+					track.width = int(width)
+					return true, err
+				case ebmlIDPixelHeight:
+					height, err := readEBMLUint(src, video)
+					track.height = int(height)
+					return true, err
+				}
+				return true, nil
+			})
+		}
+		return true, err
+	})
+}
+
+func applyMatroskaTrack(track *matroskaTrack, metadata *Metadata) {
+	codec := matroskaCodecName(track.codec)
+
+	switch track.kind {
+	case matroskaTrackVideo:
+		if metadata.VideoCodec != "" {
+			return
+		}
+		metadata.VideoCodec = codec
+		metadata.Width = track.width
+		metadata.Height = track.height
+		if track.defaultDuration > 0 {
+			metadata.FrameRate = roundFrameRate(1e9 / float64(track.defaultDuration))
+		}
+	case matroskaTrackAudio:
+		if metadata.AudioCodec == "" {
+			metadata.AudioCodec = codec
+		}
+	}
+}
+
+func matroskaCodecName(codecID string) string {
+	if codec, ok := matroskaCodecs[codecID]; ok {
+		return codec
+	}
+
+	for prefix, codec := range map[string]string{"A_AAC": "aac", "A_PCM": "pcm", "V_MS/VFW": "vfw"} {
+		if strings.HasPrefix(codecID, prefix) {
+			return codec
+		}
+	}
+
+	return strings.ToLower(codecID)
+}
+
+// walkEBML visits the elements between start and end. The callback returns
+// false to stop the walk early.
+func walkEBML(src *source, start, end int64, fn func(element ebmlElement) (bool, error)) error {
+	for offset := start; offset < end; {
+		id, idLength, err := readEBMLVint(src, offset, true)
+		if err != nil {
+			return err
+		}
+
+		size, sizeLength, err := readEBMLVint(src, offset+int64(idLength), false)
+		if err != nil {
+			return err
+		}
+
+		data := offset + int64(idLength+sizeLength)
+		elementEnd := end
+		if size != ebmlUnknownSize(sizeLength) {
+			elementEnd = data + int64(size)
+			if elementEnd > end || elementEnd < data {
+				elementEnd = end
+			}
+		}
+
+		more, err := fn(ebmlElement{id: uint32(id), data: data, end: elementEnd})
+		if err != nil || !more {
+			return err
+		}
+
+		offset = elementEnd
+	}
+
+	return nil
+}
+
+func readEBMLVint(src *source, offset int64, keepMarker bool) (uint64, int, error) {
+	first, err := src.read(offset, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	length := bits.LeadingZeros8(first[0]) + 1
+	if length > 8 {
+		return 0, 0, fmt.Errorf("%w: invalid ebml variable length integer", ErrMalformed)
+	}
+
+	buf, err := src.read(offset, int64(length))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	value := uint64(buf[0])
+	if !keepMarker {
+		value &= uint64(0xFF >> length)
+	}
+	for _, b := range buf[1:] {
+		value = value<<8 | uint64(b)
+	}
+
+	return value, length, nil
+}
+
+func ebmlUnknownSize(length int) uint64 {
+	return 1<<(7*uint(length)) - 1
+}
+
+func readEBMLUint(src *source, element ebmlElement) (uint64, error) {
+	length := element.end - element.data
+	if length > 8 {
+		return 0, fmt.Errorf("%w: ebml unsigned integer too long", ErrMalformed)
+	}
+
+	buf, err := src.read(element.data, length)
+	if err != nil {
+		return 0, err
+	}
+
+	var value uint64
+	for _, b := range buf {
+		value = value<<8 | uint64(b)
+	}
+	return value, nil
+}
+
+func readEBMLFloat(src *source, element ebmlElement) (float64, error) {
+	switch element.end - element.data {
+	case 4:
+		buf, err := src.read(element.data, 4)
+		if err != nil {
+			return 0, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(buf))), nil
+	case 8:
+		buf, err := src.read(element.data, 8)
+		if err != nil {
+			return 0, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(buf)), nil
+	case 0:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("%w: invalid ebml float length", ErrMalformed)
+	}
+}
+
+func readEBMLString(src *source, element ebmlElement) (string, error) {
+	if element.end-element.data > 1024 {
+		return "", fmt.Errorf("%w: ebml string too long", ErrMalformed)
+	}
+
+	buf, err := src.read(element.data, element.end-element.data)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(buf), "\x00"), nil
+}
@@ -0,0 +1,314 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// maxMP4Depth caps how deeply boxes are nested. Real files nest a few
+// levels, while a crafted one could otherwise recurse until the stack
+// overflows.
+const maxMP4Depth = 16
+
+// mp4TimeToSampleChunk is the number of stts entries read at a time.
+const mp4TimeToSampleChunk = 4096
+
+type mp4Box struct {
+	kind  string
+	start int64
+	data  int64
+	end   int64
+	depth int
+}
+
+type mp4Track struct {
+	handler     string
+	codec       string
+	width       int
+	height      int
+	timescale   uint32
+	duration    uint64
+	sampleCount uint64
+}
+
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"av01": "av1",
+	"vp08": "vp8",
+	"vp09": "vp9",
+	"mp4v": "mpeg4",
+	"apcn": "prores",
+	"apch": "prores",
+	"apcs": "prores",
+	"apco": "prores",
+	"ap4h": "prores",
+	"jpeg": "mjpeg",
+	"mp4a": "aac",
+	"ac-3": "ac3",
+	"ec-3": "eac3",
+	"Opus": "opus",
+	"fLaC": "flac",
+	".mp3": "mp3",
+	"lpcm": "pcm",
+	"sowt": "pcm",
+	"twos": "pcm",
+}
+
+func probeMP4(src *source) (*Metadata, error) {
+	metadata := &Metadata{Container: ContainerMP4}
+	foundMoov := false
+
+	err := walkMP4(src, 0, src.size, 0, func(box mp4Box) error {
+		switch box.kind {
+		case "ftyp":
+			brand, err := src.read(box.data, 4)
+			if err != nil {
+				return err
+			}
+			if string(brand) == "qt  " {
+				metadata.Container = ContainerMOV
+			}
+		case "moov":
+			foundMoov = true
+			return probeMP4Movie(src, box, metadata)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !foundMoov {
+		return nil, fmt.Errorf("%w: mp4 moov box not found", ErrMalformed)
+	}
+
+	return metadata, nil
+}
+
+func probeMP4Movie(src *source, moov mp4Box, metadata *Metadata) error {
+	return walkMP4(src, moov.data, moov.end, moov.depth+1, func(box mp4Box) error {
+		switch box.kind {
+		case "mvhd":
+			timescale, duration, err := readMP4Duration(src, box)
+			if err != nil {
+				return err
+			}
+			if timescale > 0 {
+				metadata.Duration, err = secondsToDuration(float64(duration) / float64(timescale))
+			}
+			return err
+		case "trak":
+			track := &mp4Track{}
+			if err := probeMP4Track(src, box, track); err != nil {
+				return err
+			}
+			applyMP4Track(track, metadata)
+		}
+		return nil
+	})
+}
+
+func probeMP4Track(src *source, parent mp4Box, track *mp4Track) error {
+	return walkMP4(src, parent.data, parent.end, parent.depth+1, func(box mp4Box) error {
+		switch box.kind {
+		case "mdia", "minf", "stbl":
+			return probeMP4Track(src, box, track)
+		case "tkhd":
+			return readMP4TrackHeader(src, box, track)
+		case "mdhd":
+			timescale, duration, err := readMP4Duration(src, box)
+			if err != nil {
+				return err
+			}
+			track.timescale = timescale
+			track.duration = duration
+		case "hdlr":
+			handler, err := src.read(box.data+8, 4)
+			if err != nil {
+				return err
+			}
+			track.handler = string(handler)
+		case "stsd":
+			return readMP4SampleDescription(src, box, track)
+		case "stts":
+			return readMP4TimeToSample(src, box, track)
+		}
+		return nil
+	})
+}
+
+func applyMP4Track(track *mp4Track, metadata *Metadata) {
+	switch track.handler {
+	case "vide":
+		if metadata.VideoCodec != "" {
+			return
+		}
+		metadata.VideoCodec = track.codec
+		metadata.Width = track.width
+		metadata.Height = track.height
+		if track.timescale > 0 && track.duration > 0 && track.sampleCount > 0 {
+			seconds := float64(track.duration) / float64(track.timescale)
+			metadata.FrameRate = roundFrameRate(float64(track.sampleCount) / seconds)
+		}
+	case "soun":
+		if metadata.AudioCodec == "" {
+			metadata.AudioCodec = track.codec
+		}
+	}
+}
+
+// readMP4Duration reads the timescale and duration fields shared by the mvhd
+// and mdhd boxes, whose layout depends on the box version.
+func readMP4Duration(src *source, box mp4Box) (uint32, uint64, error) {
+	version, err := src.read(box.data, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if version[0] == 1 {
+		buf, err := src.read(box.data+20, 12)
+		if err != nil {
+			return 0, 0, err
+		}
+		return binary.BigEndian.Uint32(buf[0:4]), binary.BigEndian.Uint64(buf[4:12]), nil
+	}
+
+	buf, err := src.read(box.data+12, 8)
+	if err != nil {
+		return 0, 0, err
+	}
+	duration := uint64(binary.BigEndian.Uint32(buf[4:8]))
+	if duration == 0xFFFFFFFF {
+		duration = 0
+	}
+	return binary.BigEndian.Uint32(buf[0:4]), duration, nil
+}
+
+func readMP4TrackHeader(src *source, box mp4Box, track *mp4Track) error {
+	version, err := src.read(box.data, 1)
+	if err != nil {
+		return err
+	}
+
+	// Width and height are 16.16 fixed point values at the end of the box.
+	offset := box.data + 76
+	if version[0] == 1 {
+		offset = box.data + 88
+	}
+
+	buf, err := src.read(offset, 8)
+	if err != nil {
+		return err
+	}
+
+	track.width = int(binary.BigEndian.Uint32(buf[0:4]) >> 16)
+	track.height = int(binary.BigEndian.Uint32(buf[4:8]) >> 16)
+	return nil
+}
+
+func readMP4SampleDescription(src *source, box mp4Box, track *mp4Track) error {
+	buf, err := src.read(box.data+8, 8)
+	if err != nil {
+		return err
+	}
+
+	format := string(buf[4:8])
+	if codec, ok := mp4Codecs[format]; ok {
+		track.codec = codec
+	} else {
+		track.codec = strings.ToLower(strings.TrimSpace(format))
+	}
+
+	if track.handler == "vide" && track.width == 0 && track.height == 0 && box.end-box.data >= 8+36 {
+		dims, err := src.read(box.data+8+32, 4)
+		if err != nil {
+			return err
+		}
+		track.width = int(binary.BigEndian.Uint16(dims[0:2]))
+		track.height = int(binary.BigEndian.Uint16(dims[2:4]))
+	}
+
+	return nil
+}
+
+func readMP4TimeToSample(src *source, box mp4Box, track *mp4Track) error {
+	buf, err := src.read(box.data+4, 4)
+	if err != nil {
+		return err
+	}
+
+	entries := int64(binary.BigEndian.Uint32(buf))
+	if entries > (box.end-box.data-8)/8 {
+		return fmt.Errorf("%w: stts entry count exceeds box size", ErrMalformed)
+	}
+
+	// The table is read in chunks, as a large box would otherwise be read
+	// into memory at once.
+	for offset := int64(0); offset < entries; offset += mp4TimeToSampleChunk {
+		count := min(entries-offset, mp4TimeToSampleChunk)
+		table, err := src.read(box.data+8+offset*8, count*8)
+		if err != nil {
+			return err
+		}
+
+		for i := int64(0); i < count; i++ {
+			track.sampleCount += uint64(binary.BigEndian.Uint32(table[i*8 : i*8+4]))
+		}
+	}
+
+	return nil
+}
+
+func walkMP4(src *source, start, end int64, depth int, fn func(box mp4Box) error) error {
+	if depth > maxMP4Depth {
+		return fmt.Errorf("%w: mp4 boxes nested deeper than %d levels", ErrMalformed, maxMP4Depth)
+	}
+
+	for offset := start; offset+8 <= end; {
+		header, err := src.read(offset, 8)
+		if err != nil {
+			return err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[0:4]))
+		headerSize := int64(8)
+
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			largeSize, err := src.read(offset+8, 8)
+			if err != nil {
+				return err
+			}
+			size = int64(binary.BigEndian.Uint64(largeSize))
+			headerSize = 16
+		}
+
+		if size < headerSize {
+			return fmt.Errorf("%w: invalid mp4 box size %d", ErrMalformed, size)
+		}
+
+		boxEnd := offset + size
+		if boxEnd > end || boxEnd < offset {
+			// Truncated trailing box, typically mdat of a partial file.
+			boxEnd = end
+		}
+
+		if err := fn(mp4Box{kind: string(header[4:8]), start: offset, data: offset + headerSize, end: boxEnd, depth: depth}); err != nil {
+			return err
+		}
+
+		offset = boxEnd
+	}
+
+	return nil
+}
+
+func roundFrameRate(fps float64) float64 {
+	return float64(int64(fps*1000+0.5)) / 1000
+}
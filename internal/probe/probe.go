@@ -0,0 +1,99 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	ContainerMP4      = "mp4"
+	ContainerMOV      = "mov"
+	ContainerMatroska = "matroska"
+	ContainerWebM     = "webm"
+	ContainerAVI      = "avi"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unrecognized video container")
+	ErrMalformed     = errors.New("malformed video container")
+)
+
+type Metadata struct {
+	Container  string
+	Duration   time.Duration
+	Width      int
+	Height     int
+	VideoCodec string
+	AudioCodec string
+	// Bitrate is the overall bitrate in bits per second.
+	Bitrate   int64
+	FrameRate float64
+}
+
+// Probe reads container headers from r, which holds size bytes, and extracts
+// the stream metadata. Only the header structures are read, so r may be backed
+// by remote storage.
+func Probe(r io.ReaderAt, size int64) (*Metadata, error) {
+	src := &source{r: r, size: size}
+
+	header, err := src.read(0, min(size, SniffLength))
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := Sniff(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata *Metadata
+	switch container {
+	case ContainerMP4, ContainerMOV:
+		metadata, err = probeMP4(src)
+	case ContainerMatroska, ContainerWebM:
+		metadata, err = probeMatroska(src)
+	case ContainerAVI:
+		metadata, err = probeAVI(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Bitrate == 0 && metadata.Duration > 0 {
+		metadata.Bitrate = int64(float64(size*8) / metadata.Duration.Seconds())
+	}
+
+	return metadata, nil
+}
+
+type source struct {
+	r    io.ReaderAt
+	size int64
+}
+
+func (s *source) read(offset, n int64) ([]byte, error) {
+	if offset < 0 || n < 0 || offset+n > s.size {
+		return nil, fmt.Errorf("%w: read of %d bytes at %d exceeds file size", ErrMalformed, n, offset)
+	}
+
+	buf := make([]byte, n)
+	read, err := s.r.ReadAt(buf, offset)
+	if int64(read) < n {
+		return nil, fmt.Errorf("failed to read video header: %w", err)
+	}
+
+	return buf, nil
+}
+
+// secondsToDuration converts a duration read from a file, rejecting values
+// that are not finite, negative or too long for a time.Duration.
+func secondsToDuration(seconds float64) (time.Duration, error) {
+	if math.IsNaN(seconds) || seconds < 0 || seconds >= float64(math.MaxInt64)/float64(time.Second) {
+		return 0, fmt.Errorf("%w: invalid duration of %v seconds", ErrMalformed, seconds)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
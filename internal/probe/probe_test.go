@@ -0,0 +1,339 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func box(kind string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	buf := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(buf, uint32(8+len(body)))
+	copy(buf[4:], kind)
+	return append(buf, body...)
+}
+
+func u32(values ...uint32) []byte {
+	buf := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(buf[i*4:], v)
+	}
+	return buf
+}
+
+func buildMP4(brand string) []byte {
+	mvhd := box("mvhd", u32(0, 0, 0, 1000, 754000), make([]byte, 80))
+
+	tkhd := box("tkhd", u32(0, 0, 0, 1, 0, 0, 0, 0, 0, 0), make([]byte, 36), u32(1920<<16, 1080<<16))
+	videoTrak := box("trak",
+		tkhd,
+		box("mdia",
+			box("mdhd", u32(0, 0, 0, 24000, 18096000, 0)),
+			box("hdlr", u32(0, 0), []byte("vide"), make([]byte, 12)),
+			box("minf", box("stbl",
+				box("stsd", u32(0, 1), box("avc1", make([]byte, 78))),
+				box("stts", u32(0, 1, 18096, 1000)),
+			)),
+		),
+	)
+
+	audioTrak := box("trak",
+		box("tkhd", u32(0, 0, 0, 2, 0, 0, 0, 0, 0, 0), make([]byte, 36), u32(0, 0)),
+		box("mdia",
+			box("mdhd", u32(0, 0, 0, 48000, 36192000, 0)),
+			box("hdlr", u32(0, 0), []byte("soun"), make([]byte, 12)),
+			box("minf", box("stbl", box("stsd", u32(0, 1), box("mp4a", make([]byte, 28))))),
+		),
+	)
+
+	return bytes.Join([][]byte{
+		box("ftyp", []byte(brand), u32(0), []byte("isom")),
+		box("moov", mvhd, videoTrak, audioTrak),
+		box("mdat", make([]byte, 1024)),
+	}, nil)
+}
+
+// buildNestedMP4 builds a track of depth mdia boxes, each inside the last.
+func buildNestedMP4(depth int) []byte {
+	nested := make([]byte, 0, 8*depth)
+	for i := 0; i < depth; i++ {
+		nested = append(nested, u32(uint32(8*(depth-i)))...)
+		nested = append(nested, "mdia"...)
+	}
+
+	return bytes.Join([][]byte{
+		box("ftyp", []byte("isom"), u32(0), []byte("isom")),
+		box("moov", box("trak", nested)),
+	}, nil)
+}
+
+func ebml(id uint32, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+
+	var idBytes []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if b := byte(id >> shift); b != 0 || len(idBytes) > 0 {
+			idBytes = append(idBytes, b)
+		}
+	}
+
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+
+	return bytes.Join([][]byte{idBytes, size, body}, nil)
+}
+
+func ebmlUint(id uint32, value uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, value)
+	return ebml(id, buf)
+}
+
+func ebmlFloat(id uint32, value float64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, math.Float64bits(value))
+	return ebml(id, buf)
+}
+
+func buildMatroska(docType string) []byte {
+	return buildMatroskaWithDuration(docType, 600500)
+}
+
+func buildMatroskaWithDuration(docType string, duration float64) []byte {
+	return bytes.Join([][]byte{
+		ebml(ebmlIDHeader, ebml(ebmlIDDocType, []byte(docType))),
+		ebml(ebmlIDSegment,
+			ebml(ebmlIDInfo,
+				ebmlUint(ebmlIDTimestampScale, 1000000),
+				ebmlFloat(ebmlIDDuration, duration),
+			),
+			ebml(ebmlIDTracks,
+				ebml(ebmlIDTrackEntry,
+					ebmlUint(ebmlIDTrackType, matroskaTrackVideo),
+					ebml(ebmlIDCodecID, []byte("V_VP9")),
+					ebmlUint(ebmlIDDefaultDuration, 40000000),
+					ebml(ebmlIDVideo,
+						ebmlUint(ebmlIDPixelWidth, 1280),
+						ebmlUint(ebmlIDPixelHeight, 720),
+					),
+				),
+				ebml(ebmlIDTrackEntry,
+					ebmlUint(ebmlIDTrackType, matroskaTrackAudio),
+					ebml(ebmlIDCodecID, []byte("A_OPUS")),
+				),
+			),
+			ebml(ebmlIDCluster, make([]byte, 512)),
+		),
+	}, nil)
+}
+
+func chunk(id string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	buf := make([]byte, 8, 8+len(body)+1)
+	copy(buf, id)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(body)))
+	buf = append(buf, body...)
+	if len(body)%2 == 1 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+func le32(values ...uint32) []byte {
+	buf := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], v)
+	}
+	return buf
+}
+
+func buildAVI() []byte {
+	avih := chunk("avih", le32(40000, 0, 0, 0, 3000, 0, 2, 0, 640, 480), make([]byte, 16))
+	videoStream := chunk("LIST", []byte("strl"),
+		chunk("strh", []byte("vids"), []byte("XVID"), le32(0, 0, 0, 1, 25, 0, 3000), make([]byte, 28)),
+		chunk("strf", le32(40, 640, 480), []byte{1, 0, 24, 0}, []byte("XVID"), make([]byte, 20)),
+	)
+	audioStream := chunk("LIST", []byte("strl"),
+		chunk("strh", []byte("auds"), make([]byte, 4), le32(0, 0, 0, 1, 44100, 0, 0), make([]byte, 28)),
+		chunk("strf", []byte{0x55, 0x00, 2, 0}, make([]byte, 14)),
+	)
+
+	body := bytes.Join([][]byte{
+		[]byte("AVI "),
+		chunk("LIST", []byte("hdrl"), avih, videoStream, audioStream),
+		chunk("LIST", []byte("movi"), make([]byte, 256)),
+	}, nil)
+
+	return chunk("RIFF", body)
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Metadata
+		wantErr error
+	}{
+		{
+			name: "mp4",
+			data: buildMP4("isom"),
+			want: Metadata{
+				Container:  ContainerMP4,
+				Duration:   754 * time.Second,
+				Width:      1920,
+				Height:     1080,
+				VideoCodec: "h264",
+				AudioCodec: "aac",
+				FrameRate:  24,
+			},
+		},
+		{
+			name: "quicktime",
+			data: buildMP4("qt  "),
+			want: Metadata{
+				Container:  ContainerMOV,
+				Duration:   754 * time.Second,
+				Width:      1920,
+				Height:     1080,
+				VideoCodec: "h264",
+				AudioCodec: "aac",
+				FrameRate:  24,
+			},
+		},
+		{
+			name: "matroska",
+			data: buildMatroska("matroska"),
+			want: Metadata{
+				Container:  ContainerMatroska,
+				Duration:   600500 * time.Millisecond,
+				Width:      1280,
+				Height:     720,
+				VideoCodec: "vp9",
+				AudioCodec: "opus",
+				FrameRate:  25,
+			},
+		},
+		{
+			name: "webm",
+			data: buildMatroska("webm"),
+			want: Metadata{
+				Container:  ContainerWebM,
+				Duration:   600500 * time.Millisecond,
+				Width:      1280,
+				Height:     720,
+				VideoCodec: "vp9",
+				AudioCodec: "opus",
+				FrameRate:  25,
+			},
+		},
+		{
+			name: "avi",
+			data: buildAVI(),
+			want: Metadata{
+				Container:  ContainerAVI,
+				Duration:   120 * time.Second,
+				Width:      640,
+				Height:     480,
+				VideoCodec: "mpeg4",
+				AudioCodec: "mp3",
+				FrameRate:  25,
+			},
+		},
+		{
+			name:    "unknown format",
+			data:    []byte("definitely not a video file"),
+			wantErr: ErrUnknownFormat,
+		},
+		{
+			name:    "mp4 without moov",
+			data:    box("ftyp", []byte("isom"), u32(0)),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "deeply nested mp4 boxes",
+			data:    buildNestedMP4(1 << 20),
+			wantErr: ErrMalformed,
+		},
+		{
+			name: "mp4 with more stts entries than fit its box",
+			data: bytes.Join([][]byte{
+				box("ftyp", []byte("isom"), u32(0), []byte("isom")),
+				box("moov", box("trak", box("stts", u32(0, 0xFFFFFFFF, 1, 1000)))),
+			}, nil),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "matroska with NaN duration",
+			data:    buildMatroskaWithDuration("matroska", math.NaN()),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "matroska with infinite duration",
+			data:    buildMatroskaWithDuration("matroska", math.Inf(1)),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "matroska with negative duration",
+			data:    buildMatroskaWithDuration("matroska", -1),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "matroska with overflowing duration",
+			data:    buildMatroskaWithDuration("matroska", 1e300),
+			wantErr: ErrMalformed,
+		},
+		{
+			name:    "truncated matroska",
+			data:    buildMatroska("matroska")[:20],
+			wantErr: ErrMalformed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Probe(bytes.NewReader(test.data), int64(len(test.data)))
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Probe() error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr != nil {
+				return
+			}
+
+			wantBitrate := int64(float64(len(test.data)*8) / test.want.Duration.Seconds())
+			test.want.Bitrate = wantBitrate
+
+			if *got != test.want {
+				t.Errorf("Probe() = %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadMP4TimeToSampleInChunks(t *testing.T) {
+	entries := 2*mp4TimeToSampleChunk + 10
+	table := make([]uint32, 0, 2*entries)
+	for i := 0; i < entries; i++ {
+		table = append(table, uint32(i%3+1), 1000)
+	}
+	data := box("stts", u32(0, uint32(entries)), u32(table...))
+	src := &source{r: bytes.NewReader(data), size: int64(len(data))}
+
+	track := &mp4Track{}
+	if err := readMP4TimeToSample(src, mp4Box{kind: "stts", data: 8, end: int64(len(data))}, track); err != nil {
+		t.Fatalf("readMP4TimeToSample() error = %v", err)
+	}
+
+	var want uint64
+	for i := 0; i < entries; i++ {
+		want += uint64(i%3 + 1)
+	}
+	if track.sampleCount != want {
+		t.Errorf("readMP4TimeToSample() sample count = %d, want %d", track.sampleCount, want)
+	}
+}
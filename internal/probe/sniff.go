@@ -0,0 +1,72 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// SniffLength is the number of leading bytes Sniff needs to identify a file.
+const SniffLength = 64
+
+var mimeTypes = map[string]string{
+	ContainerMP4:      "video/mp4",
+	ContainerMOV:      "video/quicktime",
+	ContainerMatroska: "video/x-matroska",
+	ContainerWebM:     "video/webm",
+	ContainerAVI:      "video/x-msvideo",
+}
+
+var extensionContainers = map[string][]string{
+	".mp4":  {ContainerMP4, ContainerMOV},
+	".m4v":  {ContainerMP4, ContainerMOV},
+	".mov":  {ContainerMOV, ContainerMP4},
+	".mkv":  {ContainerMatroska, ContainerWebM},
+	".webm": {ContainerWebM, ContainerMatroska},
+	".avi":  {ContainerAVI},
+}
+
+// Sniff identifies the container from the leading bytes of a file using its
+// signature: an ISO BMFF ftyp box, an EBML header or a RIFF AVI header.
+func Sniff(header []byte) (string, error) {
+	switch {
+	case len(header) >= 12 && string(header[4:8]) == "ftyp":
+		if size := binary.BigEndian.Uint32(header[0:4]); size < 12 && size != 1 {
+			return "", ErrUnknownFormat
+		}
+		if string(header[8:12]) == "qt  " {
+			return ContainerMOV, nil
+		}
+		return ContainerMP4, nil
+	case len(header) >= 4 && bytes.Equal(header[:4], ebmlMagic):
+		if bytes.Contains(header, []byte("webm")) {
+			return ContainerWebM, nil
+		}
+		return ContainerMatroska, nil
+	case len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "AVI ":
+		return ContainerAVI, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+func MimeType(container string) string {
+	if mimeType, ok := mimeTypes[container]; ok {
+		return mimeType
+	}
+
+	return "application/octet-stream"
+}
+
+// MatchesExtension reports whether a sniffed container is a plausible
+// content for a file with the given extension. ISO BMFF and Matroska
+// variants are interchangeable with their sibling extensions.
+func MatchesExtension(container, ext string) bool {
+	for _, candidate := range extensionContainers[strings.ToLower(ext)] {
+		if candidate == container {
+			return true
+		}
+	}
+
+	return false
+}
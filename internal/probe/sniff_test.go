@@ -0,0 +1,64 @@
+package probe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []byte
+		want    string
+		wantErr error
+	}{
+		{name: "mp4", header: buildMP4("isom"), want: ContainerMP4},
+		{name: "quicktime", header: buildMP4("qt  "), want: ContainerMOV},
+		{name: "matroska", header: buildMatroska("matroska"), want: ContainerMatroska},
+		{name: "webm", header: buildMatroska("webm"), want: ContainerWebM},
+		{name: "avi", header: buildAVI(), want: ContainerAVI},
+		{name: "plain text", header: []byte("test content"), wantErr: ErrUnknownFormat},
+		{name: "riff wave", header: []byte("RIFF\x00\x00\x00\x00WAVEfmt "), wantErr: ErrUnknownFormat},
+		{name: "too short", header: []byte{0x1A, 0x45}, wantErr: ErrUnknownFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			header := test.header
+			if len(header) > SniffLength {
+				header = header[:SniffLength]
+			}
+
+			got, err := Sniff(header)
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Sniff() error = %v, want %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("Sniff() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchesExtension(t *testing.T) {
+	tests := []struct {
+		container string
+		ext       string
+		want      bool
+	}{
+		{ContainerMP4, ".mp4", true},
+		{ContainerMP4, ".MOV", true},
+		{ContainerWebM, ".mkv", true},
+		{ContainerAVI, ".avi", true},
+		{ContainerAVI, ".mp4", false},
+		{ContainerMatroska, ".avi", false},
+		{ContainerMP4, ".txt", false},
+	}
+
+	for _, test := range tests {
+		if got := MatchesExtension(test.container, test.ext); got != test.want {
+			t.Errorf("MatchesExtension(%q, %q) = %v, want %v", test.container, test.ext, got, test.want)
+		}
+	}
+}
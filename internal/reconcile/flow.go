@@ -0,0 +1,153 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"roketin-case-study-challenge2/internal/storage"
+	"sort"
+	"time"
+)
+
+type Mode string
+
+const (
+	// ModeReport only reports orphans and dangling references.
+	ModeReport Mode = "report"
+	// ModeQuarantine moves orphans below the quarantine prefix.
+	ModeQuarantine Mode = "quarantine"
+	// ModeDelete removes orphans.
+	ModeDelete Mode = "delete"
+)
+
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case ModeReport, ModeQuarantine, ModeDelete:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("reconcile mode must be report, quarantine or delete: '%s'", value)
+	}
+}
+
+type ReconcileFlowInterface interface {
+	Reconcile(ctx context.Context, mode Mode) (*Report, error)
+}
+
+type ReconcileConfig struct {
+	// Prefix is the storage prefix holding movie videos.
+	Prefix string
+	// QuarantinePrefix is where ModeQuarantine moves orphans to.
+	QuarantinePrefix string
+	// GracePeriod protects files stored so recently that the movie
+	// referencing them may not have been written yet.
+	GracePeriod time.Duration
+}
+
+// Orphan is a stored object no movie references.
+type Orphan struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Action is what was done to the orphan: reported, quarantined or
+	// deleted. It stays reported when moving or deleting it failed.
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Mode     Mode            `json:"mode"`
+	Checked  int             `json:"checked"`
+	Orphans  []Orphan        `json:"orphans"`
+	Dangling []FileReference `json:"dangling"`
+}
+
+type reconcileFlow struct {
+	referenceRepo ReferenceRepository
+	storage       storage.Storage
+	cfg           ReconcileConfig
+	now           func() time.Time
+}
+
+func NewReconcileFlow(referenceRepo ReferenceRepository, storage storage.Storage, cfg ReconcileConfig) ReconcileFlowInterface {
+	return &reconcileFlow{
+		referenceRepo: referenceRepo,
+		storage:       storage,
+		cfg:           cfg,
+		now:           time.Now,
+	}
+}
+
+// Reconcile cross-references the stored videos with the files movies point
+// at. Objects are listed before the references are read, so a movie created
+// in between is not mistaken for an orphan.
+func (f *reconcileFlow) Reconcile(ctx context.Context, mode Mode) (*Report, error) {
+	objects, err := f.storage.List(ctx, f.cfg.Prefix+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stored files: %w", err)
+	}
+
+	references, err := f.referenceRepo.ListFileReferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool, len(references))
+	for _, reference := range references {
+		referenced[reference.Path] = true
+	}
+
+	stored := make(map[string]bool, len(objects))
+	report := &Report{Mode: mode, Checked: len(objects)}
+	cutoff := f.now().Add(-f.cfg.GracePeriod)
+
+	for _, object := range objects {
+		stored[object.Key] = true
+		if referenced[object.Key] || object.ModTime.After(cutoff) {
+			continue
+		}
+
+		orphan := Orphan{Key: object.Key, Size: object.Size, ModTime: object.ModTime, Action: "reported"}
+		switch mode {
+		case ModeQuarantine:
+			err = f.quarantine(ctx, object)
+			if err == nil {
+				orphan.Action = "quarantined"
+			}
+		case ModeDelete:
+			err = f.storage.Delete(ctx, object.Key)
+			if err == nil {
+				orphan.Action = "deleted"
+			}
+		}
+		if err != nil {
+			orphan.Error = err.Error()
+			err = nil
+		}
+
+		report.Orphans = append(report.Orphans, orphan)
+	}
+
+	// References outside the prefix were not listed and are looked up.
+	for _, reference := range references {
+		if stored[reference.Path] {
+			continue
+		}
+
+		_, err := f.storage.Stat(ctx, reference.Path)
+		if errors.Is(err, storage.ErrNotFound) {
+			report.Dangling = append(report.Dangling, reference)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", reference.Path, err)
+		}
+	}
+
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i].Key < report.Orphans[j].Key })
+
+	return report, nil
+}
+
+// quarantine moves an object below the quarantine prefix, keeping its key.
+func (f *reconcileFlow) quarantine(ctx context.Context, object storage.ObjectInfo) error {
+	return f.storage.Move(ctx, object.Key, path.Join(f.cfg.QuarantinePrefix, object.Key))
+}
@@ -0,0 +1,163 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"io"
+	"roketin-case-study-challenge2/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockReferenceRepository struct {
+	references []FileReference
+	err        error
+}
+
+func (m *MockReferenceRepository) ListFileReferences(ctx context.Context) ([]FileReference, error) {
+	return m.references, m.err
+}
+
+func setupStorage(t *testing.T, keys ...string) storage.Storage {
+	t.Helper()
+
+	store := storage.NewLocalStorage(t.TempDir())
+	for _, key := range keys {
+		if err := store.Put(context.Background(), key, strings.NewReader("video"), 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func TestReconcile(t *testing.T) {
+	references := []FileReference{
+		{Path: "uploads/current.mp4", MovieID: 1},
+		{Path: "uploads/previous.mp4", MovieID: 1, Replaced: true},
+		{Path: "uploads/deleted.mp4", MovieID: 2, MovieDeleted: true},
+		{Path: "uploads/missing.mp4", MovieID: 3},
+	}
+
+	tests := []struct {
+		name            string
+		mode            Mode
+		gracePeriod     time.Duration
+		wantOrphans     int
+		wantAction      string
+		wantKeys        []string
+		wantQuarantined bool
+	}{
+		{
+			name:        "report orphans",
+			mode:        ModeReport,
+			wantOrphans: 1,
+			wantAction:  "reported",
+			wantKeys:    []string{"uploads/current.mp4", "uploads/deleted.mp4", "uploads/orphan.mp4", "uploads/previous.mp4"},
+		},
+		{
+			name:            "quarantine orphans",
+			mode:            ModeQuarantine,
+			wantOrphans:     1,
+			wantAction:      "quarantined",
+			wantKeys:        []string{"uploads/current.mp4", "uploads/deleted.mp4", "uploads/previous.mp4"},
+			wantQuarantined: true,
+		},
+		{
+			name:        "delete orphans",
+			mode:        ModeDelete,
+			wantOrphans: 1,
+			wantAction:  "deleted",
+			wantKeys:    []string{"uploads/current.mp4", "uploads/deleted.mp4", "uploads/previous.mp4"},
+		},
+		{
+			name:        "recent files are left alone",
+			mode:        ModeDelete,
+			gracePeriod: 2 * time.Hour,
+			wantKeys:    []string{"uploads/current.mp4", "uploads/deleted.mp4", "uploads/orphan.mp4", "uploads/previous.mp4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := setupStorage(t, "uploads/current.mp4", "uploads/previous.mp4", "uploads/deleted.mp4", "uploads/orphan.mp4")
+
+			flow := NewReconcileFlow(&MockReferenceRepository{references: references}, store, ReconcileConfig{
+				Prefix:           "uploads",
+				QuarantinePrefix: "quarantine",
+				GracePeriod:      test.gracePeriod,
+			})
+			flow.(*reconcileFlow).now = func() time.Time { return time.Now().Add(time.Hour) }
+
+			report, err := flow.Reconcile(ctx, test.mode)
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if report.Checked != 4 {
+				t.Errorf("Reconcile() checked = %d, want 4", report.Checked)
+			}
+			if len(report.Orphans) != test.wantOrphans {
+				t.Fatalf("Reconcile() orphans = %+v, want %d", report.Orphans, test.wantOrphans)
+			}
+			if test.wantOrphans > 0 && (report.Orphans[0].Key != "uploads/orphan.mp4" || report.Orphans[0].Action != test.wantAction) {
+				t.Errorf("Reconcile() orphan = %+v, want uploads/orphan.mp4 %s", report.Orphans[0], test.wantAction)
+			}
+			if len(report.Dangling) != 1 || report.Dangling[0].Path != "uploads/missing.mp4" || report.Dangling[0].MovieID != 3 {
+				t.Errorf("Reconcile() dangling = %+v, want uploads/missing.mp4 of movie 3", report.Dangling)
+			}
+
+			objects, err := store.List(ctx, "uploads/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, object := range objects {
+				keys = append(keys, object.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(test.wantKeys, ",") {
+				t.Errorf("stored files = %v, want %v", keys, test.wantKeys)
+			}
+
+			rc, err := store.Get(ctx, "quarantine/uploads/orphan.mp4")
+			if test.wantQuarantined {
+				if err != nil {
+					t.Fatalf("quarantined file error = %v", err)
+				}
+				content, _ := io.ReadAll(rc)
+				rc.Close()
+				if string(content) != "video" {
+					t.Errorf("quarantined file content = %q, want video", content)
+				}
+			} else if !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("quarantined file error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestReconcileRepositoryError(t *testing.T) {
+	store := setupStorage(t, "uploads/orphan.mp4")
+	flow := NewReconcileFlow(&MockReferenceRepository{err: errors.New("connection refused")}, store, ReconcileConfig{Prefix: "uploads"})
+
+	if _, err := flow.Reconcile(context.Background(), ModeDelete); err == nil {
+		t.Fatal("Reconcile() error = nil, want the repository error")
+	}
+
+	if _, err := store.Stat(context.Background(), "uploads/orphan.mp4"); err != nil {
+		t.Errorf("orphan was touched although references could not be read: %v", err)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, value := range []string{"report", "quarantine", "delete"} {
+		if mode, err := ParseMode(value); err != nil || string(mode) != value {
+			t.Errorf("ParseMode(%q) = %q, %v", value, mode, err)
+		}
+	}
+
+	if _, err := ParseMode("purge"); err == nil {
+		t.Error("ParseMode(\"purge\") error = nil, want an error")
+	}
+}
@@ -0,0 +1,16 @@
+package reconcile
+
+import "context"
+
+// FileReference is a stored video a movie points at, either its current file
+// or a version it replaced.
+type FileReference struct {
+	Path         string `json:"path"`
+	MovieID      int    `json:"movie_id"`
+	MovieDeleted bool   `json:"movie_deleted"`
+	Replaced     bool   `json:"replaced"`
+}
+
+type ReferenceRepository interface {
+	ListFileReferences(ctx context.Context) ([]FileReference, error)
+}
@@ -0,0 +1,56 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"roketin-case-study-challenge2/internal/entity"
+
+	"gorm.io/gorm"
+)
+
+type mySQLReferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewMySQLReferenceRepository(db *gorm.DB) ReferenceRepository {
+	return &mySQLReferenceRepository{
+		db: db,
+	}
+}
+
+// ListFileReferences includes soft deleted movies, whose videos are kept
+// until the movie is purged.
+func (r *mySQLReferenceRepository) ListFileReferences(ctx context.Context) ([]FileReference, error) {
+	var movies []entity.Movie
+	err := r.db.WithContext(ctx).Unscoped().
+		Select("id", "file_path", "deleted_at").
+		Where("file_path <> ''").
+		Find(&movies).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list movie files: %w", err)
+	}
+
+	var versions []entity.MovieFileVersion
+	err = r.db.WithContext(ctx).Select("movie_id", "file_path").Find(&versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list movie file versions: %w", err)
+	}
+
+	references := make([]FileReference, 0, len(movies)+len(versions))
+	for _, movie := range movies {
+		references = append(references, FileReference{
+			Path:         movie.FilePath,
+			MovieID:      movie.ID,
+			MovieDeleted: movie.DeletedAt != nil && movie.DeletedAt.Valid,
+		})
+	}
+	for _, version := range versions {
+		references = append(references, FileReference{
+			Path:     version.FilePath,
+			MovieID:  version.MovieID,
+			Replaced: true,
+		})
+	}
+
+	return references, nil
+}
@@ -0,0 +1,70 @@
+package reconcile
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, error) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database connection: %v", err)
+	}
+
+	dialector := mysql.New(mysql.Config{
+		Conn:                      mockDB,
+		SkipInitializeWithVersion: true,
+	})
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open GORM DB: %v", err)
+	}
+
+	return db, mock, err
+}
+
+func TestListFileReferencesRepository(t *testing.T) {
+	db, mock, err := setupTestDB(t)
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	repo := NewMySQLReferenceRepository(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `id`,`file_path`,`deleted_at` FROM `movies` WHERE file_path <> ''")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_path", "deleted_at"}).
+			AddRow(1, "uploads/current.mp4", nil).
+			AddRow(2, "uploads/deleted.mp4", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT `movie_id`,`file_path` FROM `movie_file_versions`")).
+		WillReturnRows(sqlmock.NewRows([]string{"movie_id", "file_path"}).AddRow(1, "uploads/previous.mp4"))
+
+	references, err := repo.ListFileReferences(context.Background())
+	if err != nil {
+		t.Fatalf("ListFileReferences() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+
+	want := []FileReference{
+		{Path: "uploads/current.mp4", MovieID: 1},
+		{Path: "uploads/deleted.mp4", MovieID: 2, MovieDeleted: true},
+		{Path: "uploads/previous.mp4", MovieID: 1, Replaced: true},
+	}
+	if len(references) != len(want) {
+		t.Fatalf("ListFileReferences() = %+v, want %+v", references, want)
+	}
+	for i := range want {
+		if references[i] != want[i] {
+			t.Errorf("ListFileReferences()[%d] = %+v, want %+v", i, references[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"roketin-case-study-challenge2/internal/apperror"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Errors lists the invalid
+// fields of a validation problem and Position the 1-based character of a
+// malformed query at which parsing failed.
+type Problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Errors   []apperror.FieldError `json:"errors,omitempty"`
+	Position int                   `json:"position,omitempty"`
+}
+
+// StatusFromError maps the kind of a domain error to its HTTP status.
+func StatusFromError(err error) int {
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperror.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, apperror.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperror.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, apperror.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, apperror.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func Error(w http.ResponseWriter, status int, message string) {
+	respondWithContentType(w, ProblemContentType, status, newProblem(status, message))
+}
+
+// ErrorFrom writes err with the status matching its kind.
+func ErrorFrom(w http.ResponseWriter, err error) {
+	ErrorWithStatus(w, StatusFromError(err), err)
+}
+
+// ErrorWithStatus writes err with status, listing its field errors if any.
+func ErrorWithStatus(w http.ResponseWriter, status int, err error) {
+	problem := newProblem(status, err.Error())
+
+	var verr *apperror.ValidationErrors
+	if errors.As(err, &verr) {
+		problem.Errors = verr.Fields
+	}
+
+	var perr interface{ Position() int }
+	if errors.As(err, &perr) {
+		problem.Position = perr.Position()
+	}
+
+	respondWithContentType(w, ProblemContentType, status, problem)
+}
+
+func newProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
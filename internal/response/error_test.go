@@ -0,0 +1,35 @@
+package response
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"roketin-case-study-challenge2/internal/apperror"
+	"testing"
+)
+
+func TestStatusFromError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "not found", err: apperror.NotFound("movie with ID %d not found", 1), wantStatus: http.StatusNotFound},
+		{name: "validation", err: apperror.Validation("title is required"), wantStatus: http.StatusUnprocessableEntity},
+		{name: "conflict", err: apperror.Conflict("genre already exists"), wantStatus: http.StatusConflict},
+		{name: "forbidden", err: fmt.Errorf("%w: role viewer may not perform movie:delete", apperror.ErrForbidden), wantStatus: http.StatusForbidden},
+		{name: "unauthorized", err: apperror.New(apperror.ErrUnauthorized, "invalid token"), wantStatus: http.StatusUnauthorized},
+		{name: "precondition failed", err: apperror.New(apperror.ErrPreconditionFailed, "movie has been modified"), wantStatus: http.StatusPreconditionFailed},
+		{name: "precondition required", err: apperror.New(apperror.ErrPreconditionRequired, "If-Match is required"), wantStatus: http.StatusPreconditionRequired},
+		{name: "wrapped", err: fmt.Errorf("update failed: %w", apperror.NotFound("gone")), wantStatus: http.StatusNotFound},
+		{name: "unknown", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := StatusFromError(test.err); got != test.wantStatus {
+				t.Errorf("StatusFromError() = %v, want %v", got, test.wantStatus)
+			}
+		})
+	}
+}
@@ -6,14 +6,14 @@ import (
 )
 
 type Response struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message,omitempty"`
-	Data    interface{} `json:"data,omitempty"`
+	Status string      `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
 }
 
 type ResponseWithPagination struct {
 	Data       interface{} `json:"data"`
 	Pagination Pagination  `json:"pagination"`
+	Facets     interface{} `json:"facets,omitempty"`
 }
 
 type Pagination struct {
@@ -41,17 +41,24 @@ func SuccessWithPagination(w http.ResponseWriter, data interface{}, pagination P
 	Success(w, response)
 }
 
-func Error(w http.ResponseWriter, status int, message string) {
-	response := Response{
-		Status:  "error",
-		Message: message,
+// SuccessWithFacets is SuccessWithPagination with the facet counts of the
+// search that found data.
+func SuccessWithFacets(w http.ResponseWriter, data interface{}, pagination Pagination, facets interface{}) {
+	response := ResponseWithPagination{
+		Data:       data,
+		Pagination: pagination,
+		Facets:     facets,
 	}
 
-	respondWithJSON(w, status, response)
+	Success(w, response)
 }
 
 func respondWithJSON(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+	respondWithContentType(w, "application/json", status, data)
+}
+
+func respondWithContentType(w http.ResponseWriter, contentType string, status int, data interface{}) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(data)
 }
@@ -0,0 +1,244 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// foldedLetters are letters without a decomposition into base letter and
+// accent.
+var foldedLetters = strings.NewReplacer("ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "ł", "l", "đ", "d", "ı", "i", "þ", "th")
+
+// Normalize folds text for matching: lower case, without accents and with
+// anything but letters and digits collapsed into single spaces, so that
+// "Amélie  Poulain!" becomes "amelie poulain".
+func Normalize(text string) string {
+	text = foldedLetters.Replace(strings.ToLower(text))
+
+	var b strings.Builder
+	space := false
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+
+	return b.String()
+}
+
+// Distance is the number of single character insertions, deletions,
+// substitutions and transpositions of adjacent characters turning a into b.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(ra)][len(rb)]
+}
+
+// MaxTypos is how many typos a query of length runes may contain: none
+// below four characters, one up to seven and two beyond.
+func MaxTypos(length int) int {
+	switch {
+	case length < 4:
+		return 0
+	case length < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Match is a value close to a query. Matched holds the words of Value the
+// query matched and Start the index of the first of them.
+type Match struct {
+	ID       int
+	Value    string
+	Matched  string
+	Start    int
+	Distance int
+}
+
+type fuzzyEntry struct {
+	id         int
+	value      string
+	words      []string
+	normalized []string
+}
+
+// fuzzyPosting is the word at start of an entry.
+type fuzzyPosting struct {
+	entry int
+	start int
+}
+
+// FuzzyIndex finds values matching a query that may be misspelled or lack
+// accents. Queries are compared word by word with Distance. Typos are only
+// tolerated after the first letter, so a query is only compared with the
+// words starting with the same letter.
+type FuzzyIndex struct {
+	entries []fuzzyEntry
+	byFirst map[rune][]fuzzyPosting
+}
+
+func NewFuzzyIndex() *FuzzyIndex {
+	return &FuzzyIndex{byFirst: map[rune][]fuzzyPosting{}}
+}
+
+func (i *FuzzyIndex) Add(id int, value string) {
+	entry := fuzzyEntry{id: id, value: value}
+	for _, word := range strings.Fields(value) {
+		if normalized := Normalize(word); normalized != "" {
+			entry.words = append(entry.words, word)
+			entry.normalized = append(entry.normalized, normalized)
+		}
+	}
+
+	if len(entry.words) == 0 {
+		return
+	}
+
+	for start, word := range entry.normalized {
+		first := []rune(word)[0]
+		i.byFirst[first] = append(i.byFirst[first], fuzzyPosting{entry: len(i.entries), start: start})
+	}
+	i.entries = append(i.entries, entry)
+}
+
+// Suggest returns up to limit values with a word starting with prefix, give
+// or take MaxTypos. Closer matches come first, then values starting with
+// the prefix, then shorter values.
+func (i *FuzzyIndex) Suggest(prefix string, limit int) []Match {
+	query := []rune(Normalize(prefix))
+	if len(query) == 0 {
+		return nil
+	}
+	maxTypos := MaxTypos(len(query))
+
+	best := map[int]Match{}
+	for _, posting := range i.byFirst[query[0]] {
+		entry := i.entries[posting.entry]
+		text := []rune(strings.Join(entry.normalized[posting.start:], " "))
+
+		// The prefix may be a character shorter or longer than the part of
+		// the value it stands for.
+		for length := len(query) - 1; length <= len(query)+1; length++ {
+			if length < 1 || length > len(text) {
+				continue
+			}
+
+			distance := Distance(string(query), string(text[:length]))
+			if distance > maxTypos {
+				continue
+			}
+			if match, ok := best[posting.entry]; ok && match.Distance <= distance {
+				continue
+			}
+			best[posting.entry] = Match{
+				ID:       entry.id,
+				Value:    entry.value,
+				Matched:  strings.Join(entry.words[posting.start:], " "),
+				Start:    posting.start,
+				Distance: distance,
+			}
+		}
+	}
+
+	matches := make([]Match, 0, len(best))
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+
+	sortMatches(matches)
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	return matches
+}
+
+// Closest returns the value containing the words closest to query, give or
+// take MaxTypos of its length.
+func (i *FuzzyIndex) Closest(query string) (Match, bool) {
+	normalized := Normalize(query)
+	count := len(strings.Fields(normalized))
+	if count == 0 {
+		return Match{}, false
+	}
+	maxTypos := MaxTypos(len([]rune(normalized)))
+
+	var matches []Match
+	for _, posting := range i.byFirst[[]rune(normalized)[0]] {
+		entry := i.entries[posting.entry]
+		end := posting.start + count
+		if end > len(entry.normalized) {
+			continue
+		}
+
+		distance := Distance(normalized, strings.Join(entry.normalized[posting.start:end], " "))
+		if distance <= maxTypos {
+			matches = append(matches, Match{
+				ID:       entry.id,
+				Value:    entry.value,
+				Matched:  strings.Join(entry.words[posting.start:end], " "),
+				Start:    posting.start,
+				Distance: distance,
+			})
+		}
+	}
+
+	if len(matches) == 0 {
+		return Match{}, false
+	}
+
+	sortMatches(matches)
+	return matches[0], true
+}
+
+func sortMatches(matches []Match) {
+	sort.SliceStable(matches, func(a, b int) bool {
+		switch {
+		case matches[a].Distance != matches[b].Distance:
+			return matches[a].Distance < matches[b].Distance
+		case matches[a].Start != matches[b].Start:
+			return matches[a].Start < matches[b].Start
+		case len(matches[a].Value) != len(matches[b].Value):
+			return len(matches[a].Value) < len(matches[b].Value)
+		case matches[a].Value != matches[b].Value:
+			return matches[a].Value < matches[b].Value
+		default:
+			return matches[a].ID < matches[b].ID
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "Amélie  Poulain!", want: "amelie poulain"},
+		{input: "Łódź Straße", want: "lodz strasse"},
+		{input: "Ça m'est égal", want: "ca m est egal"},
+		{input: "  ", want: ""},
+	}
+
+	for _, test := range tests {
+		if got := Normalize(test.input); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "odyssey", b: "odyssey", want: 0},
+		{a: "odysey", b: "odyssey", want: 1},
+		{a: "odysesy", b: "odyssey", want: 1},
+		{a: "kitten", b: "sitting", want: 3},
+		{a: "", b: "abc", want: 3},
+		{a: "żółw", b: "zolw", want: 3},
+	}
+
+	for _, test := range tests {
+		if got := Distance(test.a, test.b); got != test.want {
+			t.Errorf("Distance(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestFuzzyIndexSuggest(t *testing.T) {
+	index := NewFuzzyIndex()
+	for id, value := range []string{"Amélie", "American Beauty", "Space Odyssey", "Jane Doe", "Björk", "Ame"} {
+		index.Add(id, value)
+	}
+
+	values := func(matches []Match) []string {
+		var values []string
+		for _, match := range matches {
+			values = append(values, match.Value)
+		}
+		return values
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		limit  int
+		want   []string
+	}{
+		{name: "accents are ignored", prefix: "ame", want: []string{"Ame", "Amélie", "American Beauty"}},
+		{name: "limit", prefix: "ame", limit: 2, want: []string{"Ame", "Amélie"}},
+		{name: "typo", prefix: "amelei", want: []string{"Amélie"}},
+		{name: "later words", prefix: "odysey", want: []string{"Space Odyssey"}},
+		{name: "accent in query", prefix: "BJÖR", want: []string{"Björk"}},
+		{name: "short prefixes must match exactly", prefix: "dx", want: nil},
+		{name: "the first letter must match", prefix: "bmelie", want: nil},
+		{name: "no match", prefix: "western", want: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := values(index.Suggest(test.prefix, test.limit)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Suggest(%q) = %v, want %v", test.prefix, got, test.want)
+			}
+		})
+	}
+}
+
+func TestFuzzyIndexClosest(t *testing.T) {
+	index := NewFuzzyIndex()
+	index.Add(1, "2001: A Space Odyssey")
+	index.Add(2, "Amélie")
+	index.Add(3, "Jane Doe")
+
+	tests := []struct {
+		query       string
+		wantID      int
+		wantMatched string
+		wantOK      bool
+	}{
+		{query: "space odysey", wantID: 1, wantMatched: "Space Odyssey", wantOK: true},
+		{query: "amelie", wantID: 2, wantMatched: "Amélie", wantOK: true},
+		{query: "jane do", wantID: 3, wantMatched: "Jane Doe", wantOK: true},
+		{query: "odessa", wantOK: false},
+	}
+
+	for _, test := range tests {
+		match, ok := index.Closest(test.query)
+		if ok != test.wantOK || match.ID != test.wantID || match.Matched != test.wantMatched {
+			t.Errorf("Closest(%q) = %+v, %v, want %v %q", test.query, match, ok, test.wantID, test.wantMatched)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 parameters, using the usual defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Hit is a document matching a query with its relevance.
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+// Index is an in-memory inverted index ranking documents with BM25. It is
+// safe for concurrent use.
+type Index struct {
+	mu          sync.RWMutex
+	postings    map[string]map[int]int
+	lengths     map[int]int
+	totalLength int
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: map[string]map[int]int{},
+		lengths:  map[int]int{},
+	}
+}
+
+// Add indexes the fields of document id, replacing what was indexed for it.
+func (i *Index) Add(id int, fields ...string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+
+	length := 0
+	for _, field := range fields {
+		for _, term := range Tokenize(field) {
+			docs, ok := i.postings[term]
+			if !ok {
+				docs = map[int]int{}
+				i.postings[term] = docs
+			}
+			docs[id]++
+			length++
+		}
+	}
+
+	i.lengths[id] = length
+	i.totalLength += length
+}
+
+func (i *Index) Remove(id int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.remove(id)
+}
+
+func (i *Index) remove(id int) {
+	length, ok := i.lengths[id]
+	if !ok {
+		return
+	}
+
+	for term, docs := range i.postings {
+		if _, ok := docs[id]; ok {
+			delete(docs, id)
+			if len(docs) == 0 {
+				delete(i.postings, term)
+			}
+		}
+	}
+
+	delete(i.lengths, id)
+	i.totalLength -= length
+}
+
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	return len(i.lengths)
+}
+
+// Search returns the documents containing any term of query, most relevant
+// first. Documents with the same score are ordered by ID.
+func (i *Index) Search(query string) []Hit {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if len(i.lengths) == 0 {
+		return nil
+	}
+
+	count := float64(len(i.lengths))
+	averageLength := float64(i.totalLength) / count
+	if averageLength == 0 {
+		averageLength = 1
+	}
+
+	scores := map[int]float64{}
+	seen := map[string]bool{}
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		docs := i.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+
+		df := float64(len(docs))
+		idf := math.Log(1 + (count-df+0.5)/(df+0.5))
+
+		for id, tf := range docs {
+			norm := 1 - bm25B + bm25B*float64(i.lengths[id])/averageLength
+			scores[id] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score != hits[b].Score {
+			return hits[a].Score > hits[b].Score
+		}
+		return hits[a].ID < hits[b].ID
+	})
+
+	return hits
+}
+
+// Tokenize splits text into lower case words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
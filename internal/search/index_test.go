@@ -0,0 +1,69 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("The Good, the Bad & the Ugly (1966)")
+	want := []string{"the", "good", "the", "bad", "the", "ugly", "1966"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %v, want %v", got, want)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	index := NewIndex()
+	index.Add(1, "Space Odyssey", "A voyage to Jupiter", "Drama")
+	index.Add(2, "Space Space Space", "Space adventure in space", "Action")
+	index.Add(3, "Garden Party", "A drama at a garden party", "Drama,Comedy")
+	index.Add(4, "Quiet Evening", "", "Drama")
+
+	ids := func(hits []Hit) []int {
+		var ids []int
+		for _, hit := range hits {
+			ids = append(ids, hit.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{name: "term frequency ranks higher", query: "space", want: []int{2, 1}},
+		{name: "rare terms outweigh common ones", query: "drama jupiter", want: []int{1, 4, 3}},
+		{name: "case and punctuation are ignored", query: "GARDEN-party!", want: []int{3}},
+		{name: "no match", query: "western", want: nil},
+		{name: "empty query", query: "  ", want: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hits := index.Search(test.query)
+			if got := ids(hits); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Search(%q) = %v, want %v", test.query, got, test.want)
+			}
+			for _, hit := range hits {
+				if hit.Score <= 0 {
+					t.Errorf("Search(%q) score of %v = %v, want > 0", test.query, hit.ID, hit.Score)
+				}
+			}
+		})
+	}
+
+	index.Add(2, "Garden Gnomes", "", "")
+	index.Remove(3)
+
+	if got := ids(index.Search("space")); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("Search() after Add = %v, want [1]", got)
+	}
+	if got := ids(index.Search("garden")); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Search() after Remove = %v, want [2]", got)
+	}
+	if index.Len() != 3 {
+		t.Errorf("Len() = %v, want 3", index.Len())
+	}
+}
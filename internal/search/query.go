@@ -0,0 +1,348 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Node is a node of a parsed query: And, Or, Not or Term.
+type Node interface {
+	node()
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	Node Node
+}
+
+// Term matches Value against Field, or against the default fields when
+// Field is empty. Op is a comparison (<, <=, >, >=, =) or empty.
+type Term struct {
+	Field    string
+	Op       string
+	Value    string
+	Pos      int
+	ValuePos int
+}
+
+func (And) node()  {}
+func (Or) node()   {}
+func (Not) node()  {}
+func (Term) node() {}
+
+// SyntaxError is an invalid query. Pos is the 1-based position of the
+// offending character.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func NewSyntaxError(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Msg)
+}
+
+func (e *SyntaxError) Position() int {
+	return e.Pos
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenColon
+	tokenMinus
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("%q", t.value)
+	default:
+		return "'" + t.value + "'"
+	}
+}
+
+// lex splits a query into tokens. A '-' or comparison operator only has a
+// meaning at the start of a token, so "sci-fi" stays one word.
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: pos})
+			i++
+		case r == ':':
+			tokens = append(tokens, token{kind: tokenColon, value: ":", pos: pos})
+			i++
+		case r == '-':
+			tokens = append(tokens, token{kind: tokenMinus, value: "-", pos: pos})
+			i++
+		case r == '<' || r == '>' || r == '=':
+			op := string(r)
+			i++
+			if r != '=' && i < len(runes) && runes[i] == '=' {
+				op += "="
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenOp, value: op, pos: pos})
+		case r == '"':
+			var value strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, NewSyntaxError(pos, "unterminated quoted string")
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					value.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value.String(), pos: pos})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`():"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			kind := tokenWord
+			switch word {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind: kind, value: word, pos: pos})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// Limits of a query. Parsing and evaluating a query recurse once per
+// nested group, negation and term, so both bound the stack they use.
+const (
+	MaxQueryLength = 1024
+	MaxQueryDepth  = 64
+)
+
+type queryParser struct {
+	tokens []token
+	next   int
+	depth  int
+}
+
+// ParseQuery parses a search query such as
+//
+//	genre:drama AND (artist:"Jane Doe" OR artist:kim) -genre:horror duration:<20
+//
+// Terms next to each other are combined with AND, which binds tighter than
+// OR. A term is negated by a leading '-' or NOT. Keywords are upper case;
+// quote a value to search for the word itself.
+func ParseQuery(input string) (Node, error) {
+	if length := len([]rune(input)); length > MaxQueryLength {
+		return nil, NewSyntaxError(MaxQueryLength+1, "query is longer than %d characters", MaxQueryLength)
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, NewSyntaxError(1, "query is empty")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, NewSyntaxError(tok.pos, "unexpected %s", tok)
+	}
+
+	return node, nil
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.advance()
+		case tokenWord, tokenString, tokenLParen, tokenMinus, tokenNot:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+// nest enters a group or negation starting at tok and fails if that nests
+// deeper than MaxQueryDepth. The caller leaves it with p.depth--.
+func (p *queryParser) nest(tok token) error {
+	p.depth++
+	if p.depth > MaxQueryDepth {
+		return NewSyntaxError(tok.pos, "query nests deeper than %d levels", MaxQueryDepth)
+	}
+	return nil
+}
+
+func (p *queryParser) parseUnary() (Node, error) {
+	switch p.peek().kind {
+	case tokenMinus, tokenNot:
+		if err := p.nest(p.advance()); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	default:
+		return p.parsePrimary()
+	}
+}
+
+func (p *queryParser) parsePrimary() (Node, error) {
+	tok := p.advance()
+
+	switch tok.kind {
+	case tokenLParen:
+		if err := p.nest(tok); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokenRParen {
+			if closing.kind == tokenEOF {
+				return nil, NewSyntaxError(tok.pos, "unclosed '('")
+			}
+			return nil, NewSyntaxError(closing.pos, "expected ')' but found %s", closing)
+		}
+		p.advance()
+		return node, nil
+	case tokenString:
+		return Term{Value: tok.value, Pos: tok.pos, ValuePos: tok.pos}, nil
+	case tokenWord:
+		if p.peek().kind != tokenColon {
+			return Term{Value: tok.value, Pos: tok.pos, ValuePos: tok.pos}, nil
+		}
+		colon := p.advance()
+
+		term := Term{Field: strings.ToLower(tok.value), Pos: tok.pos}
+		if p.peek().kind == tokenOp {
+			term.Op = p.advance().value
+		}
+
+		value := p.advance()
+		if value.kind != tokenWord && value.kind != tokenString {
+			pos := value.pos
+			if value.kind == tokenEOF {
+				pos = colon.pos + 1
+			}
+			return nil, NewSyntaxError(pos, "expected a value for %s but found %s", term.Field, value)
+		}
+		term.Value = value.value
+		term.ValuePos = value.pos
+		return term, nil
+	case tokenEOF:
+		return nil, NewSyntaxError(tok.pos, "unexpected end of query, expected a term")
+	default:
+		return nil, NewSyntaxError(tok.pos, "unexpected %s", tok)
+	}
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// format prints a node with explicit grouping.
+func format(node Node) string {
+	switch n := node.(type) {
+	case And:
+		return "(" + format(n.Left) + " AND " + format(n.Right) + ")"
+	case Or:
+		return "(" + format(n.Left) + " OR " + format(n.Right) + ")"
+	case Not:
+		return "-" + format(n.Node)
+	case Term:
+		value := n.Value
+		if strings.Contains(value, " ") {
+			value = fmt.Sprintf("%q", value)
+		}
+		if n.Field == "" {
+			return value
+		}
+		return n.Field + ":" + n.Op + value
+	default:
+		return "?"
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "example",
+			input: `genre:drama AND (artist:"Jane Doe" OR artist:kim) -genre:horror duration:<20`,
+			want:  `(((genre:drama AND (artist:"Jane Doe" OR artist:kim)) AND -genre:horror) AND duration:<20)`,
+		},
+		{name: "AND binds tighter than OR", input: "a b OR c AND d", want: "((a AND b) OR (c AND d))"},
+		{name: "NOT keyword", input: "NOT (genre:horror OR genre:thriller)", want: "-(genre:horror OR genre:thriller)"},
+		{name: "comparisons", input: "duration:>=90 duration:<=120 duration:=100", want: "((duration:>=90 AND duration:<=120) AND duration:=100)"},
+		{name: "hyphen inside words", input: "genre:sci-fi", want: "genre:sci-fi"},
+		{name: "field names are case insensitive", input: "Genre:Drama", want: "genre:Drama"},
+		{name: "lower case keywords are words", input: "war and peace", want: "((war AND and) AND peace)"},
+		{name: "quoted keyword", input: `"OR"`, want: "OR"},
+		{name: "deepest nesting", input: strings.Repeat("(", 64) + "a" + strings.Repeat(")", 64), want: "a"},
+		{name: "escaped quote", input: `title:"the \"best\" film"`, want: `title:"the \"best\" film"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, err := ParseQuery(test.input)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			if got := format(node); got != test.want {
+				t.Errorf("ParseQuery() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantPos int
+		wantMsg string
+	}{
+		{name: "empty", input: "   ", wantPos: 1, wantMsg: "query is empty"},
+		{name: "unclosed parenthesis", input: "a (b OR c", wantPos: 3, wantMsg: "unclosed '('"},
+		{name: "stray parenthesis", input: "a b)", wantPos: 4, wantMsg: "unexpected ')'"},
+		{name: "dangling OR", input: "genre:drama OR", wantPos: 15, wantMsg: "unexpected end of query, expected a term"},
+		{name: "missing value", input: "genre: OR a", wantPos: 8, wantMsg: "expected a value for genre but found 'OR'"},
+		{name: "missing value at end", input: "genre:", wantPos: 7, wantMsg: "expected a value for genre but found end of query"},
+		{name: "unterminated string", input: `artist:"Jane`, wantPos: 8, wantMsg: "unterminated quoted string"},
+		{name: "operator without field", input: "<20", wantPos: 1, wantMsg: "unexpected '<'"},
+		{name: "positions count characters", input: "título:é )", wantPos: 10, wantMsg: "unexpected ')'"},
+		{name: "too deep", input: strings.Repeat("(", 100) + "a" + strings.Repeat(")", 100), wantPos: 65, wantMsg: "query nests deeper than 64 levels"},
+		{name: "too deeply negated", input: strings.Repeat("-", 65) + "a", wantPos: 65, wantMsg: "query nests deeper than 64 levels"},
+		{name: "too long", input: strings.Repeat("(", 1_000_000), wantPos: 1025, wantMsg: "query is longer than 1024 characters"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseQuery(test.input)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseQuery() error = %v, want a SyntaxError", err)
+			}
+			if syntaxErr.Pos != test.wantPos || syntaxErr.Msg != test.wantMsg {
+				t.Errorf("ParseQuery() error = %v (%q), want position %v: %q", syntaxErr.Pos, syntaxErr.Msg, test.wantPos, test.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	store := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "uploads/a.mp4", strings.NewReader("movie a"), 7); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(ctx, "uploads/b.mp4", strings.NewReader("movie bb"), -1); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(ctx, "other/c.mp4", strings.NewReader("c"), 1); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	rc, err := store.Get(ctx, "uploads/a.mp4")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "movie a" {
+		t.Errorf("Get() content = %q, want %q", data, "movie a")
+	}
+
+	rc, err = store.GetRange(ctx, "uploads/a.mp4", 2, 3)
+	if err != nil {
+		t.Fatalf("GetRange() error = %v", err)
+	}
+	data, _ = io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "vie" {
+		t.Errorf("GetRange() content = %q, want %q", data, "vie")
+	}
+
+	info, err := store.Stat(ctx, "uploads/b.mp4")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 8 {
+		t.Errorf("Stat() size = %v, want 8", info.Size)
+	}
+
+	objects, err := store.List(ctx, "uploads/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 2 {
+		t.Errorf("List() got %v objects, want 2", len(objects))
+	}
+
+	if err := store.Delete(ctx, "uploads/a.mp4"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete(ctx, "uploads/a.mp4"); err != nil {
+		t.Errorf("Delete() of missing key error = %v, want nil", err)
+	}
+
+	if _, err := store.Stat(ctx, "uploads/a.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, "uploads/a.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStorageMove(t *testing.T) {
+	store := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "uploads/staging/a.mp4", strings.NewReader("movie a"), 7); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if err := store.Move(ctx, "uploads/staging/a.mp4", "films/a.mp4"); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+
+	if _, err := store.Stat(ctx, "uploads/staging/a.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() of source error = %v, want ErrNotFound", err)
+	}
+	if info, err := store.Stat(ctx, "films/a.mp4"); err != nil || info.Size != 7 {
+		t.Errorf("Stat() of destination = %v, %v, want size 7", info, err)
+	}
+
+	if err := store.Move(ctx, "uploads/staging/a.mp4", "films/b.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Move() of missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalStorageRejectsTraversal(t *testing.T) {
+	store := NewLocalStorage(t.TempDir())
+
+	for _, key := range []string{"../escape.mp4", "uploads/../../escape.mp4", "..", "/etc/passwd", "", "."} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put(%q) expected error for key outside root", key)
+		}
+	}
+
+	for _, key := range []string{"uploads/my..film.mp4", "uploads/..hidden.mp4", "uploads/staging/../film.mp4"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1); err != nil {
+			t.Errorf("Put(%q) error = %v", key, err)
+		}
+	}
+}
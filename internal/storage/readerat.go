@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+const readerAtBlockSize = 64 << 10
+
+type readerAt struct {
+	ctx   context.Context
+	store Storage
+	key   string
+	size  int64
+
+	blockOffset int64
+	block       []byte
+}
+
+// NewReaderAt exposes a stored object as an io.ReaderAt backed by ranged
+// reads. Reads are served from a small read-ahead block so parsers that walk
+// headers with many tiny reads don't issue one request per read.
+func NewReaderAt(ctx context.Context, store Storage, key string, size int64) io.ReaderAt {
+	return &readerAt{
+		ctx:   ctx,
+		store: store,
+		key:   key,
+		size:  size,
+	}
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+
+	if off >= r.size {
+		return 0, io.EOF
+	}
+
+	if off < r.blockOffset || off+int64(len(p)) > r.blockOffset+int64(len(r.block)) {
+		if err := r.fill(off, int64(len(p))); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.block[off-r.blockOffset:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (r *readerAt) fill(off, n int64) error {
+	length := max(n, readerAtBlockSize)
+	if off+length > r.size {
+		length = r.size - off
+	}
+
+	rc, err := r.store.GetRange(r.ctx, r.key, off, length)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(rc, block); err != nil {
+		return fmt.Errorf("failed to read object range: %w", err)
+	}
+
+	r.blockOffset = off
+	r.block = block
+
+	return nil
+}
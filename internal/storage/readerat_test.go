@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReaderAt(t *testing.T) {
+	store := NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+	content := "0123456789"
+	store.Put(ctx, "film.mp4", strings.NewReader(content), int64(len(content)))
+
+	r := NewReaderAt(ctx, store, "film.mp4", int64(len(content)))
+
+	buf := make([]byte, 3)
+	if n, err := r.ReadAt(buf, 4); err != nil || string(buf[:n]) != "456" {
+		t.Errorf("ReadAt(4) = %q, %v, want %q", buf[:n], err, "456")
+	}
+
+	if n, err := r.ReadAt(buf, 1); err != nil || string(buf[:n]) != "123" {
+		t.Errorf("ReadAt(1) = %q, %v, want %q", buf[:n], err, "123")
+	}
+
+	if n, err := r.ReadAt(buf, 8); err != io.EOF || string(buf[:n]) != "89" {
+		t.Errorf("ReadAt(8) = %q, %v, want %q and EOF", buf[:n], err, "89")
+	}
+
+	if _, err := r.ReadAt(buf, 10); err != io.EOF {
+		t.Errorf("ReadAt(10) error = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal in-memory stand-in for MinIO covering the subset of the
+// S3 API used by s3Storage.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	uploads map[string]map[int][]byte
+	nextID  int
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	return &fakeS3{
+		bucket:  bucket,
+		objects: map[string][]byte{},
+		uploads: map[string]map[int][]byte{},
+	}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+f.bucket)
+	key := strings.TrimPrefix(path, "/")
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && query.Get("list-type") == "2":
+		f.list(w, query.Get("prefix"), query.Get("continuation-token"))
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := strconv.Itoa(f.nextID)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		data, _ := io.ReadAll(r.Body)
+		f.uploads[query.Get("uploadId")][number] = data
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts := f.uploads[query.Get("uploadId")]
+		numbers := []int{}
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var buf bytes.Buffer
+		for _, number := range numbers {
+			buf.Write(parts[number])
+		}
+		f.objects[key] = buf.Bytes()
+		delete(f.uploads, query.Get("uploadId"))
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, _ := url.PathUnescape(strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/"+f.bucket+"/"))
+		data, ok := f.objects[source]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.objects[key] = append([]byte(nil), data...)
+		fmt.Fprint(w, "<CopyObjectResult></CopyObjectResult>")
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+			var start, end int
+			if n, _ := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); n < 2 {
+				end = len(data) - 1
+			}
+			data = data[start : end+1]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(f.objects[key])))
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix, token string) {
+	keys := []string{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > token {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key          string
+		Size         int
+		LastModified time.Time
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+
+	for i, key := range keys {
+		if i == 1 {
+			result.IsTruncated = true
+			result.NextContinuationToken = keys[0]
+			break
+		}
+		result.Contents = append(result.Contents, content{Key: key, Size: len(f.objects[key]), LastModified: time.Now().UTC()})
+	}
+
+	xml.NewEncoder(w).Encode(result)
+}
+
+func newTestS3Storage(t *testing.T, fake *fakeS3) *s3Storage {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Storage(S3Config{
+		Endpoint:     server.URL,
+		Bucket:       fake.bucket,
+		AccessKey:    "minioadmin",
+		SecretKey:    "minioadmin",
+		UsePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Storage() error = %v", err)
+	}
+
+	return store.(*s3Storage)
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := newFakeS3("films")
+	store := newTestS3Storage(t, fake)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "uploads/a b.mp4", strings.NewReader("movie a"), 7); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err := store.Put(ctx, "uploads/c.mp4", strings.NewReader("movie c"), 7); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if string(fake.objects["uploads/a b.mp4"]) != "movie a" {
+		t.Errorf("Put() stored %q, want %q", fake.objects["uploads/a b.mp4"], "movie a")
+	}
+
+	rc, err := store.Get(ctx, "uploads/a b.mp4")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "movie a" {
+		t.Errorf("Get() content = %q, want %q", data, "movie a")
+	}
+
+	for _, test := range []struct {
+		offset, length int64
+		want           string
+	}{
+		{offset: 2, length: 3, want: "vie"},
+		{offset: 6, length: -1, want: "a"},
+	} {
+		rc, err := store.GetRange(ctx, "uploads/a b.mp4", test.offset, test.length)
+		if err != nil {
+			t.Fatalf("GetRange() error = %v", err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != test.want {
+			t.Errorf("GetRange(%d, %d) content = %q, want %q", test.offset, test.length, data, test.want)
+		}
+	}
+
+	info, err := store.Stat(ctx, "uploads/c.mp4")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size != 7 {
+		t.Errorf("Stat() size = %v, want 7", info.Size)
+	}
+
+	objects, err := store.List(ctx, "uploads/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 2 {
+		t.Errorf("List() got %v objects, want 2", len(objects))
+	}
+
+	if err := store.Delete(ctx, "uploads/c.mp4"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := store.Stat(ctx, "uploads/c.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat() error = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Get(ctx, "uploads/c.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StorageMove(t *testing.T) {
+	fake := newFakeS3("films")
+	store := newTestS3Storage(t, fake)
+	ctx := context.Background()
+
+	fake.objects["uploads/staging/a b.mp4"] = []byte("movie a")
+
+	if err := store.Move(ctx, "uploads/staging/a b.mp4", "uploads/a b.mp4"); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+
+	if _, ok := fake.objects["uploads/staging/a b.mp4"]; ok {
+		t.Error("Move() left the source object")
+	}
+	if string(fake.objects["uploads/a b.mp4"]) != "movie a" {
+		t.Errorf("Move() stored %q, want %q", fake.objects["uploads/a b.mp4"], "movie a")
+	}
+
+	if err := store.Move(ctx, "uploads/staging/a b.mp4", "uploads/c.mp4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Move() of missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestS3StorageMultipartPut(t *testing.T) {
+	fake := newFakeS3("films")
+	store := newTestS3Storage(t, fake)
+	store.partSize = 4
+
+	content := "a feature length film"
+	if err := store.Put(context.Background(), "uploads/long.mkv", strings.NewReader(content), -1); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if string(fake.objects["uploads/long.mkv"]) != content {
+		t.Errorf("Put() stored %q, want %q", fake.objects["uploads/long.mkv"], content)
+	}
+
+	if len(fake.uploads) != 0 {
+		t.Errorf("Put() left %v multipart uploads open", len(fake.uploads))
+	}
+}
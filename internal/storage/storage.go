@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"roketin-case-study-challenge2/config"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage abstracts where uploaded media lives. Keys are slash separated and
+// relative to the backend root, e.g. "uploads/1700000000-film.mp4".
+type Storage interface {
+	// Put stores r under key. size may be -1 when the length is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetRange reads length bytes starting at offset. A negative length reads
+	// until the end of the object.
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Move renames src to dst, replacing dst if it exists.
+	Move(ctx context.Context, src, dst string) error
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+func New(cfg *config.AppConfig) (Storage, error) {
+	switch cfg.StorageDriver {
+	case "", config.StorageDriverLocal:
+		return NewLocalStorage(cfg.StorageLocalRoot), nil
+	case config.StorageDriverS3:
+		return NewS3Storage(S3Config{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.StorageDriver)
+	}
+}
@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		size    int64
+		want    []ByteRange
+		wantErr error
+	}{
+		{
+			name:   "single closed range",
+			header: "bytes=0-499",
+			size:   1000,
+			want:   []ByteRange{{Start: 0, Length: 500}},
+		},
+		{
+			name:   "open ended range",
+			header: "bytes=900-",
+			size:   1000,
+			want:   []ByteRange{{Start: 900, Length: 100}},
+		},
+		{
+			name:   "suffix range",
+			header: "bytes=-200",
+			size:   1000,
+			want:   []ByteRange{{Start: 800, Length: 200}},
+		},
+		{
+			name:   "suffix larger than representation",
+			header: "bytes=-2000",
+			size:   1000,
+			want:   []ByteRange{{Start: 0, Length: 1000}},
+		},
+		{
+			name:   "end clamped to size",
+			header: "bytes=500-5000",
+			size:   1000,
+			want:   []ByteRange{{Start: 500, Length: 500}},
+		},
+		{
+			name:   "multiple ranges with whitespace",
+			header: "bytes=0-9, 20-29 ,-5",
+			size:   100,
+			want:   []ByteRange{{Start: 0, Length: 10}, {Start: 20, Length: 10}, {Start: 95, Length: 5}},
+		},
+		{
+			name:   "unsatisfiable ranges are dropped",
+			header: "bytes=0-9,2000-3000",
+			size:   100,
+			want:   []ByteRange{{Start: 0, Length: 10}},
+		},
+		{
+			name:    "all ranges unsatisfiable",
+			header:  "bytes=1000-2000",
+			size:    1000,
+			wantErr: ErrUnsatisfiableRange,
+		},
+		{
+			name:    "unknown unit",
+			header:  "items=0-1",
+			size:    1000,
+			wantErr: ErrInvalidRange,
+		},
+		{
+			name:    "end before start",
+			header:  "bytes=10-5",
+			size:    1000,
+			wantErr: ErrInvalidRange,
+		},
+		{
+			name:    "not a number",
+			header:  "bytes=a-b",
+			size:    1000,
+			wantErr: ErrInvalidRange,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseRange(test.header, test.size)
+
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ParseRange() error = %v, want %v", err, test.wantErr)
+			}
+
+			if test.wantErr == nil && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseRange() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"roketin-case-study-challenge2/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRanges = 64
+
+var contentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".mov":  "video/quicktime",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+	".avi":  "video/x-msvideo",
+}
+
+func ContentTypeByExtension(name string) string {
+	if contentType, ok := contentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// ServeObject writes the stored object described by info to w honouring Range
+// and If-Range request headers. The response status has already been sent
+// when an error is returned, so callers can only log it.
+func ServeObject(w http.ResponseWriter, r *http.Request, store storage.Storage, info *storage.ObjectInfo) error {
+	ctx := r.Context()
+	key := info.Key
+	size := info.Size
+	etag := objectETag(info)
+	contentType := ContentTypeByExtension(key)
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("ETag", etag)
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+
+	rangeHeader := r.Header.Get("Range")
+	if rangeHeader != "" && !ifRangeMatches(r.Header.Get("If-Range"), etag, info.ModTime) {
+		rangeHeader = ""
+	}
+
+	if rangeHeader == "" {
+		return serveFull(ctx, w, r, store, key, size, contentType)
+	}
+
+	ranges, err := ParseRange(rangeHeader, size)
+	switch {
+	case errors.Is(err, ErrUnsatisfiableRange):
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return nil
+	case err != nil, len(ranges) > maxRanges, sumRangesSize(ranges) > size:
+		// Malformed or abusive range sets are ignored rather than rejected.
+		return serveFull(ctx, w, r, store, key, size, contentType)
+	}
+
+	if len(ranges) == 1 {
+		return serveSingleRange(ctx, w, r, store, key, size, contentType, ranges[0])
+	}
+
+	return serveMultiRange(ctx, w, r, store, key, size, contentType, ranges)
+}
+
+func serveFull(ctx context.Context, w http.ResponseWriter, r *http.Request, store storage.Storage, key string, size int64, contentType string) error {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	return copyRange(ctx, w, store, key, ByteRange{Start: 0, Length: size})
+}
+
+func serveSingleRange(ctx context.Context, w http.ResponseWriter, r *http.Request, store storage.Storage, key string, size int64, contentType string, byteRange ByteRange) error {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Range", byteRange.ContentRange(size))
+	w.Header().Set("Content-Length", strconv.FormatInt(byteRange.Length, 10))
+	w.WriteHeader(http.StatusPartialContent)
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	return copyRange(ctx, w, store, key, byteRange)
+}
+
+func serveMultiRange(ctx context.Context, w http.ResponseWriter, r *http.Request, store storage.Storage, key string, size int64, contentType string, ranges []ByteRange) error {
+	mw := multipart.NewWriter(w)
+
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	w.Header().Set("Content-Length", strconv.FormatInt(multipartLength(mw.Boundary(), size, contentType, ranges), 10))
+	w.WriteHeader(http.StatusPartialContent)
+
+	if r.Method == http.MethodHead {
+		return nil
+	}
+
+	for _, byteRange := range ranges {
+		part, err := mw.CreatePart(partHeader(contentType, byteRange, size))
+		if err != nil {
+			return err
+		}
+
+		if err := copyRange(ctx, part, store, key, byteRange); err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+func partHeader(contentType string, byteRange ByteRange, size int64) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Type":  {contentType},
+		"Content-Range": {byteRange.ContentRange(size)},
+	}
+}
+
+// multipartLength computes the exact body size of a multipart/byteranges
+// response by rendering the part headers without their content.
+func multipartLength(boundary string, size int64, contentType string, ranges []ByteRange) int64 {
+	var counter countingWriter
+	mw := multipart.NewWriter(&counter)
+	mw.SetBoundary(boundary)
+
+	var total int64
+	for _, byteRange := range ranges {
+		mw.CreatePart(partHeader(contentType, byteRange, size))
+		total += byteRange.Length
+	}
+	mw.Close()
+
+	return total + counter.n
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func copyRange(ctx context.Context, w io.Writer, store storage.Storage, key string, byteRange ByteRange) error {
+	rc, err := store.GetRange(ctx, key, byteRange.Start, byteRange.Length)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.CopyN(w, rc, byteRange.Length)
+	return err
+}
+
+// ifRangeMatches reports whether a Range header may be applied. An absent
+// If-Range always matches; an entity tag must match strongly and a date must
+// equal the last modification time exactly.
+func ifRangeMatches(ifRange, etag string, modTime time.Time) bool {
+	ifRange = strings.TrimSpace(ifRange)
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == etag
+	}
+
+	if strings.HasPrefix(ifRange, "W/") {
+		return false
+	}
+
+	date, err := http.ParseTime(ifRange)
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+
+	return modTime.UTC().Truncate(time.Second).Equal(date.UTC())
+}
+
+func objectETag(info *storage.ObjectInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime.UnixNano(), info.Size)
+}
@@ -0,0 +1,185 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"roketin-case-study-challenge2/internal/storage"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testContent = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func serveTestObject(t *testing.T, method string, headers map[string]string) (*httptest.ResponseRecorder, *storage.ObjectInfo) {
+	store := storage.NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "uploads/film.mkv", strings.NewReader(testContent), int64(len(testContent))); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := store.Stat(ctx, "uploads/film.mkv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, "/api/movies/1/stream", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	rr := httptest.NewRecorder()
+	if err := ServeObject(rr, req, store, info); err != nil {
+		t.Fatalf("ServeObject() error = %v", err)
+	}
+
+	return rr, info
+}
+
+func TestServeObject(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		headers          map[string]string
+		wantStatus       int
+		wantBody         string
+		wantContentRange string
+	}{
+		{
+			name:       "full content",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantBody:   testContent,
+		},
+		{
+			name:             "single range",
+			method:           http.MethodGet,
+			headers:          map[string]string{"Range": "bytes=10-15"},
+			wantStatus:       http.StatusPartialContent,
+			wantBody:         "abcdef",
+			wantContentRange: "bytes 10-15/36",
+		},
+		{
+			name:             "suffix range",
+			method:           http.MethodGet,
+			headers:          map[string]string{"Range": "bytes=-3"},
+			wantStatus:       http.StatusPartialContent,
+			wantBody:         "xyz",
+			wantContentRange: "bytes 33-35/36",
+		},
+		{
+			name:             "unsatisfiable range",
+			method:           http.MethodGet,
+			headers:          map[string]string{"Range": "bytes=100-200"},
+			wantStatus:       http.StatusRequestedRangeNotSatisfiable,
+			wantContentRange: "bytes */36",
+		},
+		{
+			name:       "malformed range is ignored",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Range": "bytes=oops"},
+			wantStatus: http.StatusOK,
+			wantBody:   testContent,
+		},
+		{
+			name:       "stale If-Range etag serves full content",
+			method:     http.MethodGet,
+			headers:    map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`},
+			wantStatus: http.StatusOK,
+			wantBody:   testContent,
+		},
+		{
+			name:             "head single range",
+			method:           http.MethodHead,
+			headers:          map[string]string{"Range": "bytes=0-9"},
+			wantStatus:       http.StatusPartialContent,
+			wantContentRange: "bytes 0-9/36",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr, _ := serveTestObject(t, test.method, test.headers)
+
+			if rr.Code != test.wantStatus {
+				t.Errorf("ServeObject() status = %v, want %v", rr.Code, test.wantStatus)
+			}
+
+			if test.wantStatus != http.StatusRequestedRangeNotSatisfiable && rr.Body.String() != test.wantBody {
+				t.Errorf("ServeObject() body = %q, want %q", rr.Body.String(), test.wantBody)
+			}
+
+			if got := rr.Header().Get("Content-Range"); got != test.wantContentRange {
+				t.Errorf("ServeObject() Content-Range = %q, want %q", got, test.wantContentRange)
+			}
+
+			if test.wantStatus != http.StatusRequestedRangeNotSatisfiable && rr.Header().Get("Content-Type") != "video/x-matroska" {
+				t.Errorf("ServeObject() Content-Type = %q, want video/x-matroska", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestServeObjectIfRangeMatches(t *testing.T) {
+	store := storage.NewLocalStorage(t.TempDir())
+	ctx := context.Background()
+	store.Put(ctx, "film.mp4", strings.NewReader(testContent), -1)
+	info, _ := store.Stat(ctx, "film.mp4")
+
+	for _, ifRange := range []string{objectETag(info), info.ModTime.UTC().Format(http.TimeFormat)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Range", "bytes=0-1")
+		req.Header.Set("If-Range", ifRange)
+
+		rr := httptest.NewRecorder()
+		ServeObject(rr, req, store, info)
+
+		if rr.Code != http.StatusPartialContent || rr.Body.String() != "01" {
+			t.Errorf("ServeObject() with If-Range %q status = %v, body = %q", ifRange, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestServeObjectMultiRange(t *testing.T) {
+	rr, _ := serveTestObject(t, http.MethodGet, map[string]string{"Range": "bytes=0-2,-3"})
+
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("ServeObject() status = %v, want %v", rr.Code, http.StatusPartialContent)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("ServeObject() Content-Type = %q, want multipart/byteranges", rr.Header().Get("Content-Type"))
+	}
+
+	if got := rr.Header().Get("Content-Length"); got != strconv.Itoa(rr.Body.Len()) {
+		t.Errorf("ServeObject() Content-Length = %v, body length %v", got, rr.Body.Len())
+	}
+
+	reader := multipart.NewReader(rr.Body, params["boundary"])
+	want := []struct{ contentRange, body string }{
+		{"bytes 0-2/36", "012"},
+		{"bytes 33-35/36", "xyz"},
+	}
+
+	for _, w := range want {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+
+		body, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Range") != w.contentRange || string(body) != w.body {
+			t.Errorf("part = %q %q, want %q %q", part.Header.Get("Content-Range"), body, w.contentRange, w.body)
+		}
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("NextPart() error = %v, want EOF", err)
+	}
+}
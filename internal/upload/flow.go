@@ -0,0 +1,272 @@
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/storage"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUploadNotFound   = errors.New("upload not found")
+	ErrInvalidLength    = errors.New("upload length must not be negative")
+	ErrInvalidMetadata  = errors.New("invalid upload metadata")
+	ErrUploadExpired    = errors.New("upload has expired")
+	ErrUploadIncomplete = errors.New("upload is not complete")
+	ErrUploadTooLarge   = errors.New("upload exceeds the maximum size")
+	ErrOffsetMismatch   = errors.New("upload offset does not match")
+	ErrChunkTooLarge    = errors.New("chunk exceeds the upload length")
+)
+
+type UploadFlowInterface interface {
+	CreateUpload(ctx context.Context, length int64, metadata string) (*entity.Upload, error)
+	GetUpload(ctx context.Context, id string) (*entity.Upload, error)
+	WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (*entity.Upload, error)
+	TerminateUpload(ctx context.Context, id string) error
+	ClaimUpload(ctx context.Context, id string, baseUploadPath string) (string, error)
+	CleanupExpired(ctx context.Context) (int, error)
+	MaxSize() int64
+}
+
+// UploadConfig.Dir holds the partial uploads on the local disk, also when
+// finished videos go to remote storage: tus appends to them chunk by chunk,
+// which object stores cannot do. Several instances must share the directory
+// or route each upload to the same instance.
+type UploadConfig struct {
+	Dir        string
+	MaxSize    int64
+	Expiration time.Duration
+}
+
+type uploadFlow struct {
+	uploadRepo UploadRepository
+	storage    storage.Storage
+	cfg        UploadConfig
+	locks      sync.Map
+	now        func() time.Time
+}
+
+func NewUploadFlow(uploadRepo UploadRepository, storage storage.Storage, cfg UploadConfig) UploadFlowInterface {
+	return &uploadFlow{
+		uploadRepo: uploadRepo,
+		storage:    storage,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+func (f *uploadFlow) MaxSize() int64 {
+	return f.cfg.MaxSize
+}
+
+func (f *uploadFlow) CreateUpload(ctx context.Context, length int64, metadata string) (*entity.Upload, error) {
+	if length < 0 {
+		return nil, ErrInvalidLength
+	}
+
+	if f.cfg.MaxSize > 0 && length > f.cfg.MaxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	if _, err := ParseMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(f.cfg.Dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	file, err := os.Create(f.partialPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	file.Close()
+
+	currentTime := f.now()
+	upload := &entity.Upload{
+		ID:        id,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: currentTime.Add(f.cfg.Expiration),
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+	}
+
+	createdUpload, err := f.uploadRepo.CreateUpload(ctx, upload)
+	if err != nil {
+		os.Remove(f.partialPath(id))
+		return nil, err
+	}
+
+	return createdUpload, nil
+}
+
+func (f *uploadFlow) GetUpload(ctx context.Context, id string) (*entity.Upload, error) {
+	upload, err := f.uploadRepo.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.IsExpired(f.now()) {
+		return nil, ErrUploadExpired
+	}
+
+	return upload, nil
+}
+
+func (f *uploadFlow) WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (*entity.Upload, error) {
+	unlock := f.lock(id)
+	defer unlock()
+
+	upload, err := f.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset != upload.Offset {
+		return nil, ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(f.partialPath(id), os.O_WRONLY|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	// Drop bytes written after the last persisted offset, e.g. by a request
+	// that was interrupted before its offset could be saved.
+	if err := file.Truncate(upload.Offset); err != nil {
+		return nil, fmt.Errorf("failed to truncate upload file: %w", err)
+	}
+
+	if _, err := file.Seek(upload.Offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek upload file: %w", err)
+	}
+
+	written, copyErr := io.Copy(file, io.LimitReader(r, upload.Length-upload.Offset))
+	if err := file.Sync(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	// The offset is saved even when the client went away, since that is the
+	// request the next one resumes from.
+	upload.Offset += written
+	upload.ExpiresAt = f.now().Add(f.cfg.Expiration)
+	if err := f.uploadRepo.UpdateUploadOffset(context.WithoutCancel(ctx), id, upload.Offset, upload.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	if copyErr != nil {
+		return upload, fmt.Errorf("failed to write upload chunk: %w", copyErr)
+	}
+
+	if upload.IsComplete() {
+		if n, _ := r.Read(make([]byte, 1)); n > 0 {
+			return upload, ErrChunkTooLarge
+		}
+	}
+
+	return upload, nil
+}
+
+func (f *uploadFlow) TerminateUpload(ctx context.Context, id string) error {
+	unlock := f.lock(id)
+	defer unlock()
+
+	if err := f.uploadRepo.DeleteUpload(ctx, id); err != nil {
+		return err
+	}
+
+	f.locks.Delete(id)
+	if err := os.Remove(f.partialPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove upload file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *uploadFlow) ClaimUpload(ctx context.Context, id string, baseUploadPath string) (string, error) {
+	unlock := f.lock(id)
+	defer unlock()
+
+	upload, err := f.GetUpload(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if !upload.IsComplete() {
+		return "", ErrUploadIncomplete
+	}
+
+	file, err := os.Open(f.partialPath(id))
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	key := path.Join(baseUploadPath, fmt.Sprintf("%d-%s", f.now().UnixNano(), FileName(upload)))
+	if err := f.storage.Put(ctx, key, file, upload.Length); err != nil {
+		return "", fmt.Errorf("failed to store upload: %w", err)
+	}
+
+	if err := f.uploadRepo.DeleteUpload(ctx, id); err != nil {
+		f.storage.Delete(context.WithoutCancel(ctx), key)
+		return "", err
+	}
+
+	f.locks.Delete(id)
+	os.Remove(f.partialPath(id))
+
+	return key, nil
+}
+
+func (f *uploadFlow) CleanupExpired(ctx context.Context) (int, error) {
+	uploads, err := f.uploadRepo.ListExpiredUploads(ctx, f.now())
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, upload := range uploads {
+		if err := f.TerminateUpload(ctx, upload.ID); err != nil && !errors.Is(err, ErrUploadNotFound) {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+func (f *uploadFlow) lock(id string) func() {
+	value, _ := f.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func (f *uploadFlow) partialPath(id string) string {
+	return filepath.Join(f.cfg.Dir, filepath.Base(id))
+}
+
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,296 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"roketin-case-study-challenge2/internal/entity"
+	"roketin-case-study-challenge2/internal/storage"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockUploadRepository struct {
+	uploads map[string]entity.Upload
+	err     error
+}
+
+func NewMockUploadRepository() *MockUploadRepository {
+	return &MockUploadRepository{
+		uploads: map[string]entity.Upload{},
+	}
+}
+
+func (m *MockUploadRepository) CreateUpload(ctx context.Context, upload *entity.Upload) (*entity.Upload, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.uploads[upload.ID] = *upload
+	return upload, nil
+}
+
+func (m *MockUploadRepository) GetUpload(ctx context.Context, id string) (*entity.Upload, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	upload, ok := m.uploads[id]
+	if !ok {
+		return nil, ErrUploadNotFound
+	}
+	return &upload, nil
+}
+
+func (m *MockUploadRepository) UpdateUploadOffset(ctx context.Context, id string, offset int64, expiresAt time.Time) error {
+	if m.err != nil {
+		return m.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	upload, ok := m.uploads[id]
+	if !ok {
+		return ErrUploadNotFound
+	}
+	upload.Offset = offset
+	upload.ExpiresAt = expiresAt
+	m.uploads[id] = upload
+	return nil
+}
+
+func (m *MockUploadRepository) DeleteUpload(ctx context.Context, id string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	if _, ok := m.uploads[id]; !ok {
+		return ErrUploadNotFound
+	}
+	delete(m.uploads, id)
+	return nil
+}
+
+func (m *MockUploadRepository) ListExpiredUploads(ctx context.Context, before time.Time) ([]entity.Upload, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	expired := []entity.Upload{}
+	for _, upload := range m.uploads {
+		if upload.ExpiresAt.Before(before) {
+			expired = append(expired, upload)
+		}
+	}
+	return expired, nil
+}
+
+func newTestUploadFlow(t *testing.T, repo UploadRepository) (*uploadFlow, storage.Storage) {
+	store := storage.NewLocalStorage(t.TempDir())
+	flow := NewUploadFlow(repo, store, UploadConfig{
+		Dir:        t.TempDir(),
+		MaxSize:    100,
+		Expiration: time.Hour,
+	})
+
+	return flow.(*uploadFlow), store
+}
+
+func TestUploadFlowResumesAndClaims(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockUploadRepository()
+	flow, store := newTestUploadFlow(t, repo)
+
+	upload, err := flow.CreateUpload(ctx, 11, "filename ZmlsbS5tcDQ=")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	upload, err = flow.WriteChunk(ctx, upload.ID, 0, strings.NewReader("hello "))
+	if err != nil {
+		t.Fatalf("WriteChunk() error = %v", err)
+	}
+	if upload.Offset != 6 {
+		t.Errorf("WriteChunk() offset = %v, want 6", upload.Offset)
+	}
+
+	if _, err := flow.WriteChunk(ctx, upload.ID, 3, strings.NewReader("xx")); !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("WriteChunk() error = %v, want ErrOffsetMismatch", err)
+	}
+
+	if _, err := flow.ClaimUpload(ctx, upload.ID, "uploads"); !errors.Is(err, ErrUploadIncomplete) {
+		t.Errorf("ClaimUpload() error = %v, want ErrUploadIncomplete", err)
+	}
+
+	// A fresh flow over the same repository and directory simulates a restart.
+	restarted := NewUploadFlow(repo, store, flow.cfg)
+
+	upload, err = restarted.WriteChunk(ctx, upload.ID, 6, strings.NewReader("world"))
+	if err != nil {
+		t.Fatalf("WriteChunk() after restart error = %v", err)
+	}
+	if !upload.IsComplete() {
+		t.Errorf("WriteChunk() offset = %v, want complete upload of %v", upload.Offset, upload.Length)
+	}
+
+	key, err := restarted.ClaimUpload(ctx, upload.ID, "uploads")
+	if err != nil {
+		t.Fatalf("ClaimUpload() error = %v", err)
+	}
+	if !strings.HasPrefix(key, "uploads/") || !strings.HasSuffix(key, "-film.mp4") {
+		t.Errorf("ClaimUpload() key = %v, want uploads/<timestamp>-film.mp4", key)
+	}
+
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello world" {
+		t.Errorf("claimed content = %q, want %q", data, "hello world")
+	}
+
+	if _, err := restarted.GetUpload(ctx, upload.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("GetUpload() after claim error = %v, want ErrUploadNotFound", err)
+	}
+}
+
+// disconnectingReader returns data, then cancels the request context and
+// fails, as the body of a request whose client went away.
+type disconnectingReader struct {
+	data   []byte
+	cancel context.CancelFunc
+}
+
+func (r *disconnectingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		r.cancel()
+		return 0, errors.New("connection reset by peer")
+	}
+
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestUploadFlowSavesOffsetOfInterruptedChunk(t *testing.T) {
+	repo := NewMockUploadRepository()
+	flow, store := newTestUploadFlow(t, repo)
+
+	upload, err := flow.CreateUpload(context.Background(), 11, "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err = flow.WriteChunk(ctx, upload.ID, 0, &disconnectingReader{data: []byte("hello"), cancel: cancel})
+	if err == nil {
+		t.Fatal("WriteChunk() expected error for interrupted body")
+	}
+
+	if offset := repo.uploads[upload.ID].Offset; offset != 5 {
+		t.Fatalf("stored offset = %d, want 5", offset)
+	}
+
+	resumed, err := flow.WriteChunk(context.Background(), upload.ID, 5, strings.NewReader(" world"))
+	if err != nil {
+		t.Fatalf("WriteChunk() resumed error = %v", err)
+	}
+	if !resumed.IsComplete() {
+		t.Errorf("resumed upload offset = %d, want complete", resumed.Offset)
+	}
+
+	key, err := flow.ClaimUpload(context.Background(), upload.ID, "uploads")
+	if err != nil {
+		t.Fatalf("ClaimUpload() error = %v", err)
+	}
+	reader, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer reader.Close()
+	data, _ := io.ReadAll(reader)
+	if string(data) != "hello world" {
+		t.Errorf("claimed content = %q, want %q", data, "hello world")
+	}
+}
+
+func TestUploadFlowCreateUpload(t *testing.T) {
+	tests := []struct {
+		name     string
+		length   int64
+		metadata string
+		wantErr  error
+	}{
+		{
+			name:   "success create upload",
+			length: 10,
+		},
+		{
+			name:    "fail create upload - too large",
+			length:  101,
+			wantErr: ErrUploadTooLarge,
+		},
+		{
+			name:     "fail create upload - invalid metadata",
+			length:   10,
+			metadata: "filename !!!",
+			wantErr:  errors.New("invalid upload metadata value for filename"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flow, _ := newTestUploadFlow(t, NewMockUploadRepository())
+
+			upload, err := flow.CreateUpload(context.Background(), test.length, test.metadata)
+
+			if test.wantErr != nil {
+				if err == nil || err.Error() != test.wantErr.Error() {
+					t.Errorf("CreateUpload() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("CreateUpload() error = %v", err)
+			}
+			if upload.ID == "" {
+				t.Error("CreateUpload() returned empty ID")
+			}
+		})
+	}
+}
+
+func TestUploadFlowExpiration(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockUploadRepository()
+	flow, _ := newTestUploadFlow(t, repo)
+
+	upload, err := flow.CreateUpload(ctx, 10, "")
+	if err != nil {
+		t.Fatalf("CreateUpload() error = %v", err)
+	}
+
+	flow.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	if _, err := flow.WriteChunk(ctx, upload.ID, 0, strings.NewReader("data")); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("WriteChunk() error = %v, want ErrUploadExpired", err)
+	}
+
+	removed, err := flow.CleanupExpired(ctx)
+	if err != nil {
+		t.Fatalf("CleanupExpired() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("CleanupExpired() removed = %v, want 1", removed)
+	}
+	if len(repo.uploads) != 0 {
+		t.Errorf("CleanupExpired() left %v uploads", len(repo.uploads))
+	}
+}
@@ -0,0 +1,181 @@
+package upload
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"roketin-case-study-challenge2/internal/constant"
+	"roketin-case-study-challenge2/internal/response"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+const (
+	TusResumable  = "1.0.0"
+	TusExtensions = "creation,termination,expiration"
+
+	offsetOctetStream = "application/offset+octet-stream"
+)
+
+// UploadHandler implements the tus 1.0 resumable upload protocol with the
+// creation, termination and expiration extensions.
+type UploadHandler struct {
+	uploadFlow UploadFlowInterface
+}
+
+func NewUploadHandler(uploadFlow UploadFlowInterface) *UploadHandler {
+	return &UploadHandler{
+		uploadFlow: uploadFlow,
+	}
+}
+
+func (h *UploadHandler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Options("/", h.Options)
+	r.Options("/{id}", h.Options)
+
+	r.Group(func(r chi.Router) {
+		r.Use(requireTusResumable)
+
+		// A chunk takes as long to write as it takes to send.
+		r.Patch("/{id}", h.WriteChunk)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Timeout(constant.REQUEST_TIMEOUT))
+
+			r.Post("/", h.CreateUpload)
+			r.Head("/{id}", h.GetUploadStatus)
+			r.Delete("/{id}", h.TerminateUpload)
+		})
+	})
+
+	return r
+}
+
+func requireTusResumable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", TusResumable)
+
+		if r.Header.Get("Tus-Resumable") != TusResumable {
+			w.Header().Set("Tus-Version", TusResumable)
+			response.Error(w, http.StatusPreconditionFailed, "unsupported tus version")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *UploadHandler) Options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusResumable)
+	w.Header().Set("Tus-Version", TusResumable)
+	w.Header().Set("Tus-Extension", TusExtensions)
+	if maxSize := h.uploadFlow.MaxSize(); maxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		response.Error(w, http.StatusBadRequest, "deferred upload length is not supported")
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		response.Error(w, http.StatusBadRequest, "invalid Upload-Length header")
+		return
+	}
+
+	upload, err := h.uploadFlow.CreateUpload(ctx, length, r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		response.Error(w, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Location", path.Join(r.URL.Path, upload.ID))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *UploadHandler) GetUploadStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	upload, err := h.uploadFlow.GetUpload(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(uploadErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *UploadHandler) WriteChunk(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), offsetOctetStream) {
+		response.Error(w, http.StatusUnsupportedMediaType, "content type must be "+offsetOctetStream)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.Error(w, http.StatusBadRequest, "invalid Upload-Offset header")
+		return
+	}
+
+	upload, err := h.uploadFlow.WriteChunk(ctx, chi.URLParam(r, "id"), offset, r.Body)
+	if err != nil {
+		response.Error(w, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UploadHandler) TerminateUpload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := h.uploadFlow.TerminateUpload(ctx, chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, uploadErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, ErrOffsetMismatch):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidLength), errors.Is(err, ErrInvalidMetadata):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUploadTooLarge), errors.Is(err, ErrChunkTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
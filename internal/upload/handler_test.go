@@ -0,0 +1,169 @@
+package upload
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func TestUploadHandlerProtocol(t *testing.T) {
+	flow, _ := newTestUploadFlow(t, NewMockUploadRepository())
+	server := httptest.NewServer(NewUploadHandler(flow).Routes())
+	defer server.Close()
+
+	do := func(method, path string, headers map[string]string, body string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do(http.MethodOptions, "/", nil, "")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Tus-Extension") != TusExtensions {
+		t.Errorf("OPTIONS status = %v, extensions = %q", resp.StatusCode, resp.Header.Get("Tus-Extension"))
+	}
+
+	resp = do(http.MethodPost, "/", map[string]string{"Upload-Length": "10"}, "")
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("POST without Tus-Resumable status = %v, want %v", resp.StatusCode, http.StatusPreconditionFailed)
+	}
+
+	resp = do(http.MethodPost, "/", map[string]string{"Tus-Resumable": TusResumable, "Upload-Length": "10"}, "")
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST status = %v, want %v", resp.StatusCode, http.StatusCreated)
+	}
+	location := resp.Header.Get("Location")
+	if location == "" || resp.Header.Get("Upload-Expires") == "" {
+		t.Fatalf("POST missing Location or Upload-Expires headers")
+	}
+
+	patch := func(offset, body string) *http.Response {
+		return do(http.MethodPatch, location, map[string]string{
+			"Tus-Resumable": TusResumable,
+			"Content-Type":  offsetOctetStream,
+			"Upload-Offset": offset,
+		}, body)
+	}
+
+	resp = patch("0", "01234")
+	if resp.StatusCode != http.StatusNoContent || resp.Header.Get("Upload-Offset") != "5" {
+		t.Errorf("PATCH status = %v, offset = %q", resp.StatusCode, resp.Header.Get("Upload-Offset"))
+	}
+
+	resp = patch("2", "xx")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("PATCH with stale offset status = %v, want %v", resp.StatusCode, http.StatusConflict)
+	}
+
+	resp = do(http.MethodHead, location, map[string]string{"Tus-Resumable": TusResumable}, "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Upload-Offset") != "5" || resp.Header.Get("Upload-Length") != "10" {
+		t.Errorf("HEAD status = %v, offset = %q, length = %q", resp.StatusCode, resp.Header.Get("Upload-Offset"), resp.Header.Get("Upload-Length"))
+	}
+	if resp.Header.Get("Cache-Control") != "no-store" {
+		t.Errorf("HEAD Cache-Control = %q, want no-store", resp.Header.Get("Cache-Control"))
+	}
+
+	resp = do(http.MethodPatch, location, map[string]string{"Tus-Resumable": TusResumable, "Upload-Offset": "5"}, "56789")
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("PATCH without content type status = %v, want %v", resp.StatusCode, http.StatusUnsupportedMediaType)
+	}
+
+	resp = do(http.MethodDelete, location, map[string]string{"Tus-Resumable": TusResumable}, "")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("DELETE status = %v, want %v", resp.StatusCode, http.StatusNoContent)
+	}
+
+	resp = do(http.MethodHead, location, map[string]string{"Tus-Resumable": TusResumable}, "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("HEAD after DELETE status = %v, want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestCreateUploadHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		repoErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success create upload",
+			headers:    map[string]string{"Upload-Length": "10"},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "fail create upload - invalid metadata",
+			headers:    map[string]string{"Upload-Length": "10", "Upload-Metadata": "filename !!!"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "fail create upload - too large",
+			headers:    map[string]string{"Upload-Length": "101"},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "fail create upload - database error",
+			headers:    map[string]string{"Upload-Length": "10"},
+			repoErr:    errors.New("database unavailable"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := NewMockUploadRepository()
+			repo.err = test.repoErr
+			flow, _ := newTestUploadFlow(t, repo)
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			req.Header.Set("Tus-Resumable", TusResumable)
+			for key, value := range test.headers {
+				req.Header.Set(key, value)
+			}
+			rr := httptest.NewRecorder()
+
+			NewUploadHandler(flow).Routes().ServeHTTP(rr, req)
+
+			if rr.Code != test.wantStatus {
+				t.Errorf("status = %v, want %v: %s", rr.Code, test.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUploadRoutesTimeout(t *testing.T) {
+	flow, _ := newTestUploadFlow(t, NewMockUploadRepository())
+
+	// Every tus route requires Tus-Resumable; all but PATCH also time out.
+	want := map[string]int{
+		"OPTIONS /":     0,
+		"OPTIONS /{id}": 0,
+		"POST /":        2,
+		"HEAD /{id}":    2,
+		"PATCH /{id}":   1,
+		"DELETE /{id}":  2,
+	}
+
+	err := chi.Walk(NewUploadHandler(flow).Routes(), func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		if got := len(middlewares); got != want[method+" "+route] {
+			t.Errorf("route %s %s has %d middlewares, want %d", method, route, got, want[method+" "+route])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+}
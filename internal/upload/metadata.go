@@ -0,0 +1,47 @@
+package upload
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"roketin-case-study-challenge2/internal/entity"
+	"strings"
+)
+
+// ParseMetadata decodes a tus Upload-Metadata header: comma separated pairs of
+// a key and an optional base64 encoded value.
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("%w pair: %q", ErrInvalidMetadata, pair)
+		}
+
+		value := ""
+		if len(parts) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w value for %s", ErrInvalidMetadata, parts[0])
+			}
+			value = string(decoded)
+		}
+
+		metadata[parts[0]] = value
+	}
+
+	return metadata, nil
+}
+
+func FileName(upload *entity.Upload) string {
+	metadata, err := ParseMetadata(upload.Metadata)
+	if err != nil || metadata["filename"] == "" {
+		return upload.ID
+	}
+
+	return filepath.Base(metadata["filename"])
+}
@@ -0,0 +1,15 @@
+package upload
+
+import (
+	"context"
+	"roketin-case-study-challenge2/internal/entity"
+	"time"
+)
+
+type UploadRepository interface {
+	CreateUpload(ctx context.Context, upload *entity.Upload) (*entity.Upload, error)
+	GetUpload(ctx context.Context, id string) (*entity.Upload, error)
+	UpdateUploadOffset(ctx context.Context, id string, offset int64, expiresAt time.Time) error
+	DeleteUpload(ctx context.Context, id string) error
+	ListExpiredUploads(ctx context.Context, before time.Time) ([]entity.Upload, error)
+}
@@ -0,0 +1,82 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"roketin-case-study-challenge2/internal/entity"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type mySQLUploadRepository struct {
+	db *gorm.DB
+}
+
+func NewMySQLUploadRepository(db *gorm.DB) UploadRepository {
+	return &mySQLUploadRepository{
+		db: db,
+	}
+}
+
+func (r *mySQLUploadRepository) CreateUpload(ctx context.Context, upload *entity.Upload) (*entity.Upload, error) {
+	result := r.db.WithContext(ctx).Create(upload)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", result.Error)
+	}
+
+	return upload, nil
+}
+
+func (r *mySQLUploadRepository) GetUpload(ctx context.Context, id string) (*entity.Upload, error) {
+	var upload entity.Upload
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&upload).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	return &upload, nil
+}
+
+func (r *mySQLUploadRepository) UpdateUploadOffset(ctx context.Context, id string, offset int64, expiresAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&entity.Upload{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"offset":     offset,
+		"expires_at": expiresAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update upload offset: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUploadNotFound
+	}
+
+	return nil
+}
+
+func (r *mySQLUploadRepository) DeleteUpload(ctx context.Context, id string) error {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&entity.Upload{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete upload: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrUploadNotFound
+	}
+
+	return nil
+}
+
+func (r *mySQLUploadRepository) ListExpiredUploads(ctx context.Context, before time.Time) ([]entity.Upload, error) {
+	var uploads []entity.Upload
+	err := r.db.WithContext(ctx).Where("expires_at < ?", before).Find(&uploads).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired uploads: %w", err)
+	}
+
+	return uploads, nil
+}
@@ -1,16 +1,17 @@
 package internal
 
 import (
+	"context"
 	"fmt"
-	"io"
 	"mime/multipart"
-	"os"
+	"path"
 	"path/filepath"
+	"roketin-case-study-challenge2/internal/storage"
 	"strings"
 	"time"
 )
 
-func SaveUploadedFile(file *multipart.FileHeader, baseUploadPath string) (string, error) {
+func SaveUploadedFile(ctx context.Context, store storage.Storage, file *multipart.FileHeader, baseUploadPath string) (string, error) {
 	if file == nil {
 		return "", fmt.Errorf("file cannot be nil")
 	}
@@ -21,25 +22,15 @@ func SaveUploadedFile(file *multipart.FileHeader, baseUploadPath string) (string
 	}
 	defer src.Close()
 
-	uniqueFileName := fmt.Sprintf("%d-%s", time.Now().UnixNano(), file.Filename)
+	uniqueFileName := fmt.Sprintf("%d-%s", time.Now().UnixNano(), filepath.Base(file.Filename))
 
-	filePath := filepath.Join(baseUploadPath, uniqueFileName)
+	key := path.Join(baseUploadPath, uniqueFileName)
 
-	if err := os.MkdirAll(baseUploadPath, 0750); err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+	if err := store.Put(ctx, key, src, file.Size); err != nil {
+		return "", fmt.Errorf("failed to store file: %w", err)
 	}
 
-	dst, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file: %w", err)
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, src); err != nil {
-		return "", fmt.Errorf("failed to copy file: %w", err)
-	}
-
-	return filePath, nil
+	return key, nil
 }
 
 func CleanCsvString(input string) string {
@@ -59,4 +50,4 @@ func CleanCsvString(input string) string {
 		}
 	}
 	return strings.Join(finalParts, ",")
-}
\ No newline at end of file
+}
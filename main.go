@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"roketin-case-study-challenge2/config"
+	"roketin-case-study-challenge2/internal/auth"
+	"roketin-case-study-challenge2/internal/constant"
 	"roketin-case-study-challenge2/internal/database"
+	"roketin-case-study-challenge2/internal/genre"
 	"roketin-case-study-challenge2/internal/movie"
+	"roketin-case-study-challenge2/internal/person"
+	"roketin-case-study-challenge2/internal/reconcile"
+	"roketin-case-study-challenge2/internal/storage"
+	"roketin-case-study-challenge2/internal/upload"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -26,19 +36,106 @@ func main() {
 
 	fmt.Println("MySQL database initialized successfully")
 
+	migrated, err := genre.MigrateMovieGenres(context.Background(), db)
+	if err != nil {
+		log.Fatalf("Failed to migrate movie genres: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Linked genres of %d movies", migrated)
+	}
+
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	referenceRepo := reconcile.NewMySQLReferenceRepository(db)
+	reconcileFlow := reconcile.NewReconcileFlow(referenceRepo, store, reconcile.ReconcileConfig{
+		Prefix:           constant.MOVIE_UPLOAD_PATH,
+		QuarantinePrefix: constant.MOVIE_QUARANTINE_PATH,
+		GracePeriod:      cfg.ReconcileGracePeriod,
+	})
+
+	// "go run main.go reconcile [-mode report|quarantine|delete]" runs the
+	// reconciler once instead of starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(runReconcileCommand(reconcileFlow, os.Args[2:]))
+	}
+
+	if cfg.ReconcileInterval > 0 {
+		go runReconcile(reconcileFlow, reconcile.Mode(cfg.ReconcileMode), cfg.ReconcileInterval)
+	}
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+
+	userRepo := auth.NewMySQLUserRepository(db)
+	tokenManager := auth.NewTokenManager(auth.TokenConfig{
+		Secret:     cfg.JWTSecret,
+		Issuer:     cfg.JWTIssuer,
+		AccessTTL:  cfg.JWTAccessTTL,
+		RefreshTTL: cfg.JWTRefreshTTL,
+	})
+	authFlow := auth.NewAuthFlow(userRepo, tokenManager, auth.AuthConfig{
+		AdminEmail:    cfg.AdminEmail,
+		AdminPassword: cfg.AdminPassword,
+	})
+	if _, err := authFlow.SeedAdmin(context.Background()); err != nil {
+		log.Fatalf("Failed to seed admin: %v", err)
+	}
+	authHandler := auth.NewAuthHandler(authFlow)
+
+	go runTokenCleanup(authFlow, time.Hour)
+
+	uploadRepo := upload.NewMySQLUploadRepository(db)
+	uploadFlow := upload.NewUploadFlow(uploadRepo, store, upload.UploadConfig{
+		Dir:        cfg.UploadDir,
+		MaxSize:    cfg.UploadMaxSize,
+		Expiration: cfg.UploadExpiration,
+	})
+	uploadHandler := upload.NewUploadHandler(uploadFlow)
+
+	go runUploadCleanup(uploadFlow, time.Hour)
+
+	genreRepo := genre.NewMySQLGenreRepository(db)
+	genreFlow := genre.NewGenreFlow(genreRepo)
+	genreHandler := genre.NewGenreHandler(genreFlow)
+
+	personRepo := person.NewMySQLPersonRepository(db)
+	personFlow := person.NewPersonFlow(personRepo)
+	personParser := person.NewPersonParser()
+	personHandler := person.NewPersonHandler(personParser, personFlow)
 
 	movieRepo := movie.NewMySQLMovieRepository(db)
-	movieFlow := movie.NewMovieFlow(movieRepo)
+	movieFlow := movie.NewMovieFlow(movieRepo, genreRepo, store, movie.MovieFlowConfig{
+		RejectDurationMismatch: cfg.DurationMismatchPolicy == config.DurationMismatchReject,
+		RequireIfMatch:         cfg.RequireIfMatch,
+		Retention:              cfg.MovieRetention,
+	})
+	if cfg.MovieRetention > 0 {
+		go runMoviePurge(movieFlow, cfg.MovieRetentionDryRun, time.Hour)
+	}
+
 	movieParser := movie.NewMovieParser()
-	movieHandler := movie.NewMovieHandler(movieParser, movieFlow)
+	movieHandler := movie.NewMovieHandler(movieParser, movieFlow, store, uploadFlow)
 
-	r.Mount("/api/movies", movieHandler.Routes())
+	timeout := middleware.Timeout(constant.REQUEST_TIMEOUT)
+
+	r.With(timeout).Mount("/api/auth", authHandler.Routes())
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Authenticator(authFlow))
+
+		// Routes transferring videos apply the timeout to their other routes
+		// themselves, as a transfer takes as long as the video needs.
+		r.Mount("/api/movies", movieHandler.Routes())
+		r.With(auth.Require(auth.ActionUploadMovie)).Mount("/api/uploads", uploadHandler.Routes())
+		r.With(timeout).Mount("/api/genres", genreHandler.Routes())
+		r.With(timeout).Mount("/api/people", personHandler.Routes())
+	})
 
 	serverAddr := fmt.Sprintf(":%s", cfg.AppPort)
 	fmt.Printf("Server running at http://localhost%s\n", serverAddr)
@@ -46,3 +143,113 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+func runUploadCleanup(uploadFlow upload.UploadFlowInterface, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removed, err := uploadFlow.CleanupExpired(context.Background())
+		if err != nil {
+			log.Printf("Failed to clean up expired uploads: %v", err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("Removed %d expired uploads", removed)
+		}
+	}
+}
+
+func runMoviePurge(movieFlow movie.MovieFlowInterface, dryRun bool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := movieFlow.PurgeDeletedMovies(context.Background(), dryRun)
+		if err != nil {
+			log.Printf("Failed to purge deleted movies: %v", err)
+		}
+		if report == nil {
+			continue
+		}
+
+		verb := "Purged"
+		if report.DryRun {
+			verb = "Would purge"
+		}
+		for _, purged := range report.Movies {
+			log.Printf("%s movie %d %q deleted at %s, files: %v", verb, purged.ID, purged.Title, purged.DeletedAt.Format(time.RFC3339), purged.Files)
+		}
+	}
+}
+
+func runReconcileCommand(reconcileFlow reconcile.ReconcileFlowInterface, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	modeValue := flags.String("mode", string(reconcile.ModeReport), "what to do with orphaned files: report, quarantine or delete")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	mode, err := reconcile.ParseMode(*modeValue)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	report, err := reconcileFlow.Reconcile(context.Background(), mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to reconcile movie files: %v\n", err)
+		return 1
+	}
+
+	printReconcileReport(report, func(format string, args ...interface{}) {
+		fmt.Printf(format+"\n", args...)
+	})
+	return 0
+}
+
+func runReconcile(reconcileFlow reconcile.ReconcileFlowInterface, mode reconcile.Mode, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := reconcileFlow.Reconcile(context.Background(), mode)
+		if err != nil {
+			log.Printf("Failed to reconcile movie files: %v", err)
+			continue
+		}
+		if len(report.Orphans) > 0 || len(report.Dangling) > 0 {
+			printReconcileReport(report, log.Printf)
+		}
+	}
+}
+
+func printReconcileReport(report *reconcile.Report, printf func(format string, args ...interface{})) {
+	for _, orphan := range report.Orphans {
+		if orphan.Error != "" {
+			printf("Orphaned file %s (%d bytes): %s failed: %s", orphan.Key, orphan.Size, report.Mode, orphan.Error)
+			continue
+		}
+		printf("Orphaned file %s (%d bytes): %s", orphan.Key, orphan.Size, orphan.Action)
+	}
+	for _, reference := range report.Dangling {
+		printf("Missing file %s referenced by movie %d", reference.Path, reference.MovieID)
+	}
+	printf("Checked %d files: %d orphaned, %d missing", report.Checked, len(report.Orphans), len(report.Dangling))
+}
+
+func runTokenCleanup(authFlow auth.AuthFlowInterface, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		removed, err := authFlow.CleanupExpired(context.Background())
+		if err != nil {
+			log.Printf("Failed to clean up expired tokens: %v", err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("Removed %d expired tokens", removed)
+		}
+	}
+}